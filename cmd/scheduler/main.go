@@ -6,13 +6,17 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/cmd/kube-scheduler/app"
 
-	// Import our plugin package to ensure its PluginRegistry is available.
-	_ "sigs.k8s.io/controller-spread-scheduler/pkg/controllerspread"
+	// The plugins of PluginRegistryWithContext are registered with the scheduler command below.
+	"sigs.k8s.io/controller-spread-scheduler/pkg/controllerspread"
 )
 
 func main() {
 	klog.InitFlags(nil)
-	cmd := app.NewSchedulerCommand()
+	var opts []app.Option
+	for name, factory := range controllerspread.PluginRegistryWithContext {
+		opts = append(opts, app.WithPlugin(name, factory))
+	}
+	cmd := app.NewSchedulerCommand(opts...)
 	if err := cmd.Execute(); err != nil {
 		klog.ErrorS(err, "Scheduler command failed")
 		os.Exit(1)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/controller-spread-scheduler/pkg/admission"
+)
+
+func main() {
+	klog.InitFlags(nil)
+	kubeconfig := flag.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	addr := flag.String("bind-address", ":8443", "Address to serve the webhook on.")
+	certFile := flag.String("tls-cert-file", "", "File containing the serving certificate.")
+	keyFile := flag.String("tls-private-key-file", "", "File containing the serving certificate key.")
+	defaultMinHosts := flag.Int("default-min-hosts", 2, "DefaultMinHosts of the scheduler plugin args.")
+	topologyKey := flag.String("topology-key", v1.LabelHostname, "Node label the pods are spread across, the last of the TopologyKeys of the scheduler plugin args.")
+	flag.Parse()
+
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		klog.ErrorS(err, "Failed to build client config")
+		os.Exit(1)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		klog.ErrorS(err, "Failed to create client")
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	factory := informers.NewSharedInformerFactory(client, 0)
+	nodes := factory.Core().V1().Nodes().Lister()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", &admission.Validator{
+		Nodes:           nodes,
+		DefaultMinHosts: int32(*defaultMinHosts),
+		TopologyKey:     *topologyKey,
+	})
+	server := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil && err != http.ErrServerClosed {
+		klog.ErrorS(err, "Webhook server failed")
+		os.Exit(1)
+	}
+}
@@ -3,11 +3,16 @@ module sigs.k8s.io/controller-spread-scheduler
 go 1.24.0
 
 require (
+	github.com/go-logr/logr v1.4.1
+	github.com/google/go-cmp v0.6.0
 	k8s.io/api v0.30.5
 	k8s.io/apimachinery v0.30.5
 	k8s.io/client-go v0.30.5
+	k8s.io/component-base v0.30.5
+	k8s.io/component-helpers v0.30.5
 	k8s.io/klog/v2 v2.120.1
 	k8s.io/kubernetes v1.30.10
+	k8s.io/utils v0.0.0-20230726121419-3b25d923346b
 )
 
 require (
@@ -27,7 +32,6 @@ require (
 	github.com/evanphx/json-patch v5.6.0+incompatible // indirect
 	github.com/felixge/httpsnoop v1.0.3 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
-	github.com/go-logr/logr v1.4.1 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-openapi/jsonpointer v0.19.6 // indirect
 	github.com/go-openapi/jsonreference v0.20.2 // indirect
@@ -37,7 +41,6 @@ require (
 	github.com/golang/protobuf v1.5.4 // indirect
 	github.com/google/cel-go v0.17.8 // indirect
 	github.com/google/gnostic-models v0.6.8 // indirect
-	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/google/gofuzz v1.2.0 // indirect
 	github.com/google/uuid v1.3.1 // indirect
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0 // indirect
@@ -99,8 +102,6 @@ require (
 	k8s.io/apiextensions-apiserver v0.30.5 // indirect
 	k8s.io/apiserver v0.30.5 // indirect
 	k8s.io/cloud-provider v0.30.5 // indirect
-	k8s.io/component-base v0.30.5 // indirect
-	k8s.io/component-helpers v0.30.5 // indirect
 	k8s.io/controller-manager v0.30.5 // indirect
 	k8s.io/csi-translation-lib v0.30.5 // indirect
 	k8s.io/dynamic-resource-allocation v0.30.5 // indirect
@@ -109,7 +110,6 @@ require (
 	k8s.io/kube-scheduler v0.30.5 // indirect
 	k8s.io/kubelet v0.30.5 // indirect
 	k8s.io/mount-utils v0.30.5 // indirect
-	k8s.io/utils v0.0.0-20230726121419-3b25d923346b // indirect
 	sigs.k8s.io/apiserver-network-proxy/konnectivity-client v0.29.0 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.1 // indirect
@@ -0,0 +1,159 @@
+// pkg/admission/validator.go
+//
+// Validating admission webhook for the spread annotations. The scheduler lowers a min-hosts
+// requirement its pods cannot meet to the feasible number of domains, but not the requirement of a
+// strict controller, whose pods then stay Pending. Validator rejects such strict controllers when
+// they are created or updated instead, using the feasibility computation of the scheduler plugin,
+// see controllerspread.FeasibleDomains, so the two agree on what is possible. Invalid min-hosts
+// values are rejected as well.
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/controller-spread-scheduler/pkg/controllerspread"
+)
+
+// maxRequestBytes bounds the size of an admission review request body.
+const maxRequestBytes = 3 * 1024 * 1024
+
+// Validator is an http.Handler serving admission.k8s.io/v1 AdmissionReviews for Deployments,
+// StatefulSets, ReplicaSets and ReplicationControllers. It denies a strict controller if its
+// replica count exceeds the number of domains of the nodes its pod template may run on, and any
+// controller with an invalid min-hosts annotation.
+type Validator struct {
+	// Nodes lists the nodes of the cluster.
+	Nodes corelisters.NodeLister
+	// DefaultMinHosts is the DefaultMinHosts of the scheduler plugin args.
+	DefaultMinHosts int32
+	// TopologyKey is the node label the pods are spread across unless the controller selects one
+	// with the topology-key annotation, i.e. the last of the TopologyKeys of the plugin args.
+	TopologyKey string
+}
+
+var _ http.Handler = &Validator{}
+
+// ServeHTTP decodes the AdmissionReview of the request and responds with the verdict.
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading request: %v", err), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "request is not an AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
+	response := v.review(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.ErrorS(err, "Failed to write admission response")
+	}
+}
+
+// review returns the verdict for the request. Objects of other kinds and requests that cannot
+// be evaluated are allowed, so that the webhook never blocks more than the scheduler would.
+func (v *Validator) review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return allowed
+	}
+	meta, replicas, template, err := decodeController(request.Kind, request.Object.Raw)
+	if err != nil {
+		klog.V(2).InfoS("Admitting object that could not be decoded", "kind", request.Kind.Kind,
+			"namespace", request.Namespace, "name", request.Name, "err", err)
+		return allowed
+	}
+	if template == nil {
+		return allowed
+	}
+
+	desired := int32(1)
+	if replicas != nil {
+		desired = *replicas
+	}
+	if _, err := controllerspread.RequiredHosts(meta.Annotations, desired, v.DefaultMinHosts); err != nil {
+		return denied(fmt.Sprintf("invalid annotation: %v", err))
+	}
+	if !controllerspread.StrictSpread(meta.Annotations) || desired <= 1 {
+		// The scheduler lowers any other requirement to the feasible number of domains.
+		return allowed
+	}
+
+	nodes, err := v.Nodes.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Admitting object as nodes could not be listed", "kind", request.Kind.Kind,
+			"namespace", request.Namespace, "name", request.Name)
+		return allowed
+	}
+	pod := &v1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+	topologyKey := controllerspread.TopologyKey(meta.Annotations, v.TopologyKey)
+	feasible := controllerspread.FeasibleDomains(pod, nodes, topologyKey)
+	if int(desired) <= feasible {
+		return allowed
+	}
+	return denied(fmt.Sprintf("strict %s %s/%s requires its %d pods on distinct %s domains, but its pod template only fits nodes in %d",
+		request.Kind.Kind, request.Namespace, meta.Name, desired, topologyKey, feasible))
+}
+
+// decodeController decodes the object of a supported kind and returns its metadata, replica
+// count and pod template. The template is nil for other kinds.
+func decodeController(kind metav1.GroupVersionKind, raw []byte) (*metav1.ObjectMeta, *int32, *v1.PodTemplateSpec, error) {
+	switch (schema.GroupKind{Group: kind.Group, Kind: kind.Kind}) {
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "Deployment"}:
+		obj := &appsv1.Deployment{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, nil, nil, err
+		}
+		return &obj.ObjectMeta, obj.Spec.Replicas, &obj.Spec.Template, nil
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "StatefulSet"}:
+		obj := &appsv1.StatefulSet{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, nil, nil, err
+		}
+		return &obj.ObjectMeta, obj.Spec.Replicas, &obj.Spec.Template, nil
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "ReplicaSet"}:
+		obj := &appsv1.ReplicaSet{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, nil, nil, err
+		}
+		return &obj.ObjectMeta, obj.Spec.Replicas, &obj.Spec.Template, nil
+	case schema.GroupKind{Group: v1.GroupName, Kind: "ReplicationController"}:
+		obj := &v1.ReplicationController{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, nil, nil, err
+		}
+		return &obj.ObjectMeta, obj.Spec.Replicas, obj.Spec.Template, nil
+	}
+	return nil, nil, nil, nil
+}
+
+// denied returns a response rejecting the object with the message.
+func denied(message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: message,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// newNodeLister returns a lister over nodes of the names, the first pool nodes carrying the label
+// pool=a.
+func newNodeLister(names []string, pool int) corelisters.NodeLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for i, name := range names {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelHostname: name}}}
+		if i < pool {
+			node.Labels["pool"] = "a"
+		}
+		_ = indexer.Add(node)
+	}
+	return corelisters.NewNodeLister(indexer)
+}
+
+// makeDeployment returns a Deployment of the replicas and annotations whose pods select the nodes
+// of pool a.
+func makeDeployment(replicas int32, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: annotations},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{NodeSelector: map[string]string{"pool": "a"}}},
+		},
+	}
+}
+
+// newReview returns an AdmissionReview of the operation on the object.
+func newReview(t *testing.T, operation admissionv1.Operation, kind metav1.GroupVersionKind, obj runtime.Object) []byte {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("encoding object: %v", err)
+	}
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "review-uid",
+			Kind:      kind,
+			Namespace: "default",
+			Name:      "web",
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("encoding review: %v", err)
+	}
+	return body
+}
+
+func TestValidator(t *testing.T) {
+	deploymentKind := metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	tests := []struct {
+		name        string
+		operation   admissionv1.Operation
+		kind        metav1.GroupVersionKind
+		obj         runtime.Object
+		wantAllowed bool
+	}{
+		{
+			name:        "min-hosts above the feasible domains",
+			operation:   admissionv1.Create,
+			kind:        deploymentKind,
+			obj:         makeDeployment(3, map[string]string{"controller-spread-scheduler/min-hosts": "3"}),
+			wantAllowed: true,
+		},
+		{
+			name:      "strict replicas above the feasible domains",
+			operation: admissionv1.Create,
+			kind:      deploymentKind,
+			obj:       makeDeployment(3, map[string]string{"controller-spread-scheduler/strict": "true"}),
+		},
+		{
+			name:        "strict replicas within the feasible domains",
+			operation:   admissionv1.Update,
+			kind:        deploymentKind,
+			obj:         makeDeployment(2, map[string]string{"controller-spread-scheduler/strict": "true"}),
+			wantAllowed: true,
+		},
+		{
+			name:        "invalid strict value",
+			operation:   admissionv1.Create,
+			kind:        deploymentKind,
+			obj:         makeDeployment(3, map[string]string{"controller-spread-scheduler/strict": "yes please"}),
+			wantAllowed: true,
+		},
+		{
+			name:      "invalid min-hosts value",
+			operation: admissionv1.Create,
+			kind:      deploymentKind,
+			obj:       makeDeployment(3, map[string]string{"controller-spread-scheduler/min-hosts": "tree"}),
+		},
+		{
+			name:        "deletion",
+			operation:   admissionv1.Delete,
+			kind:        deploymentKind,
+			obj:         makeDeployment(3, map[string]string{"controller-spread-scheduler/strict": "true"}),
+			wantAllowed: true,
+		},
+		{
+			name:        "unsupported kind",
+			operation:   admissionv1.Create,
+			kind:        metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			obj:         &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"controller-spread-scheduler/strict": "true"}}},
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &Validator{Nodes: newNodeLister([]string{"node-a", "node-b", "node-c"}, 2), DefaultMinHosts: 2, TopologyKey: v1.LabelHostname}
+			rec := httptest.NewRecorder()
+			validator.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(newReview(t, tt.operation, tt.kind, tt.obj))))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status code = %d, want %d", rec.Code, http.StatusOK)
+			}
+			review := admissionv1.AdmissionReview{}
+			if err := json.NewDecoder(rec.Body).Decode(&review); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if review.Response == nil || review.Response.UID != "review-uid" {
+				t.Fatalf("response = %+v, want one for the request", review.Response)
+			}
+			if review.Response.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v: %v", review.Response.Allowed, tt.wantAllowed, review.Response.Result)
+			}
+		})
+	}
+}
+
+func TestValidatorBadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "not JSON",
+			body: "web",
+		},
+		{
+			name: "review without a request",
+			body: `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &Validator{Nodes: newNodeLister(nil, 0), DefaultMinHosts: 2, TopologyKey: v1.LabelHostname}
+			rec := httptest.NewRecorder()
+			validator.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewBufferString(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status code = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// pkg/controllerspread/achieved_spread.go
+//
+// Reporting of the achieved spread on scheduled pods. With AnnotateAchievedSpread set in the
+// plugin args, PreBind annotates each spread pod with the number of distinct domains of the last
+// topology level, hostnames by default, that its controller's pods span once it is bound, so
+// that downstream tooling can verify the spread without recomputing it. The annotation is best
+// effort: a failed patch is logged and never fails the binding.
+package controllerspread
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key on a scheduled pod recording the spread achieved with its placement.
+	achievedHostsAnnotationKey = "controller-spread-scheduler/achieved-hosts"
+
+	// achievedSpreadPatchTimeout bounds the patch annotating a pod, which delays its binding.
+	achievedSpreadPatchTimeout = 5 * time.Second
+)
+
+// achievedDomains returns the number of distinct domains of the last level that run a peer or
+// the node. A node outside spread accounting adds no domain.
+func (csf *ControllerSpreadFilter) achievedDomains(logger klog.Logger, s *controllerSpreadState, node *v1.Node) int {
+	if len(s.levels) == 0 {
+		return 0
+	}
+	level := s.levels[len(s.levels)-1]
+	domains := make(map[string]bool, len(level.domainCounts)+1)
+	for domain, count := range level.domainCounts {
+		if count > 0 {
+			domains[domain] = true
+		}
+	}
+	if !csf.isExcludedNode(node) {
+		domains[topologyDomain(logger, node, level.key)] = true
+	}
+	return len(domains)
+}
+
+// annotateAchievedSpread patches the pod with the achieved-hosts annotation for its placement on
+// the node, if AnnotateAchievedSpread is set. Failures are logged only.
+func (csf *ControllerSpreadFilter) annotateAchievedSpread(ctx context.Context, logger klog.Logger, pod *v1.Pod, s *controllerSpreadState, node *v1.Node) {
+	if !csf.args.AnnotateAchievedSpread {
+		return
+	}
+	achieved := strconv.Itoa(csf.achievedDomains(logger, s, node))
+	if pod.Annotations[achievedHostsAnnotationKey] == achieved {
+		return
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{achievedHostsAnnotationKey: achieved},
+		},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to build achieved spread patch", "pod", klog.KObj(pod))
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, achievedSpreadPatchTimeout)
+	defer cancel()
+	_, err = csf.handle.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		logger.Error(err, "Failed to annotate pod with achieved spread", "pod", klog.KObj(pod), "node", node.Name, "achievedHosts", achieved)
+		return
+	}
+	logger.V(5).Info("Annotated pod with achieved spread", "pod", klog.KObj(pod), "node", node.Name, "achievedHosts", achieved)
+}
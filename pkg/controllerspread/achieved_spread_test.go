@@ -0,0 +1,87 @@
+package controllerspread
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPreBindAnnotatesAchievedSpread(t *testing.T) {
+	ciSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"node-role.example.com/ci": "true"}}
+	nodes := []*v1.Node{
+		makeNode("node-a", nil),
+		makeNode("node-b", nil),
+		makeNode("node-c", nil),
+		makeNode("node-ci", map[string]string{"node-role.example.com/ci": "true"}),
+	}
+	tests := []struct {
+		name     string
+		args     ControllerSpreadArgs
+		nodeName string
+		// inClient reports whether the pod exists in the API server, so that the patch succeeds.
+		inClient bool
+		// want is the achieved-hosts annotation of the pod after PreBind, or empty if it has none.
+		want string
+	}{
+		{
+			name:     "annotation disabled",
+			nodeName: "node-c",
+			inClient: true,
+		},
+		{
+			name:     "new host",
+			args:     ControllerSpreadArgs{AnnotateAchievedSpread: true},
+			nodeName: "node-c",
+			inClient: true,
+			want:     "3",
+		},
+		{
+			name:     "host of a peer",
+			args:     ControllerSpreadArgs{AnnotateAchievedSpread: true},
+			nodeName: "node-a",
+			inClient: true,
+			want:     "2",
+		},
+		{
+			name:     "excluded node",
+			args:     ControllerSpreadArgs{AnnotateAchievedSpread: true, ExcludedNodeSelector: ciSelector},
+			nodeName: "node-ci",
+			inClient: true,
+			want:     "2",
+		},
+		{
+			name:     "patch failed",
+			args:     ControllerSpreadArgs{AnnotateAchievedSpread: true},
+			nodeName: "node-c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 4, map[string]string{minHostsAnnotationKey: "2"}), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			if tt.inClient {
+				objs = append(objs, pod)
+			}
+			p := newTestPlugin(t, &tt.args, nodes, objs...)
+			state, status := preFilter(t, p, pod)
+			if !status.IsSuccess() {
+				t.Fatalf("PreFilter: %v", status)
+			}
+
+			if status := p.PreBind(t.Context(), state, pod, tt.nodeName); !status.IsSuccess() {
+				t.Fatalf("PreBind() = %v, want Success", status)
+			}
+			if !tt.inClient {
+				return
+			}
+			got, err := p.handle.ClientSet().CoreV1().Pods(testNamespace).Get(t.Context(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting pod: %v", err)
+			}
+			if got.Annotations[achievedHostsAnnotationKey] != tt.want {
+				t.Errorf("achieved-hosts annotation = %q, want %q", got.Annotations[achievedHostsAnnotationKey], tt.want)
+			}
+		})
+	}
+}
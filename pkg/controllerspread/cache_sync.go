@@ -0,0 +1,68 @@
+// pkg/controllerspread/cache_sync.go
+//
+// Readiness gate for the informer caches. Until the pod and controller informers have synced,
+// listing returns an empty or partial view and the spread check would be too permissive, so
+// PreFilter fails with a retriable error instead. The gate also covers the Namespace informer of
+// NamespaceSelector, which would otherwise fail namespace lookups, and the ConfigMap informer of
+// DomainWeightsConfigMap, which would otherwise score without the weights.
+package controllerspread
+
+import (
+	"sync/atomic"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// cacheSyncGate reports whether all informers the plugin reads from have synced.
+type cacheSyncGate struct {
+	synced    atomic.Bool
+	hasSynced []cache.InformerSynced
+}
+
+// newCacheSyncGate returns a gate over the shared informers used by the plugin, the informers of
+// the custom controllers and the informer of the domain weights, if any.
+func newCacheSyncGate(handle framework.Handle, args *ControllerSpreadArgs, customControllers map[string]*customController, domainWeights *domainWeightsLoader) *cacheSyncGate {
+	factory := handle.SharedInformerFactory()
+	g := &cacheSyncGate{hasSynced: []cache.InformerSynced{
+		factory.Core().V1().Pods().Informer().HasSynced,
+		factory.Core().V1().Nodes().Informer().HasSynced,
+		factory.Core().V1().ReplicationControllers().Informer().HasSynced,
+		factory.Apps().V1().DaemonSets().Informer().HasSynced,
+		factory.Apps().V1().Deployments().Informer().HasSynced,
+		factory.Apps().V1().ReplicaSets().Informer().HasSynced,
+		factory.Apps().V1().StatefulSets().Informer().HasSynced,
+		factory.Batch().V1().Jobs().Informer().HasSynced,
+		factory.Batch().V1().CronJobs().Informer().HasSynced,
+		factory.Policy().V1().PodDisruptionBudgets().Informer().HasSynced,
+	}}
+	if args.HPAAware {
+		g.hasSynced = append(g.hasSynced, factory.Autoscaling().V2().HorizontalPodAutoscalers().Informer().HasSynced)
+	}
+	if args.NamespaceSelector != nil {
+		g.hasSynced = append(g.hasSynced, factory.Core().V1().Namespaces().Informer().HasSynced)
+	}
+	if domainWeights != nil {
+		g.hasSynced = append(g.hasSynced, domainWeights.hasSynced)
+	}
+	for _, cc := range customControllers {
+		g.hasSynced = append(g.hasSynced, cc.hasSynced)
+	}
+	return g
+}
+
+// ready reports whether all informers have synced. Once they have, it stays true.
+func (g *cacheSyncGate) ready() bool {
+	if g.synced.Load() {
+		return true
+	}
+	for _, hasSynced := range g.hasSynced {
+		if !hasSynced() {
+			return false
+		}
+	}
+	klog.V(2).InfoS("Informer caches synced, enforcing controller spread")
+	g.synced.Store(true)
+	return true
+}
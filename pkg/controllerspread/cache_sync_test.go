@@ -0,0 +1,84 @@
+package controllerspread
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestCacheSyncGate(t *testing.T) {
+	notSynced := &domainWeightsLoader{hasSynced: func() bool { return false }}
+	synced := &domainWeightsLoader{hasSynced: func() bool { return true }}
+	tests := []struct {
+		name          string
+		args          ControllerSpreadArgs
+		domainWeights *domainWeightsLoader
+		// started reports whether the informers the gate adds are started before checking it.
+		started bool
+		want    bool
+	}{
+		{
+			name:    "synced informers",
+			started: true,
+			want:    true,
+		},
+		{
+			name:    "namespace informer synced",
+			args:    ControllerSpreadArgs{NamespaceSelector: &metav1.LabelSelector{}},
+			started: true,
+			want:    true,
+		},
+		{
+			name: "namespace informer not synced",
+			args: ControllerSpreadArgs{NamespaceSelector: &metav1.LabelSelector{}},
+		},
+		{
+			name:          "domain weights synced",
+			domainWeights: synced,
+			started:       true,
+			want:          true,
+		},
+		{
+			name:          "domain weights not synced",
+			domainWeights: notSynced,
+			started:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			factory := informers.NewSharedInformerFactory(client, 0)
+			fh := newTestFramework(t, nil, nil, frameworkruntime.WithClientSet(client), frameworkruntime.WithInformerFactory(factory))
+			// The informers every gate covers are started and synced first.
+			newCacheSyncGate(fh, &ControllerSpreadArgs{}, nil, nil)
+			factory.Start(t.Context().Done())
+			factory.WaitForCacheSync(t.Context().Done())
+
+			gate := newCacheSyncGate(fh, &tt.args, nil, tt.domainWeights)
+			if tt.started {
+				factory.Start(t.Context().Done())
+				factory.WaitForCacheSync(t.Context().Done())
+			}
+			if got := gate.ready(); got != tt.want {
+				t.Errorf("ready() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreFilterWaitsForCacheSync(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	deploy := makeDeployment("web", 2, nil)
+	objs := makeDeploymentPods(deploy, "node-a")
+	pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+	p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+	p.caches = newCacheSyncGate(p.handle, p.args, nil, &domainWeightsLoader{hasSynced: func() bool { return false }})
+
+	if _, status := preFilter(t, p, pod); status.Code() != framework.Error {
+		t.Errorf("PreFilter() = %v, want Error while the domain weights are not synced", status)
+	}
+}
@@ -0,0 +1,97 @@
+// pkg/controllerspread/circuit_breaker.go
+//
+// Rejection circuit breaker for ControllerSpreadFilter. A misconfiguration, e.g. a min-hosts
+// annotation that no cluster can satisfy, can make the plugin reject most placements and leave
+// workloads pending. With MaxRejectRate set, the fraction of Filter evaluations rejected by the
+// spread constraint is measured over fixed windows; after a window whose rate exceeds the limit
+// the breaker opens and Filter fails open for the next window. The decisions are still computed
+// while the breaker is open, and it closes after a window whose rate is back within the limit.
+package controllerspread
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// defaultRejectRateWindow is the default length of a measurement window.
+	defaultRejectRateWindow = time.Minute
+
+	// minBreakerSamples is the number of Filter evaluations a window needs before its rate is
+	// taken into account, so that a few rejections in a quiet window do not open the breaker.
+	minBreakerSamples = 100
+)
+
+// rejectBreaker measures the rejection rate of Filter and fails open while it is too high.
+type rejectBreaker struct {
+	plugin  string
+	maxRate float64
+	window  time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	total       int
+	rejected    int
+	open        bool
+}
+
+// newRejectBreaker returns a breaker for the args, or nil if MaxRejectRate is not set.
+func newRejectBreaker(args *ControllerSpreadArgs) *rejectBreaker {
+	if args.MaxRejectRate == 0 {
+		return nil
+	}
+	return &rejectBreaker{plugin: args.PluginName, maxRate: args.MaxRejectRate, window: args.RejectRateWindow.Duration}
+}
+
+// apply records the Filter status and returns it, or Success while the breaker is open.
+func (b *rejectBreaker) apply(logger klog.Logger, pod *v1.Pod, status *framework.Status, now time.Time) *framework.Status {
+	if b == nil {
+		return status
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.windowStart) >= b.window {
+		b.roll(now)
+	}
+	b.total++
+	if status.Code() == framework.Unschedulable {
+		b.rejected++
+	}
+	if b.open && !status.IsSuccess() {
+		logger.V(4).Info("Circuit breaker open, accepting node", "pod", klog.KObj(pod), "reason", status.Message())
+		return framework.NewStatus(framework.Success)
+	}
+	return status
+}
+
+// roll evaluates the finished window, opening or closing the breaker, and starts a new window.
+func (b *rejectBreaker) roll(now time.Time) {
+	if b.total >= minBreakerSamples {
+		rate := float64(b.rejected) / float64(b.total)
+		switch {
+		case rate > b.maxRate && !b.open:
+			klog.ErrorS(nil, "Spread rejection rate exceeds the limit, opening the circuit breaker: the spread constraint is not enforced",
+				"plugin", b.plugin, "rejectRate", rate, "maxRejectRate", b.maxRate, "window", b.window)
+			b.open = true
+		case rate <= b.maxRate && b.open:
+			klog.InfoS("Spread rejection rate is back within the limit, closing the circuit breaker",
+				"plugin", b.plugin, "rejectRate", rate, "maxRejectRate", b.maxRate)
+			b.open = false
+		}
+	} else if b.open {
+		klog.InfoS("Too few Filter evaluations to measure the rejection rate, closing the circuit breaker", "plugin", b.plugin)
+		b.open = false
+	}
+	if b.open {
+		circuitBreakerOpen.WithLabelValues(b.plugin).Set(1)
+	} else {
+		circuitBreakerOpen.WithLabelValues(b.plugin).Set(0)
+	}
+	b.windowStart = now
+	b.total = 0
+	b.rejected = 0
+}
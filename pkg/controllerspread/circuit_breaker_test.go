@@ -0,0 +1,89 @@
+package controllerspread
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestRejectBreaker(t *testing.T) {
+	type window struct{ total, rejected int }
+	tests := []struct {
+		name    string
+		windows []window
+		// want is the status code of a rejection in the window after the windows.
+		want framework.Code
+	}{
+		{
+			name: "no windows",
+			want: framework.Unschedulable,
+		},
+		{
+			name:    "rate within the limit",
+			windows: []window{{total: 100, rejected: 50}},
+			want:    framework.Unschedulable,
+		},
+		{
+			name:    "rate above the limit",
+			windows: []window{{total: 100, rejected: 51}},
+			want:    framework.Success,
+		},
+		{
+			name:    "rate above the limit with too few samples",
+			windows: []window{{total: minBreakerSamples - 1, rejected: minBreakerSamples - 1}},
+			want:    framework.Unschedulable,
+		},
+		{
+			name:    "rate back within the limit",
+			windows: []window{{total: 100, rejected: 100}, {total: 100, rejected: 10}},
+			want:    framework.Unschedulable,
+		},
+		{
+			name:    "rate still above the limit",
+			windows: []window{{total: 100, rejected: 100}, {total: 100, rejected: 90}},
+			want:    framework.Success,
+		},
+		{
+			name:    "too few samples while open",
+			windows: []window{{total: 100, rejected: 100}, {total: 1, rejected: 1}},
+			want:    framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &ControllerSpreadArgs{PluginName: Name, MaxRejectRate: 0.5, RejectRateWindow: metav1.Duration{Duration: time.Minute}}
+			b := newRejectBreaker(args)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			rejected := framework.NewStatus(framework.Unschedulable, "spread constraint not met")
+			now := time.Now()
+			for _, w := range tt.windows {
+				for i := range w.total {
+					status := framework.NewStatus(framework.Success)
+					if i < w.rejected {
+						status = rejected
+					}
+					b.apply(klog.Background(), pod, status, now)
+				}
+				now = now.Add(time.Minute)
+			}
+
+			if got := b.apply(klog.Background(), pod, rejected, now); got.Code() != tt.want {
+				t.Errorf("apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectBreakerDisabled(t *testing.T) {
+	if b := newRejectBreaker(&ControllerSpreadArgs{}); b != nil {
+		t.Fatalf("newRejectBreaker() = %v, want nil without MaxRejectRate", b)
+	}
+	var b *rejectBreaker
+	rejected := framework.NewStatus(framework.Unschedulable, "spread constraint not met")
+	if got := b.apply(klog.Background(), makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash")), rejected, time.Now()); got != rejected {
+		t.Errorf("apply() = %v, want the status unchanged", got)
+	}
+}
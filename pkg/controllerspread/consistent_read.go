@@ -0,0 +1,88 @@
+// pkg/controllerspread/consistent_read.go
+//
+// Consistent reads for critical controllers. The pods of a controller are normally listed from
+// the informer cache, which may lag behind the API server, e.g. right after a peer was bound by
+// another scheduler. With the "controller-spread-scheduler/consistent-read" annotation set to
+// "true" on the controller, PreFilter lists its pods from the API server instead, with a quorum
+// read that reflects every write made before it. Such reads are slow and load the API server,
+// so they are disabled unless ConsistentReadQPS is set in the plugin args, and rate limited to
+// it across all controllers. A throttled read falls back to the informer cache.
+package controllerspread
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key to list the controller's pods from the API server rather than the cache.
+	consistentReadAnnotationKey = "controller-spread-scheduler/consistent-read"
+
+	// resultThrottled is the result label value of consistent reads denied by the rate limit.
+	resultThrottled = "throttled"
+)
+
+// newConsistentReadLimiter returns the rate limiter of consistent reads, or nil if
+// ConsistentReadQPS is not set. The burst is the QPS rounded up.
+func newConsistentReadLimiter(args *ControllerSpreadArgs) flowcontrol.RateLimiter {
+	if args.ConsistentReadQPS == 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(args.ConsistentReadQPS), int(math.Ceil(args.ConsistentReadQPS)))
+}
+
+// wantsConsistentRead reports whether the controller's pods should be listed from the API server.
+func (csf *ControllerSpreadFilter) wantsConsistentRead(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	if csf.consistentReads == nil {
+		return false
+	}
+	val, exists := annotations[consistentReadAnnotationKey]
+	if !exists {
+		return false
+	}
+	consistent, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", consistentReadAnnotationKey, "value", val, "controller", controller.Name)
+		return false
+	}
+	return consistent
+}
+
+// listControllerPodsConsistently returns the active pods of the controller in the namespace read
+// from the API server, filtered like listControllerPods. It returns false if the read was
+// throttled and the caller should list from the informer cache instead.
+func (csf *ControllerSpreadFilter) listControllerPodsConsistently(ctx context.Context, namespace string, controller ControllerInfo) ([]*v1.Pod, bool, error) {
+	logger := klog.FromContext(ctx)
+	if !csf.consistentReads.TryAccept() {
+		klog.FromContext(ctx).V(4).Info("Consistent read throttled, listing pods from the cache", "namespace", namespace,
+			"controllerType", controller.Type, "controller", controller.Name)
+		consistentReads.WithLabelValues(csf.Name(), resultThrottled).Inc()
+		return nil, false, nil
+	}
+	opts := metav1.ListOptions{}
+	if controller.Type == LabelGroupType {
+		opts.LabelSelector = labelGroupSelector(controller).String()
+	}
+	// An unset ResourceVersion requests a quorum read of the most recent state.
+	list, err := csf.handle.ClientSet().CoreV1().Pods(namespace).List(ctx, opts)
+	if err != nil {
+		consistentReads.WithLabelValues(csf.Name(), resultError).Inc()
+		return nil, false, err
+	}
+	consistentReads.WithLabelValues(csf.Name(), resultSuccess).Inc()
+
+	var controllerPods []*v1.Pod
+	for i := range list.Items {
+		p := &list.Items[i]
+		if csf.isActivePod(p) && csf.isOwnedByTopController(logger, p, controller) && !csf.ownedBySuspendedJob(p) {
+			controllerPods = append(controllerPods, p)
+		}
+	}
+	return controllerPods, true, nil
+}
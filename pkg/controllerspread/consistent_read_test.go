@@ -0,0 +1,77 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestFilterConsistentRead(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		// consistentRead is the consistent-read annotation of the Deployment, if not empty.
+		consistentRead string
+		// throttled denies every consistent read.
+		throttled bool
+		want      []string
+	}{
+		{
+			name: "informer cache",
+			args: ControllerSpreadArgs{ConsistentReadQPS: 10},
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:           "consistent read",
+			args:           ControllerSpreadArgs{ConsistentReadQPS: 10},
+			consistentRead: "true",
+			want:           []string{"node-c"},
+		},
+		{
+			name:           "consistent reads disabled",
+			consistentRead: "true",
+			want:           []string{"node-b", "node-c"},
+		},
+		{
+			name:           "consistent read throttled",
+			args:           ControllerSpreadArgs{ConsistentReadQPS: 10},
+			consistentRead: "true",
+			throttled:      true,
+			want:           []string{"node-b", "node-c"},
+		},
+		{
+			name:           "invalid annotation",
+			args:           ControllerSpreadArgs{ConsistentReadQPS: 10},
+			consistentRead: "always",
+			want:           []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{minHostsAnnotationKey: "3"}
+			if tt.consistentRead != "" {
+				annotations[consistentReadAnnotationKey] = tt.consistentRead
+			}
+			objs := makeDeploymentPods(makeDeployment("web", 3, annotations), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+			// The scheduler's caches lag behind the API server: web-1, bound to node-b by another
+			// scheduler, is not in them yet.
+			cached := objs[2].(*v1.Pod)
+			p.handle = newTestFramework(t, nodes, []*v1.Pod{cached}, frameworkruntime.WithClientSet(p.handle.ClientSet()))
+			p.podInformer = nil
+			p.podLister = newTestListers(nil, cached, pod).Pods
+			if tt.throttled {
+				p.consistentReads = flowcontrol.NewFakeNeverRateLimiter()
+			}
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
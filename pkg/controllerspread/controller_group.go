@@ -0,0 +1,119 @@
+// pkg/controllerspread/controller_group.go
+//
+// Controller groups for ControllerSpreadFilter. Services that run as several controllers, e.g. a
+// leader Deployment and a follower Deployment, can be spread as one by giving each controller the
+// same "controller-spread-scheduler/group-id" annotation. The peers of a pod are then the pods of
+// every controller in its namespace carrying the group-id, and the desired count is the sum of
+// their desired counts. The annotations of the peers' controllers are read through the spec
+// cache, so resolving the group does not read every controller on every cycle.
+package controllerspread
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key on controllers whose pods are spread together as one group.
+	groupIDAnnotationKey = "controller-spread-scheduler/group-id"
+
+	// controllerGroupKeyPrefix prefixes the group key of the pods of a controller group. Controller
+	// UIDs never contain a colon, so the keys never collide.
+	controllerGroupKeyPrefix = "group-id:"
+)
+
+// controllerGroupID returns the group-id of the controller from its annotations, or "" if it is
+// not part of a controller group. Label groups have no controller to annotate.
+func controllerGroupID(annotations map[string]string, controller ControllerInfo) string {
+	if controller.Type == LabelGroupType {
+		return ""
+	}
+	return annotations[groupIDAnnotationKey]
+}
+
+// controllerGroupKey returns the group key of the controller group in the namespace.
+func controllerGroupKey(namespace, groupID string) string {
+	return controllerGroupKeyPrefix + namespace + "/" + groupID
+}
+
+// listControllerGroupPods returns the active pods in the namespace whose top-level controller
+// carries the group-id, and the desired count of each of these controllers by UID. Controllers
+// of the group without active pods are not found.
+func (csf *ControllerSpreadFilter) listControllerGroupPods(ctx context.Context, namespace, groupID string) ([]*v1.Pod, map[string]int32, error) {
+	logger := klog.FromContext(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	allPods, err := csf.podLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var groupPods []*v1.Pod
+	members := make(map[string]bool)
+	desired := make(map[string]int32)
+	for i, p := range allPods {
+		if i%listContextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+		if !csf.isActivePod(p) || csf.ownedBySuspendedJob(p) {
+			continue
+		}
+		controller, ok := csf.resolveTopOwner(logger, p)
+		if !ok {
+			continue
+		}
+		member, seen := members[controller.UID]
+		if !seen {
+			controllerDesired, annotations, err := csf.getControllerSpec(logger, namespace, controller)
+			member = err == nil && controllerGroupID(annotations, controller) == groupID
+			members[controller.UID] = member
+			if member {
+				desired[controller.UID] = controllerDesired
+			}
+		}
+		if member {
+			groupPods = append(groupPods, p)
+		}
+	}
+	return groupPods, desired, nil
+}
+
+// groupDesired returns the sum of the desired counts of the group's controllers, using own for
+// the controller of the pod being scheduled, which may not have active pods yet.
+func groupDesired(desired map[string]int32, controller ControllerInfo, own int32) int32 {
+	total := own
+	for uid, count := range desired {
+		if uid != controller.UID {
+			total += count
+		}
+	}
+	return total
+}
+
+// inControllerGroupOf reports whether the top-level controller of p is in the controller group of
+// the controller.
+func (csf *ControllerSpreadFilter) inControllerGroupOf(logger klog.Logger, namespace string, controller ControllerInfo, p *v1.Pod) bool {
+	if controller.Type == LabelGroupType {
+		return false
+	}
+	_, annotations, err := csf.getControllerSpec(logger, namespace, controller)
+	if err != nil {
+		return false
+	}
+	groupID := controllerGroupID(annotations, controller)
+	if groupID == "" {
+		return false
+	}
+	peerController, ok := csf.resolveTopOwner(logger, p)
+	if !ok {
+		return false
+	}
+	_, peerAnnotations, err := csf.getControllerSpec(logger, namespace, peerController)
+	return err == nil && controllerGroupID(peerAnnotations, peerController) == groupID
+}
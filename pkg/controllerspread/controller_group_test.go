@@ -0,0 +1,89 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGroupDesired(t *testing.T) {
+	leader := ControllerInfo{Type: DeploymentType, Name: "leader", UID: string(testUID("leader"))}
+	tests := []struct {
+		name    string
+		desired map[string]int32
+		own     int32
+		want    int32
+	}{
+		{
+			name: "no members with pods",
+			own:  1,
+			want: 1,
+		},
+		{
+			name:    "other members",
+			desired: map[string]int32{string(testUID("follower")): 2, string(testUID("observer")): 3},
+			own:     1,
+			want:    6,
+		},
+		{
+			name:    "own count over the listed one",
+			desired: map[string]int32{leader.UID: 5, string(testUID("follower")): 2},
+			own:     1,
+			want:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupDesired(tt.desired, leader, tt.own); got != tt.want {
+				t.Errorf("groupDesired() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterControllerGroup(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		// leaderGroup and followerGroup are the group-id annotations of the Deployments.
+		leaderGroup   string
+		followerGroup string
+		want          []string
+	}{
+		{
+			name: "no controller group",
+			want: []string{"node-a", "node-c"},
+		},
+		{
+			name:          "same controller group",
+			leaderGroup:   "db",
+			followerGroup: "db",
+			want:          []string{"node-c"},
+		},
+		{
+			name:          "different controller groups",
+			leaderGroup:   "db",
+			followerGroup: "cache",
+			want:          []string{"node-a", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := func(groupID string) map[string]string {
+				a := map[string]string{minHostsAnnotationKey: "3"}
+				if groupID != "" {
+					a[groupIDAnnotationKey] = groupID
+				}
+				return a
+			}
+			objs := makeDeploymentPods(makeDeployment("leader", 1, annotations(tt.leaderGroup)), "node-a")
+			objs = append(objs, makeDeploymentPods(makeDeployment("follower", 2, annotations(tt.followerGroup)), "node-b")...)
+			pod := makePod("follower-new", "", ownerRef(ReplicaSetType, "follower-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,33 +1,49 @@
 // pkg/controllerspread/controller_spread.go
 //
 // Package controllerspread implements an out-of-tree scheduler plugin.
-// The ControllerSpreadFilter plugin prevents pods from the same controller (ReplicaSet, StatefulSet,
-// Job, or CronJob) with more than one desired replica/parallelism from being scheduled on a single node.
+// The ControllerSpreadFilter plugin prevents pods from the same controller (Deployment, ReplicaSet,
+// StatefulSet, DaemonSet, Job, CronJob, or ReplicationController) with more than one desired replica/parallelism from being scheduled on a single node.
 // It supports an annotation "controller-spread-scheduler/min-hosts" that specifies the minimum
-// number of distinct hosts (default: 2).
+// number of distinct hosts (default: DefaultMinHosts from the plugin args, or 2).
 package controllerspread
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	// Core API types.
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	// Object metadata.
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	// For runtime conversion.
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	// For label operations.
 	"k8s.io/apimachinery/pkg/labels"
-	// For runtime conversion.
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	// For managing sets.
-	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	// Listers.
-	podlister "k8s.io/client-go/listers/core/v1"
+	daemonSetLister "k8s.io/client-go/listers/apps/v1"
+	deploymentLister "k8s.io/client-go/listers/apps/v1"
 	rsLister "k8s.io/client-go/listers/apps/v1"
 	stsLister "k8s.io/client-go/listers/apps/v1"
-	jobLister "k8s.io/client-go/listers/batch/v1"
+	hpaLister "k8s.io/client-go/listers/autoscaling/v2"
 	cronJobLister "k8s.io/client-go/listers/batch/v1"
+	jobLister "k8s.io/client-go/listers/batch/v1"
+	nodeLister "k8s.io/client-go/listers/core/v1"
+	podlister "k8s.io/client-go/listers/core/v1"
+	pdbLister "k8s.io/client-go/listers/policy/v1"
+	// Informer indexes.
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 	// klog for logging.
 	"k8s.io/klog/v2"
 	// Upstream scheduler framework.
@@ -40,19 +56,219 @@ const (
 
 	// Annotation key for minimum distinct hosts.
 	minHostsAnnotationKey = "controller-spread-scheduler/min-hosts"
+
+	// Annotation key for the maximum number of controller pods on a single node.
+	maxPodsPerNodeAnnotationKey = "controller-spread-scheduler/max-pods-per-node"
+
+	// Annotation key on a pod that exempts it from the spread constraint.
+	disableAnnotationKey = "controller-spread-scheduler/disable"
+
+	// Annotation key on the controller selecting a hard (required) or best-effort (preferred)
+	// spread, overriding the plugin-wide Mode.
+	spreadModeAnnotationKey = "controller-spread-scheduler/mode"
+
+	// Values of the mode annotation.
+	requiredSpreadMode  = "required"
+	preferredSpreadMode = "preferred"
+
+	// Annotation key on the controller requiring each of its pods, up to the desired count, on
+	// a distinct node regardless of min-hosts.
+	strictAnnotationKey = "controller-spread-scheduler/strict"
+
+	// defaultMinHosts is the minimum number of distinct hosts used when neither the
+	// annotation nor DefaultMinHosts in the plugin args is set.
+	defaultMinHosts = 2
+
+	// listContextCheckInterval is how many pods are examined between checks of the context
+	// while scanning for the controller's pods.
+	listContextCheckInterval = 256
+
+	// defaultMaxOwnerChainDepth is how many owners above the pod's direct owner are followed
+	// when resolving the top-level controller (e.g. ReplicaSet -> Deployment).
+	defaultMaxOwnerChainDepth = 2
+)
+
+// Mode controls whether the plugin enforces its decisions.
+type Mode string
+
+const (
+	// EnforceMode rejects nodes that violate the spread constraint.
+	EnforceMode Mode = "Enforce"
+	// ObserveMode computes decisions and reports would-be rejections through logs and
+	// metrics, but never rejects a node.
+	ObserveMode Mode = "Observe"
+)
+
+// ErrorPolicy controls how errors that prevent the spread check are handled.
+type ErrorPolicy string
+
+const (
+	// OnErrorOpen schedules the pod without the spread constraint (fail open).
+	OnErrorOpen ErrorPolicy = "Open"
+	// OnErrorClosed fails the scheduling attempt with a retriable error, so that the pod stays
+	// pending rather than possibly violating the spread (fail closed).
+	OnErrorClosed ErrorPolicy = "Closed"
 )
 
 // ControllerSpreadArgs holds configuration parameters for the plugin.
-type ControllerSpreadArgs struct{}
+type ControllerSpreadArgs struct {
+	// PluginName is the name returned by Name() and used in logs, events and metrics. It must
+	// match the name under which the plugin is registered and enabled in the profile.
+	// Defaults to ControllerSpreadFilter.
+	PluginName string `json:"pluginName,omitempty"`
+	// DefaultMinHosts is the minimum number of distinct hosts used when a controller has no
+	// valid min-hosts annotation. Must be at least 2. Defaults to 2.
+	DefaultMinHosts int32 `json:"defaultMinHosts,omitempty"`
+	// JobCompletionsWindow, when set, raises the desired count of a Job to its completions, capped
+	// at this value, if they exceed its parallelism. See job_completions.go.
+	JobCompletionsWindow int32 `json:"jobCompletionsWindow,omitempty"`
+	// EventDrivenCounts keeps the per-node placements of pods in memory, updated by pod informer
+	// events, so that PreFilter does not list the pods of a controller in every cycle.
+	EventDrivenCounts bool `json:"eventDrivenCounts,omitempty"`
+	// SpreadGate is the name of a pod scheduling gate that marks a controller as not yet ready to
+	// spread: while any of its pods carries the gate, its other pods are placed freely.
+	SpreadGate string `json:"spreadGate,omitempty"`
+	// NamespaceDefaults overrides DefaultMinHosts for the controllers of the named namespaces.
+	// The min-hosts annotation of a controller still takes precedence.
+	NamespaceDefaults map[string]int32 `json:"namespaceDefaults,omitempty"`
+	// TypeDefaults overrides DefaultMinHosts for the controllers of the given types, e.g. 3 for
+	// StatefulSets. NamespaceDefaults and the min-hosts annotation take precedence.
+	TypeDefaults map[ControllerType]int32 `json:"typeDefaults,omitempty"`
+	// OpenKruise enables spreading for pods of OpenKruise CloneSets and Advanced StatefulSets
+	// (apps.kruise.io), read through dynamic informers.
+	OpenKruise bool `json:"openKruise,omitempty"`
+	// CustomControllers lists user-defined controller kinds (e.g. CRDs) that are treated like
+	// the built-in controllers.
+	CustomControllers []CustomControllerConfig `json:"customControllers,omitempty"`
+	// ScaleGroupResources are group resources, e.g. "rollouts.argoproj.io", of scalable controllers
+	// whose desired replica count is read from their scale subresource. Their kinds are resolved
+	// through API discovery when the plugin starts.
+	ScaleGroupResources []string `json:"scaleGroupResources,omitempty"`
+	// EnabledControllerTypes restricts spread enforcement to the listed controller types,
+	// including custom controller kinds. Empty enables all types.
+	EnabledControllerTypes []ControllerType `json:"enabledControllerTypes,omitempty"`
+	// NamespaceSelector restricts spreading to pods in namespaces whose labels match it.
+	// Nil selects all namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ExcludedNodeSelector selects nodes, e.g. build or CI nodes, that are not valid spread
+	// domains. Pods on them are not counted and placing a pod on them is not checked. Nil
+	// excludes no nodes.
+	ExcludedNodeSelector *metav1.LabelSelector `json:"excludedNodeSelector,omitempty"`
+	// Preset expands into a combination of args for a common spreading policy. HA requires
+	// spreading across zones and prefers spreading across nodes. Explicit args take precedence.
+	// Empty applies no preset.
+	Preset Preset `json:"preset,omitempty"`
+	// Mode is either Enforce or Observe. Defaults to Enforce.
+	Mode Mode `json:"mode,omitempty"`
+	// OnError is Open or Closed and applies to errors that prevent the spread check, such as
+	// an unresolvable namespace or an invalid controller spec. Defaults to Open.
+	OnError ErrorPolicy `json:"onError,omitempty"`
+	// MaxOwnerChainDepth limits how many owner references are followed above the pod's
+	// direct owner. It guards against cyclic owner references. Defaults to 2.
+	MaxOwnerChainDepth int32 `json:"maxOwnerChainDepth,omitempty"`
+	// TopologyKeys are node labels, ordered from the coarsest to the finest level, across
+	// which the pods of controllers without a topology-key annotation are spread. Every level
+	// must reach its minimum spread. Empty spreads across hostnames.
+	TopologyKeys []string `json:"topologyKeys,omitempty"`
+	// TopologyTaintKey is a node taint key whose values are counted as spread domains, e.g. to
+	// spread across maintenance domains. Nodes without the taint form a single untainted domain.
+	// The taint level is added above the TopologyKeys levels. Empty disables it.
+	TopologyTaintKey string `json:"topologyTaintKey,omitempty"`
+	// RequireResource is a resource name, e.g. "nvidia.com/gpu". When set, only pods whose
+	// containers request the resource are spread, and only such peers are counted. Empty spreads
+	// all pods.
+	RequireResource string `json:"requireResource,omitempty"`
+	// IgnoreCordonedNodes excludes the pods on cordoned (unschedulable) nodes from the spread
+	// accounting, e.g. while the nodes are drained.
+	IgnoreCordonedNodes bool `json:"ignoreCordonedNodes,omitempty"`
+	// ReservedNodeAnnotation is a node annotation key whose value names the tenant the node is
+	// reserved for. Nodes reserved for another tenant than the pod's are neither counted nor
+	// candidates. Empty disables reservations.
+	ReservedNodeAnnotation string `json:"reservedNodeAnnotation,omitempty"`
+	// TenantLabel is the pod label key whose value is the pod's tenant for ReservedNodeAnnotation.
+	// Empty means pods have no tenant, so every reserved node is excluded.
+	TenantLabel string `json:"tenantLabel,omitempty"`
+	// AnnotateAchievedSpread annotates each spread pod in PreBind with the number of distinct
+	// domains its controller's pods span once it is bound. Failed patches do not fail binding.
+	AnnotateAchievedSpread bool `json:"annotateAchievedSpread,omitempty"`
+	// DebugEndpoint is the address, e.g. ":10260", of a read-only HTTP endpoint serving the
+	// tracked spread state as JSON. Empty disables the endpoint.
+	DebugEndpoint string `json:"debugEndpoint,omitempty"`
+	// DomainWeightsConfigMap references a ConfigMap assigning relative weights to the domains
+	// of a topology key. Score then spreads pods across these domains in proportion to their
+	// weight. Nil disables domain weights.
+	DomainWeightsConfigMap *ConfigMapReference `json:"domainWeightsConfigMap,omitempty"`
+	// GroupJobsByCronJob groups the pods of all Jobs created by a CronJob with the CronJob,
+	// instead of spreading the pods of each Job separately. Defaults to false.
+	GroupJobsByCronJob bool `json:"groupJobsByCronJob,omitempty"`
+	// HPAAware uses the desired replicas of a HorizontalPodAutoscaler targeting the controller
+	// as its desired count when they exceed the controller's replica count. Defaults to false.
+	HPAAware bool `json:"hpaAware,omitempty"`
+	// PDBAware raises the required number of domains of a controller so that losing any one
+	// domain keeps the minAvailable of a PodDisruptionBudget selecting its pods.
+	PDBAware bool `json:"pdbAware,omitempty"`
+	// VPAAware relaxes the spread of pods replacing a peer a VerticalPodAutoscaler evicted, like
+	// during a scale-up grace period. Defaults to false.
+	VPAAware bool `json:"vpaAware,omitempty"`
+	// VPAUpdateWindow is how long after a VerticalPodAutoscaler evicted a pod its replacement
+	// gets the VPAAware relaxation. Defaults to 10m.
+	VPAUpdateWindow metav1.Duration `json:"vpaUpdateWindow,omitempty"`
+	// MaxConcurrentBinds caps the number of pods of a controller that bind at the same time;
+	// further pods wait in Permit. 0 disables the throttle.
+	MaxConcurrentBinds int32 `json:"maxConcurrentBinds,omitempty"`
+	// ConsistentReadQPS enables listing the pods of controllers with the consistent-read annotation
+	// from the API server, at most this many times per second across all controllers. 0 disables
+	// consistent reads.
+	ConsistentReadQPS float64 `json:"consistentReadQPS,omitempty"`
+	// RequeueBatchSize caps the number of rejected pods of a controller that a peer event moves
+	// back to the scheduling queue, so that a burst of rejected pods is retried in waves. It needs
+	// the SchedulerQueueingHints feature. 0 queues all of them.
+	RequeueBatchSize int32 `json:"requeueBatchSize,omitempty"`
+	// BindWaitTimeout is how long a pod waits in Permit for a bind slot. Defaults to 1m.
+	BindWaitTimeout metav1.Duration `json:"bindWaitTimeout,omitempty"`
+	// MaxRejectRate is the fraction, between 0 and 1, of Filter evaluations in a window that may
+	// be rejected by the spread constraint. Above it, the plugin fails open for the next window.
+	// 0 disables the circuit breaker.
+	MaxRejectRate float64 `json:"maxRejectRate,omitempty"`
+	// RejectRateWindow is the window over which the rejection rate is measured. Defaults to 1m.
+	RejectRateWindow metav1.Duration `json:"rejectRateWindow,omitempty"`
+	// RejectionLogInterval is the minimum interval between the verbosity 2 summaries of the
+	// nodes rejected for a controller. Defaults to 1m.
+	RejectionLogInterval metav1.Duration `json:"rejectionLogInterval,omitempty"`
+	// CountedPhases are the pod phases in which a pod occupies its node for spreading.
+	// Defaults to Running and Pending.
+	CountedPhases []v1.PodPhase `json:"countedPhases,omitempty"`
+	// ExternalPolicyEndpoint is the URL of an external placement service that makes the final
+	// spread decision in Filter. Empty disables delegation.
+	ExternalPolicyEndpoint string `json:"externalPolicyEndpoint,omitempty"`
+	// ExternalPolicyTimeout bounds each call to the external policy endpoint. Defaults to 1s.
+	ExternalPolicyTimeout metav1.Duration `json:"externalPolicyTimeout,omitempty"`
+	// ExternalPolicyFailurePolicy is Ignore (accept the node) or Fail (reject the node) when
+	// the external policy endpoint fails. Defaults to Ignore.
+	ExternalPolicyFailurePolicy FailurePolicy `json:"externalPolicyFailurePolicy,omitempty"`
+}
 
 // ControllerType represents a type of controller.
 type ControllerType string
 
 const (
+	DaemonSetType   ControllerType = "DaemonSet"
+	DeploymentType  ControllerType = "Deployment"
 	ReplicaSetType  ControllerType = "ReplicaSet"
 	StatefulSetType ControllerType = "StatefulSet"
 	JobType         ControllerType = "Job"
 	CronJobType     ControllerType = "CronJob"
+
+	// ReplicationControllerType is the legacy core/v1 predecessor of ReplicaSet.
+	ReplicationControllerType ControllerType = "ReplicationController"
+
+	// LabelGroupType groups pods by the value of a label instead of by owner reference.
+	LabelGroupType ControllerType = "LabelGroup"
+
+	// CloneSetType and AdvancedStatefulSetType are the OpenKruise workloads, see OpenKruise in
+	// ControllerSpreadArgs.
+	CloneSetType            ControllerType = "CloneSet"
+	AdvancedStatefulSetType ControllerType = "AdvancedStatefulSet"
 )
 
 // ControllerInfo holds identifying information about a controller.
@@ -60,44 +276,317 @@ type ControllerInfo struct {
 	Type ControllerType
 	UID  string
 	Name string
+	// GroupLabel is the label key of a LabelGroupType group, whose value is Name.
+	GroupLabel string
 }
 
 // ControllerSpreadFilter implements the framework.Plugin interface.
 type ControllerSpreadFilter struct {
-	podLister     podlister.PodLister
-	rsLister      rsLister.ReplicaSetLister
-	stsLister     stsLister.StatefulSetLister
-	jobLister     jobLister.JobLister
-	cronJobLister cronJobLister.CronJobLister
-	args          *ControllerSpreadArgs
+	handle           framework.Handle
+	podLister        podlister.PodLister
+	podInformer      cache.SharedIndexInformer
+	rsInformer       cache.SharedIndexInformer
+	daemonSetLister  daemonSetLister.DaemonSetLister
+	deploymentLister deploymentLister.DeploymentLister
+	rsLister         rsLister.ReplicaSetLister
+	stsLister        stsLister.StatefulSetLister
+	jobLister        jobLister.JobLister
+	cronJobLister    cronJobLister.CronJobLister
+	pdbLister        pdbLister.PodDisruptionBudgetLister
+	rcLister         podlister.ReplicationControllerLister
+	nodeLister       nodeLister.NodeLister
+	args             *ControllerSpreadArgs
+	// lostPeers tracks the pods lost to node failures for enforce-on-reschedule; nil with
+	// injected listers.
+	lostPeers *lostPeers
+	// vpaEvictions tracks the pods evicted by a VerticalPodAutoscaler; nil unless VPAAware is set,
+	// and with injected listers.
+	vpaEvictions *lostPeers
+	// hpaLister looks up HorizontalPodAutoscalers; nil unless HPAAware is set.
+	hpaLister hpaLister.HorizontalPodAutoscalerLister
+	// customControllers are the user-defined controllers from the plugin args, keyed by kind.
+	customControllers map[string]*customController
+	// countedPhases is the set of CountedPhases.
+	countedPhases map[v1.PodPhase]bool
+	// enabledTypes is the set of controller types subject to spreading; nil enables all types.
+	enabledTypes map[ControllerType]bool
+	// namespaces restricts spreading to the namespaces matching NamespaceSelector.
+	namespaces *namespaceScope
+	// excludedNodes is the compiled ExcludedNodeSelector; nil excludes no nodes.
+	excludedNodes labels.Selector
+	// events emits FailedSpread events on pods the spread constraint left unschedulable.
+	events *spreadEventRecorder
+	// assumed tracks placements made by Reserve that are not yet visible in the informer cache.
+	assumed *assumedPods
+	// externalPolicy delegates the final decision of Filter; nil when not configured.
+	externalPolicy *externalPolicy
+	// nodeTopology caches node label values resolved through nodeLister; nil with injected listers.
+	nodeTopology *nodeTopologyCache
+	// domainWeights biases Score toward weighted domains; nil when not configured.
+	domainWeights *domainWeightsLoader
+	// caches gates PreFilter until the informer caches have synced.
+	caches *cacheSyncGate
+	// specs caches the desired count and annotations of controllers; nil with injected listers.
+	specs *specCache
+	// counter provides event-driven peer placements; nil when EventDrivenCounts is not set or with
+	// injected listers.
+	counter peerCounter
+	// binds throttles concurrent binds per controller; nil when not configured.
+	binds *bindThrottle
+	// consistentReads rate limits consistent reads; nil when they are disabled.
+	consistentReads flowcontrol.RateLimiter
+	// requeues limits the rejected pods queued per peer event; nil when not configured.
+	requeues *requeueBatcher
+	// breaker fails Filter open while the rejection rate is too high; nil when not configured.
+	breaker *rejectBreaker
+	// rejections rate-limits the per-controller summaries of rejected nodes.
+	rejections *rejectionLogger
+	// scaleUps records recent scale-ups of controllers for the scale-up grace period; nil with
+	// injected listers.
+	scaleUps *scaleUpTracker
+	// tracker keeps the last computed spread per controller for the debug endpoint; nil when
+	// the endpoint is disabled.
+	tracker *spreadTracker
+	// clampedMinHosts holds the UIDs of the controllers whose min-hosts annotation was reported as
+	// exceeding their desired count, so that each is reported once.
+	clampedMinHosts sync.Map
 }
 
+var _ framework.FilterPlugin = &ControllerSpreadFilter{}
+
 // getControllerInfo extracts controller information from a pod's owner references.
-func getControllerInfo(pod *v1.Pod) (ControllerInfo, bool) {
-	for _, ownerRef := range pod.OwnerReferences {
+// Kinds of the configured custom controllers are matched in addition to the built-ins.
+func getControllerInfo(pod *v1.Pod, customControllers map[string]*customController) (ControllerInfo, bool) {
+	return getOwnerInfo(pod.OwnerReferences, customControllers)
+}
+
+// builtinControllerGroups maps the built-in controller kinds to their API group.
+var builtinControllerGroups = map[ControllerType]string{
+	DaemonSetType:             appsv1.GroupName,
+	DeploymentType:            appsv1.GroupName,
+	ReplicaSetType:            appsv1.GroupName,
+	StatefulSetType:           appsv1.GroupName,
+	JobType:                   batchv1.GroupName,
+	CronJobType:               batchv1.GroupName,
+	ReplicationControllerType: v1.GroupName,
+}
+
+// isBuiltinOwner reports whether the owner reference refers to the built-in controller kind,
+// checking the API group of its apiVersion as well as its kind.
+func isBuiltinOwner(ownerRef metav1.OwnerReference, kind ControllerType) bool {
+	if ownerRef.Kind != string(kind) {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	return err == nil && gv.Group == builtinControllerGroups[kind]
+}
+
+// getOwnerInfo returns the known controller among the given owner references, if any.
+func getOwnerInfo(ownerRefs []metav1.OwnerReference, customControllers map[string]*customController) (ControllerInfo, bool) {
+	for _, ownerRef := range ownerRefs {
 		if ownerRef.UID == "" || ownerRef.Name == "" {
 			continue
 		}
-		switch ownerRef.Kind {
-		case string(ReplicaSetType):
-			return ControllerInfo{Type: ReplicaSetType, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
-		case string(StatefulSetType):
-			return ControllerInfo{Type: StatefulSetType, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
-		case string(JobType):
-			return ControllerInfo{Type: JobType, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
-		case string(CronJobType):
-			return ControllerInfo{Type: CronJobType, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
+		if t, ok := openKruiseOwnerType(ownerRef); ok {
+			if _, enabled := customControllers[string(t)]; enabled {
+				return ControllerInfo{Type: t, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
+			}
+			continue
+		}
+		kind := ControllerType(ownerRef.Kind)
+		if _, builtin := builtinControllerGroups[kind]; builtin {
+			// A kind of another API group, e.g. a CRD named Job, is not a built-in controller.
+			if isBuiltinOwner(ownerRef, kind) {
+				return ControllerInfo{Type: kind, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
+			}
+			continue
+		}
+		if cc, ok := customControllers[ownerRef.Kind]; ok && cc.isOwner(ownerRef) {
+			return ControllerInfo{Type: kind, UID: string(ownerRef.UID), Name: ownerRef.Name}, true
 		}
 	}
 	return ControllerInfo{}, false
 }
 
-// parseMinHostsAnnotation parses the annotation value into an int32; defaults to 2.
-func parseMinHostsAnnotation(val string) int32 {
-	if parsed, err := strconv.ParseInt(val, 10, 32); err == nil && parsed >= 2 && parsed <= math.MaxInt32 {
-		return int32(parsed)
+// resolveTopOwner walks the owner chain starting at the pod's direct owner and returns the
+// top-most known controller, so that pods of a Deployment resolve to the Deployment rather
+// than to the ReplicaSet of the current revision. At most MaxOwnerChainDepth hops are followed.
+func (csf *ControllerSpreadFilter) resolveTopOwner(logger klog.Logger, pod *v1.Pod) (ControllerInfo, bool) {
+	controller, ok := getControllerInfo(pod, csf.customControllers)
+	if !ok {
+		return ControllerInfo{}, false
+	}
+	for depth := int32(0); depth < csf.args.MaxOwnerChainDepth; depth++ {
+		ownerRefs, err := csf.ownerReferencesOf(pod.Namespace, controller)
+		if err != nil {
+			logger.V(4).Info("Could not resolve owner chain", "controller", controller.Name, "namespace", pod.Namespace, "err", err)
+			break
+		}
+		parent, ok := getOwnerInfo(ownerRefs, csf.customControllers)
+		if !ok {
+			break
+		}
+		controller = parent
+	}
+	return controller, true
+}
+
+// ownerReferencesOf returns the owner references of the given controller object.
+// Controllers that are never owned by another known controller return no references.
+func (csf *ControllerSpreadFilter) ownerReferencesOf(namespace string, controller ControllerInfo) ([]metav1.OwnerReference, error) {
+	switch controller.Type {
+	case ReplicaSetType:
+		rs, err := csf.rsLister.ReplicaSets(namespace).Get(controller.Name)
+		if err != nil {
+			return nil, err
+		}
+		if string(rs.UID) != controller.UID {
+			return nil, fmt.Errorf("ReplicaSet %s/%s has UID %s, expected %s", namespace, controller.Name, rs.UID, controller.UID)
+		}
+		return rs.OwnerReferences, nil
+	case JobType:
+		if !csf.args.GroupJobsByCronJob {
+			return nil, nil
+		}
+		job, err := csf.jobLister.Jobs(namespace).Get(controller.Name)
+		if err != nil {
+			return nil, err
+		}
+		if string(job.UID) != controller.UID {
+			return nil, fmt.Errorf("Job %s/%s has UID %s, expected %s", namespace, controller.Name, job.UID, controller.UID)
+		}
+		return job.OwnerReferences, nil
+	default:
+		if cc, ok := csf.customControllers[string(controller.Type)]; ok {
+			obj, err := cc.get(namespace, controller)
+			if err != nil {
+				return nil, err
+			}
+			return obj.GetOwnerReferences(), nil
+		}
+	}
+	return nil, nil
+}
+
+// minHostsAnnotation returns the min-hosts annotation of the pod or, if the pod does not carry
+// it, of its controller. Pods created from a template may set their own value, which takes
+// precedence over the controller's.
+func minHostsAnnotation(pod *v1.Pod, controllerAnnotations map[string]string) (string, bool) {
+	if val, exists := pod.Annotations[minHostsAnnotationKey]; exists {
+		return val, true
+	}
+	val, exists := controllerAnnotations[minHostsAnnotationKey]
+	return val, exists
+}
+
+// defaultMinHostsFor returns the min-hosts default of a controller of the type in the namespace:
+// the NamespaceDefaults entry of the namespace, the TypeDefaults entry of the type, or
+// DefaultMinHosts.
+func (csf *ControllerSpreadFilter) defaultMinHostsFor(namespace string, t ControllerType) int32 {
+	if val, ok := csf.args.NamespaceDefaults[namespace]; ok {
+		return val
+	}
+	if val, ok := csf.args.TypeDefaults[t]; ok {
+		return val
+	}
+	return csf.args.DefaultMinHosts
+}
+
+// parseMinHostsAnnotation parses the annotation value into an int32. A percentage such as "50%"
+// is taken of the desired count, rounded up and clamped to [2, desired]. Invalid values return
+// defaultValue along with an error, so that callers can report them.
+func parseMinHostsAnnotation(val string, desired, defaultValue int32) (int32, error) {
+	if pct, ok := strings.CutSuffix(val, "%"); ok {
+		parsed, err := strconv.ParseInt(pct, 10, 32)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			return defaultValue, fmt.Errorf("invalid percentage %q: must be between 1%% and 100%%", val)
+		}
+		// Round up, so that e.g. 33% of 10 replicas requires 4 hosts.
+		hosts := int32((int64(desired)*parsed + 99) / 100)
+		return max(2, min(hosts, desired)), nil
+	}
+	parsed, err := strconv.ParseInt(val, 10, 32)
+	if err != nil || parsed < 2 || parsed > math.MaxInt32 {
+		return defaultValue, fmt.Errorf("invalid value %q: must be an integer of at least 2 or a percentage", val)
+	}
+	return int32(parsed), nil
+}
+
+// reportMinHostsClamped logs, once per controller, that the min-hosts annotation exceeds the
+// desired count and is lowered to it, and counts it in the minhosts_clamped_total metric.
+func (csf *ControllerSpreadFilter) reportMinHostsClamped(logger klog.Logger, pod *v1.Pod, controller ControllerInfo, minHosts, desired int32) {
+	if _, reported := csf.clampedMinHosts.LoadOrStore(controller.UID, struct{}{}); reported {
+		return
+	}
+	logger.Info("min-hosts annotation exceeds the desired count, requiring the desired count instead", "namespace", pod.Namespace,
+		"controllerType", controller.Type, "controller", controller.Name, "minHosts", minHosts, "desired", desired)
+	minHostsClamped.WithLabelValues(csf.Name()).Inc()
+}
+
+// reportInvalidAnnotation logs an annotation value that is ignored in favor of its default and
+// counts it in the invalid_annotation_total metric.
+func (csf *ControllerSpreadFilter) reportInvalidAnnotation(logger klog.Logger, annotation string, controller ControllerInfo, err error) {
+	logger.V(2).Info("Ignoring invalid annotation, using the default", "annotation", annotation, "controllerType", controller.Type,
+		"controller", controller.Name, "err", err)
+	invalidAnnotations.WithLabelValues(csf.Name(), annotation).Inc()
+}
+
+// parseMaxPodsPerNodeAnnotation parses the annotation value into a positive int32.
+// It returns false for values that are not a positive integer.
+func parseMaxPodsPerNodeAnnotation(val string) (int32, bool) {
+	if parsed, err := strconv.ParseInt(val, 10, 32); err == nil && parsed > 0 {
+		return int32(parsed), true
+	}
+	return 0, false
+}
+
+// isSpreadDisabled reports whether the pod opted out of the spread constraint through its own
+// disable annotation. Values that are not a valid bool are logged and ignored.
+func isSpreadDisabled(logger klog.Logger, pod *v1.Pod) bool {
+	val, exists := pod.Annotations[disableAnnotationKey]
+	if !exists {
+		return false
+	}
+	disabled, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", disableAnnotationKey, "value", val, "pod", klog.KObj(pod))
+		return false
+	}
+	return disabled
+}
+
+// isStrictSpread reports whether the controller requires each pod on a distinct node through its
+// strict annotation. Values that are not a valid bool are logged and ignored.
+func isStrictSpread(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	val, exists := annotations[strictAnnotationKey]
+	if !exists {
+		return false
+	}
+	strict, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", strictAnnotationKey, "value", val, "controller", controller.Name)
+		return false
+	}
+	return strict
+}
+
+// parseSpreadModeAnnotation returns the enforcement mode of the controller and whether its spread
+// is only preferred. "required" enforces the spread even in Observe mode, "preferred" never
+// rejects a node and leaves spreading to Score. Without a valid annotation, defaultMode applies.
+func parseSpreadModeAnnotation(logger klog.Logger, annotations map[string]string, defaultMode Mode) (Mode, bool) {
+	val, exists := annotations[spreadModeAnnotationKey]
+	if !exists {
+		return defaultMode, false
+	}
+	switch val {
+	case requiredSpreadMode:
+		return EnforceMode, false
+	case preferredSpreadMode:
+		return defaultMode, true
+	default:
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", spreadModeAnnotationKey, "value", val)
+		return defaultMode, false
 	}
-	return 2
 }
 
 // min returns the smaller of two int32 values.
@@ -109,8 +598,16 @@ func min(a, b int32) int32 {
 }
 
 // New is the factory for ControllerSpreadFilter.
-// It implements framework.PluginFactory.
+// The background work of the plugin, such as the debug endpoint, runs for the life of the
+// process; NewWithContext stops it with the scheduler.
 func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	return NewWithContext(context.Background(), obj, handle)
+}
+
+// NewWithContext is the factory for ControllerSpreadFilter.
+// It implements frameworkruntime.PluginFactory. The background work of the plugin, such as the
+// debug endpoint, stops when ctx is done.
+func NewWithContext(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	args := &ControllerSpreadArgs{}
 	if obj != nil {
 		uObj, ok := obj.(*unstructured.Unstructured)
@@ -121,39 +618,258 @@ func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error)
 			}
 		}
 	}
+	return newWithListers(ctx, args, handle, nil)
+}
+
+// newWithListers creates the plugin from the args, which it defaults and validates, reading from
+// the listers if not nil and from the shared informers of the handle otherwise.
+func newWithListers(ctx context.Context, args *ControllerSpreadArgs, handle framework.Handle, listers *Listers) (framework.Plugin, error) {
+	RegisterMetrics()
+
+	SetDefaults_ControllerSpreadArgs(args)
+	if err := ValidateControllerSpreadArgs(nil, args); err != nil {
+		return nil, fmt.Errorf("invalid ControllerSpreadArgs: %w", err)
+	}
+	customControllers, err := newCustomControllers(args, handle)
+	if err != nil {
+		return nil, err
+	}
+	customControllers, err = addScaleControllers(args, handle, customControllers)
+	if err != nil {
+		return nil, err
+	}
+	enabledControllerTypes := newEnabledControllerTypes(args.EnabledControllerTypes)
+	namespaces := &namespaceScope{}
+	if args.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(args.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector: %v", err)
+		}
+		namespaces.selector = selector
+	}
+	var excludedNodes labels.Selector
+	if args.ExcludedNodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(args.ExcludedNodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excludedNodeSelector: %v", err)
+		}
+		excludedNodes = selector
+	}
+
+	domainWeights := newDomainWeightsLoader(args.DomainWeightsConfigMap, handle)
+	var podInformer, rsInformer cache.SharedIndexInformer
+	caches := &cacheSyncGate{}
+	var specs *specCache
+	var scaleUps *scaleUpTracker
+	var nodeTopology *nodeTopologyCache
+	injected := listers != nil
+	if !injected {
+		fromHandle := ListersFromHandle(handle)
+		if args.NamespaceSelector != nil {
+			fromHandle.Namespaces = handle.SharedInformerFactory().Core().V1().Namespaces().Lister()
+		}
+		if args.HPAAware {
+			fromHandle.HorizontalPodAutoscalers = handle.SharedInformerFactory().Autoscaling().V2().HorizontalPodAutoscalers().Lister()
+		}
+		listers = &fromHandle
+		podInformer = addOwnerUIDIndex(handle)
+		rsInformer = addReplicaSetOwnerUIDIndex(handle)
+		caches = newCacheSyncGate(handle, args, customControllers, domainWeights)
+		specs = newSpecCache(handle)
+		var err error
+		if scaleUps, err = newScaleUpTracker(ctx, handle); err != nil {
+			return nil, err
+		}
+		nodeTopology = newNodeTopologyCache(handle)
+	}
+	if args.NamespaceSelector != nil {
+		if listers.Namespaces == nil {
+			return nil, errors.New("namespaceSelector requires a Namespaces lister")
+		}
+		namespaces.lister = listers.Namespaces
+	}
+	if args.HPAAware && listers.HorizontalPodAutoscalers == nil {
+		return nil, errors.New("hpaAware requires a HorizontalPodAutoscalers lister")
+	}
+
+	csf := &ControllerSpreadFilter{
+		handle:           handle,
+		podLister:        listers.Pods,
+		podInformer:      podInformer,
+		rsInformer:       rsInformer,
+		daemonSetLister:  listers.DaemonSets,
+		deploymentLister: listers.Deployments,
+		rsLister:         listers.ReplicaSets,
+		stsLister:        listers.StatefulSets,
+		jobLister:        listers.Jobs,
+		cronJobLister:    listers.CronJobs,
+		pdbLister:        listers.PodDisruptionBudgets,
+		rcLister:         listers.ReplicationControllers,
+		nodeLister:       listers.Nodes,
+		hpaLister:        listers.HorizontalPodAutoscalers,
+		args:             args,
+
+		customControllers: customControllers,
+		countedPhases:     newCountedPhases(args.CountedPhases),
+		enabledTypes:      enabledControllerTypes,
+		namespaces:        namespaces,
+		excludedNodes:     excludedNodes,
+		events:            newSpreadEventRecorder(handle.EventRecorder()),
+		assumed:           newAssumedPods(),
+		specs:             specs,
+		scaleUps:          scaleUps,
+		caches:            caches,
+		externalPolicy:    newExternalPolicy(args),
+		breaker:           newRejectBreaker(args),
+		rejections:        newRejectionLogger(args),
+		requeues:          newRequeueBatcher(args),
+		consistentReads:   newConsistentReadLimiter(args),
+		binds:             newBindThrottle(ctx, args),
+		nodeTopology:      nodeTopology,
+		domainWeights:     domainWeights,
+	}
+	for _, cc := range customControllers {
+		if cc.scales != nil {
+			csf.specs.invalidateOn(cc.informer)
+		}
+	}
+	if podInformer != nil {
+		csf.lostPeers = newLostPeers(klog.FromContext(ctx), podInformer, csf.resolveTopOwner, lostToNodeFailure, rescheduleWindow)
+		if args.VPAAware {
+			csf.vpaEvictions = newLostPeers(klog.FromContext(ctx), podInformer, csf.resolveTopOwner, evictedByVPA, args.VPAUpdateWindow.Duration)
+		}
+	}
+	if args.EventDrivenCounts && !injected {
+		counter, err := newInformerPeerCounter(ctx, handle, csf.isActivePod)
+		if err != nil {
+			return nil, err
+		}
+		csf.counter = counter
+	}
+	if args.DebugEndpoint != "" {
+		csf.tracker = newSpreadTracker(ctx)
+		if err := csf.serveDebugEndpoint(ctx, args.DebugEndpoint); err != nil {
+			return nil, err
+		}
+	}
+	return csf, nil
+}
+
+// newEnabledControllerTypes returns the configured controller types as a set.
+// It returns nil when no types are configured, which enables all types.
+func newEnabledControllerTypes(types []ControllerType) map[ControllerType]bool {
+	if len(types) == 0 {
+		return nil
+	}
+	enabled := make(map[ControllerType]bool, len(types))
+	for _, t := range types {
+		enabled[t] = true
+	}
+	return enabled
+}
+
+// newCountedPhases returns the counted phases as a set.
+func newCountedPhases(phases []v1.PodPhase) map[v1.PodPhase]bool {
+	counted := make(map[v1.PodPhase]bool, len(phases))
+	for _, phase := range phases {
+		counted[phase] = true
+	}
+	return counted
+}
 
-	return &ControllerSpreadFilter{
-		podLister:     handle.SharedInformerFactory().Core().V1().Pods().Lister(),
-		rsLister:      handle.SharedInformerFactory().Apps().V1().ReplicaSets().Lister(),
-		stsLister:     handle.SharedInformerFactory().Apps().V1().StatefulSets().Lister(),
-		jobLister:     handle.SharedInformerFactory().Batch().V1().Jobs().Lister(),
-		cronJobLister: handle.SharedInformerFactory().Batch().V1().CronJobs().Lister(),
-		args:          args,
-	}, nil
+// isControllerTypeEnabled reports whether pods of the controller type are subject to spreading.
+func (csf *ControllerSpreadFilter) isControllerTypeEnabled(t ControllerType) bool {
+	return csf.enabledTypes == nil || csf.enabledTypes[t]
 }
 
-// Name returns the name of the plugin.
+// Name returns the name of the plugin instance, see ControllerSpreadArgs.PluginName.
 func (csf *ControllerSpreadFilter) Name() string {
-	return Name
+	return csf.args.PluginName
 }
 
-// Filter is invoked during scheduling.
-func (csf *ControllerSpreadFilter) Filter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
-	controller, ok := getControllerInfo(pod)
-	if !ok {
-		return framework.NewStatus(framework.Success)
+// errInvalidSpec marks controller spec lookups that fail for a reason a retry cannot fix, such as
+// a malformed annotation or a controller that was replaced by another object of the same name.
+var errInvalidSpec = errors.New("invalid controller spec")
+
+// errorStatus returns the status for an error that prevents the spread check. With the Open
+// policy it returns a status of the open code, i.e. Skip in PreFilter; with the Closed policy
+// a retriable Error.
+func (csf *ControllerSpreadFilter) errorStatus(err error, open framework.Code) *framework.Status {
+	if csf.args.OnError == OnErrorClosed {
+		return framework.AsStatus(err)
 	}
+	return framework.NewStatus(open)
+}
 
+// isRetriableSpecError reports whether a failed spec lookup may succeed when retried. A controller
+// that is not found or whose spec is invalid is not spread, so the pod may be scheduled; any other
+// error, e.g. from the API server, is retried rather than scheduling the pod unconstrained.
+func isRetriableSpecError(err error) bool {
+	return !apierrors.IsNotFound(err) && !errors.Is(err, errInvalidSpec)
+}
+
+// getControllerSpec returns the desired replica/parallelism count and the annotations of the
+// controller, served from the spec cache when possible. With HPAAware, the desired count
+// accounts for an HPA targeting the controller, see hpaDesiredReplicas.
+func (csf *ControllerSpreadFilter) getControllerSpec(logger klog.Logger, namespace string, controller ControllerInfo) (int32, map[string]string, error) {
+	if !isCacheableControllerType(controller.Type) && !csf.isScaleController(controller.Type) {
+		desired, annotations, err := csf.readControllerSpec(logger, namespace, controller)
+		if err != nil {
+			return 0, nil, err
+		}
+		return csf.hpaDesiredReplicas(logger, namespace, controller, desired), annotations, nil
+	}
+	now := time.Now()
+	if spec, ok := csf.specs.get(controller.UID, now); ok {
+		return csf.hpaDesiredReplicas(logger, namespace, controller, spec.desired), spec.annotations, nil
+	}
+	desired, annotations, err := csf.readControllerSpec(logger, namespace, controller)
+	if err != nil {
+		return 0, nil, err
+	}
+	csf.specs.set(controller.UID, desired, annotations, now)
+	return csf.hpaDesiredReplicas(logger, namespace, controller, desired), annotations, nil
+}
+
+// readControllerSpec reads the desired replica/parallelism count and the annotations of the
+// controller from the listers. For a DaemonSet, the desired count is the number of nodes matching
+// its node selector.
+func (csf *ControllerSpreadFilter) readControllerSpec(logger klog.Logger, namespace string, controller ControllerInfo) (int32, map[string]string, error) {
 	var desired int32
-	minHostsVal := int32(2)
-	annotations := map[string]string{}
+	var annotations map[string]string
 
 	switch controller.Type {
+	case DaemonSetType:
+		ds, err := csf.daemonSetLister.DaemonSets(namespace).Get(controller.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		nodeInfos, err := csf.handle.SnapshotSharedLister().NodeInfos().List()
+		if err != nil {
+			return 0, nil, err
+		}
+		selector := labels.SelectorFromSet(ds.Spec.Template.Spec.NodeSelector)
+		for _, nodeInfo := range nodeInfos {
+			if node := nodeInfo.Node(); node != nil && selector.Matches(labels.Set(node.Labels)) {
+				desired++
+			}
+		}
+		annotations = ds.Annotations
+	case DeploymentType:
+		deploy, err := csf.deploymentLister.Deployments(namespace).Get(controller.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		if deploy.Spec.Replicas != nil {
+			desired = *deploy.Spec.Replicas
+		} else {
+			desired = 1
+		}
+		annotations = deploy.Annotations
 	case ReplicaSetType:
-		rs, err := csf.rsLister.ReplicaSets(pod.Namespace).Get(controller.Name)
+		rs, err := csf.rsLister.ReplicaSets(namespace).Get(controller.Name)
 		if err != nil {
-			klog.ErrorS(err, "Could not retrieve ReplicaSet", "controller", controller.Name, "namespace", pod.Namespace)
-			return framework.NewStatus(framework.Success)
+			return 0, nil, err
 		}
 		if rs.Spec.Replicas != nil {
 			desired = *rs.Spec.Replicas
@@ -162,10 +878,9 @@ func (csf *ControllerSpreadFilter) Filter(ctx context.Context, cycleState *frame
 		}
 		annotations = rs.Annotations
 	case StatefulSetType:
-		sts, err := csf.stsLister.StatefulSets(pod.Namespace).Get(controller.Name)
+		sts, err := csf.stsLister.StatefulSets(namespace).Get(controller.Name)
 		if err != nil {
-			klog.ErrorS(err, "Could not retrieve StatefulSet", "controller", controller.Name, "namespace", pod.Namespace)
-			return framework.NewStatus(framework.Success)
+			return 0, nil, err
 		}
 		if sts.Spec.Replicas != nil {
 			desired = *sts.Spec.Replicas
@@ -174,95 +889,216 @@ func (csf *ControllerSpreadFilter) Filter(ctx context.Context, cycleState *frame
 		}
 		annotations = sts.Annotations
 	case JobType:
-		job, err := csf.jobLister.Jobs(pod.Namespace).Get(controller.Name)
+		job, err := csf.jobLister.Jobs(namespace).Get(controller.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		desired = csf.jobDesiredReplicas(job.Spec)
+		annotations = csf.jobAnnotations(logger, job)
+	case ReplicationControllerType:
+		rc, err := csf.rcLister.ReplicationControllers(namespace).Get(controller.Name)
 		if err != nil {
-			klog.ErrorS(err, "Could not retrieve Job", "controller", controller.Name, "namespace", pod.Namespace)
-			return framework.NewStatus(framework.Success)
+			return 0, nil, err
 		}
-		if job.Spec.Parallelism != nil {
-			desired = *job.Spec.Parallelism
+		if rc.Spec.Replicas != nil {
+			desired = *rc.Spec.Replicas
 		} else {
 			desired = 1
 		}
-		annotations = job.Annotations
+		annotations = rc.Annotations
 	case CronJobType:
-		cj, err := csf.cronJobLister.CronJobs(pod.Namespace).Get(controller.Name)
+		cj, err := csf.cronJobLister.CronJobs(namespace).Get(controller.Name)
 		if err != nil {
-			klog.ErrorS(err, "Could not retrieve CronJob", "controller", controller.Name, "namespace", pod.Namespace)
-			return framework.NewStatus(framework.Success)
-		}
-		if cj.Spec.JobTemplate.Spec.Parallelism != nil {
-			desired = *cj.Spec.JobTemplate.Spec.Parallelism
-		} else {
-			desired = 1
+			return 0, nil, err
 		}
+		desired = csf.jobDesiredReplicas(cj.Spec.JobTemplate.Spec)
 		annotations = cj.Annotations
 	default:
-		return framework.NewStatus(framework.Success)
+		cc, ok := csf.customControllers[string(controller.Type)]
+		if !ok {
+			return 0, nil, fmt.Errorf("%w: unsupported controller type %q", errInvalidSpec, controller.Type)
+		}
+		obj, err := cc.get(namespace, controller)
+		if err != nil {
+			return 0, nil, err
+		}
+		desired, err = cc.desiredReplicas(obj)
+		if err != nil {
+			return 0, nil, err
+		}
+		annotations = obj.GetAnnotations()
 	}
+	return desired, annotations, nil
+}
 
-	if val, exists := annotations[minHostsAnnotationKey]; exists {
-		minHostsVal = parseMinHostsAnnotation(val)
+// listControllerPods returns the active pods in the namespace that belong to the controller.
+// Terminating and finished pods are skipped, see isActivePod, as are pods of suspended Jobs.
+// The scan stops with the context's error when the context is cancelled or its deadline passes.
+func (csf *ControllerSpreadFilter) listControllerPods(ctx context.Context, namespace string, controller ControllerInfo) ([]*v1.Pod, error) {
+	logger := klog.FromContext(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-
-	requiredHosts := min(desired, minHostsVal)
-	if desired <= 1 {
-		return framework.NewStatus(framework.Success)
+	var allPods []*v1.Pod
+	var err error
+	if controller.Type == LabelGroupType {
+		allPods, err = csf.podLister.Pods(namespace).List(labelGroupSelector(controller))
+	} else {
+		allPods, err = csf.candidatePods(logger, namespace, controller)
 	}
-
-	allPods, err := csf.podLister.Pods(pod.Namespace).List(labels.Everything())
 	if err != nil {
-		klog.ErrorS(err, "Error listing pods", "namespace", pod.Namespace)
-		return framework.NewStatus(framework.Error, fmt.Sprintf("error listing pods: %v", err))
+		return nil, err
 	}
 
-	var controllerPods []v1.Pod
-	for _, p := range allPods {
-		if isOwnedByController(p, controller) && (p.Status.Phase == v1.PodRunning || p.Status.Phase == v1.PodPending) {
-			controllerPods = append(controllerPods, *p)
+	var controllerPods []*v1.Pod
+	for i, p := range allPods {
+		if i%listContextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if csf.isActivePod(p) && csf.isOwnedByTopController(logger, p, controller) && !csf.ownedBySuspendedJob(p) {
+			controllerPods = append(controllerPods, p)
 		}
 	}
-	if len(controllerPods) <= 1 {
-		return framework.NewStatus(framework.Success)
+	return controllerPods, nil
+}
+
+// isActivePod reports whether the pod occupies its node for spreading. Terminating pods are not
+// active, as the nodes they run on are being emptied (e.g. by a drain). Otherwise the pod is
+// active if its phase is one of the CountedPhases, by default Running and Pending. Succeeded
+// and Failed pods are not active by default: finished Job pods are kept around for their logs
+// but no longer run, which matches the pods the Job controller counts in status.active.
+func (csf *ControllerSpreadFilter) isActivePod(p *v1.Pod) bool {
+	if p.DeletionTimestamp != nil {
+		return false
 	}
+	return csf.countedPhases[p.Status.Phase]
+}
 
-	nodeSet := sets.NewString()
-	for _, p := range controllerPods {
-		if p.Spec.NodeName != "" {
-			nodeSet.Insert(p.Spec.NodeName)
+// Filter is invoked during scheduling. It relies on the state computed by PreFilter, and computes
+// it itself if the plugin is not enabled at the PreFilter extension point. It logs through the
+// contextual logger of ctx, so that log lines carry the values of the scheduling cycle.
+func (csf *ControllerSpreadFilter) Filter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	startTime := time.Now()
+	logger := klog.FromContext(ctx)
+	if isSpreadDisabled(logger, pod) {
+		return framework.NewStatus(framework.Success)
+	}
+	if nodeInfo == nil || nodeInfo.Node() == nil {
+		// The node may have been deleted while the pod was being scheduled.
+		status := framework.NewStatus(framework.Error, "node not found")
+		observeFilter(csf.Name(), "", status, startTime)
+		return status
+	}
+	node := nodeInfo.Node()
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		var status *framework.Status
+		if s, status = csf.computePreFilterState(ctx, cycleState, pod); s == nil {
+			observeFilter(csf.Name(), "", status, startTime)
+			return status
 		}
 	}
-
-	effectiveSpread := nodeSet.Len()
-	if !nodeSet.Has(nodeInfo.Node().Name) {
-		effectiveSpread++
+	if status := csf.filterReservedNode(pod, node); status != nil {
+		// The reservation is not part of the spread; it applies in every mode.
+		logger.V(4).Info("Rejecting node reserved for another tenant", "pod", klog.KObj(pod), "node", node.Name, "reason", status.Message())
+		observeFilter(csf.Name(), s.controller.Type, status, startTime)
+		return status
+	}
+	status := csf.filterNode(logger, s, nodeInfo)
+	if csf.externalPolicy != nil {
+		status = csf.externalPolicy.evaluate(ctx, pod, s, node.Name, status)
+	}
+	if s.preferred && !status.IsSuccess() {
+		logger.V(4).Info("Preferred spread not satisfied, leaving it to Score", "pod", klog.KObj(pod), "node", node.Name,
+			"controllerType", s.controller.Type, "controller", s.controller.Name, "reason", status.Message())
+		status = framework.NewStatus(framework.Success)
 	}
+	if s.mode == ObserveMode && !status.IsSuccess() {
+		logger.V(2).Info("Observe mode: would reject node", "pod", klog.KObj(pod), "node", node.Name,
+			"controllerType", s.controller.Type, "controller", s.controller.Name, "reason", status.Message())
+		observedRejections.WithLabelValues(csf.Name(), string(s.controller.Type)).Inc()
+		status = framework.NewStatus(framework.Success)
+	}
+	status = csf.breaker.apply(logger, pod, status, time.Now())
+	observeFilter(csf.Name(), s.controller.Type, status, startTime)
+	if status.Code() == framework.Unschedulable {
+		csf.rejections.record(logger, pod, s.controller, status.Message(), time.Now())
+	}
+	return status
+}
 
-	if effectiveSpread < int(requiredHosts) {
-		klog.V(4).InfoS("Rejecting scheduling due to minimum host spread constraint",
-			"candidateNode", nodeInfo.Node().Name,
-			"currentSpread", nodeSet.Len(),
-			"requiredHosts", requiredHosts,
-			"controllerUID", controller.UID,
-			"controllerName", controller.Name)
-		return framework.NewStatus(framework.Unschedulable,
-			fmt.Sprintf("must schedule across at least %d distinct nodes", requiredHosts))
+// filterNode checks whether placing the pod on the node satisfies the controller's spread
+// constraint. The logger is the contextual logger of the scheduling cycle.
+func (csf *ControllerSpreadFilter) filterNode(logger klog.Logger, s *controllerSpreadState, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node()
+	if csf.isExcludedNode(node) {
+		// Excluded nodes are outside spread accounting; a pod placed there does not count.
+		return framework.NewStatus(framework.Success)
+	}
+	candidateDomains := make([]string, len(s.levels))
+	for i, level := range s.levels {
+		candidateDomains[i] = topologyDomain(logger, node, level.key)
 	}
 
-	return framework.NewStatus(framework.Success)
+	decision := evaluateSpread(s, node.Name, candidateDomains)
+	if decision.Allowed {
+		return framework.NewStatus(framework.Success)
+	}
+	logger.V(5).Info("Rejecting scheduling due to spread constraint",
+		"candidateNode", node.Name,
+		"podsOnNode", s.nodeCounts[node.Name],
+		"currentSpread", describeSpread(s.levels),
+		"rule", decision.Rule,
+		"reason", decision.Reason,
+		"controllerUID", s.controller.UID,
+		"controllerName", s.controller.Name)
+	return framework.NewStatus(framework.Unschedulable, decision.Message())
 }
 
 func isOwnedByController(pod *v1.Pod, controller ControllerInfo) bool {
-	for _, ownerRef := range pod.OwnerReferences {
-		if ownerRef.Kind == string(controller.Type) && string(ownerRef.UID) == controller.UID {
+	return isOwnedBy(pod.OwnerReferences, controller)
+}
+
+// isOwnedBy reports whether the owner references include the controller.
+func isOwnedBy(ownerRefs []metav1.OwnerReference, controller ControllerInfo) bool {
+	for _, ownerRef := range ownerRefs {
+		if ownerRef.Kind == ownerKind(controller.Type) && string(ownerRef.UID) == controller.UID {
 			return true
 		}
 	}
 	return false
 }
 
+// isOwnedByTopController reports whether the pod belongs to the controller either directly
+// or through its owner chain (e.g. a pod of any ReplicaSet revision of a Deployment).
+// Pods of a label group belong to it when they carry the group label value.
+func (csf *ControllerSpreadFilter) isOwnedByTopController(logger klog.Logger, pod *v1.Pod, controller ControllerInfo) bool {
+	if controller.Type == LabelGroupType {
+		return pod.Labels[controller.GroupLabel] == controller.Name
+	}
+	if isOwnedByController(pod, controller) {
+		return true
+	}
+	top, ok := csf.resolveTopOwner(logger, pod)
+	return ok && top.Type == controller.Type && top.UID == controller.UID
+}
+
 // Export the plugin registry so that your scheduler binary can merge it.
 // Your scheduler must be patched or built to merge this registry into its default registry.
+// To register the plugin under another name as well, add an entry for that name pointing to New
+// and set pluginName in the plugin args to the same name.
+//
+// Deprecated: Use PluginRegistryWithContext, whose factories stop the background work of the
+// plugin with the scheduler.
 var PluginRegistry = map[string]func(runtime.Object, framework.Handle) (framework.Plugin, error){
 	Name: New,
 }
+
+// PluginRegistryWithContext is PluginRegistry with factories implementing
+// frameworkruntime.PluginFactory. To register the plugin under another name as well, add an entry
+// for that name pointing to NewWithContext and set pluginName in the plugin args to the same name.
+var PluginRegistryWithContext = map[string]func(context.Context, runtime.Object, framework.Handle) (framework.Plugin, error){
+	Name: NewWithContext,
+}
@@ -0,0 +1,1462 @@
+package controllerspread
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+	"k8s.io/utils/ptr"
+)
+
+// testNamespace is the namespace of the test objects.
+const testNamespace = "default"
+
+// fakeSnapshot is a framework.SharedLister over a fixed set of nodes.
+type fakeSnapshot struct {
+	nodeInfos tf.NodeInfoLister
+}
+
+func (s fakeSnapshot) NodeInfos() framework.NodeInfoLister {
+	return s.nodeInfos
+}
+
+func (s fakeSnapshot) StorageInfos() framework.StorageInfoLister {
+	return nil
+}
+
+// newSnapshot returns a snapshot of the nodes with the pods bound to them.
+func newSnapshot(nodes []*v1.Node, pods []*v1.Pod) fakeSnapshot {
+	nodeInfos := tf.BuildNodeInfos(nodes)
+	for _, pod := range pods {
+		for _, nodeInfo := range nodeInfos {
+			if nodeInfo.Node().Name == pod.Spec.NodeName {
+				nodeInfo.AddPod(pod)
+			}
+		}
+	}
+	return fakeSnapshot{nodeInfos: nodeInfos}
+}
+
+// newTestFramework returns a framework handle over a snapshot of the nodes and pods that records
+// events in a FakeRecorder.
+func newTestFramework(t testing.TB, nodes []*v1.Node, pods []*v1.Pod, opts ...frameworkruntime.Option) framework.Framework {
+	t.Helper()
+	opts = append([]frameworkruntime.Option{
+		frameworkruntime.WithSnapshotSharedLister(newSnapshot(nodes, pods)),
+		frameworkruntime.WithEventRecorder(events.NewFakeRecorder(100)),
+	}, opts...)
+	fh, err := frameworkruntime.NewFramework(t.Context(), nil, nil, opts...)
+	if err != nil {
+		t.Fatalf("NewFramework: %v", err)
+	}
+	return fh
+}
+
+// newTestPlugin returns the plugin for the args, reading the nodes and objects from the shared
+// informers of a fake clientset, which are started and synced.
+func newTestPlugin(t testing.TB, args *ControllerSpreadArgs, nodes []*v1.Node, objs ...runtime.Object) *ControllerSpreadFilter {
+	t.Helper()
+	var pods []*v1.Pod
+	all := make([]runtime.Object, 0, len(nodes)+len(objs))
+	for _, node := range nodes {
+		all = append(all, node)
+	}
+	for _, obj := range objs {
+		if pod, ok := obj.(*v1.Pod); ok {
+			pods = append(pods, pod)
+		}
+		all = append(all, obj)
+	}
+	client := clientsetfake.NewSimpleClientset(all...)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	fh := newTestFramework(t, nodes, pods, frameworkruntime.WithClientSet(client), frameworkruntime.WithInformerFactory(informerFactory))
+	p, err := newWithListers(t.Context(), args, fh, nil)
+	if err != nil {
+		t.Fatalf("newWithListers: %v", err)
+	}
+	informerFactory.Start(t.Context().Done())
+	informerFactory.WaitForCacheSync(t.Context().Done())
+	return p.(*ControllerSpreadFilter)
+}
+
+// newTestListers returns listers over indexers holding the nodes and objects.
+func newTestListers(nodes []*v1.Node, objs ...runtime.Object) Listers {
+	newIndexer := func() cache.Indexer {
+		return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	}
+	indexers := map[string]cache.Indexer{}
+	indexer := func(kind string) cache.Indexer {
+		if indexers[kind] == nil {
+			indexers[kind] = newIndexer()
+		}
+		return indexers[kind]
+	}
+	for _, node := range nodes {
+		_ = indexer("Node").Add(node)
+	}
+	for _, obj := range objs {
+		_ = indexer(fmt.Sprintf("%T", obj)).Add(obj)
+	}
+	return Listers{
+		Pods:                   corelisters.NewPodLister(indexer("*v1.Pod")),
+		DaemonSets:             appslisters.NewDaemonSetLister(indexer("*v1.DaemonSet")),
+		Deployments:            appslisters.NewDeploymentLister(indexer("*v1.Deployment")),
+		ReplicaSets:            appslisters.NewReplicaSetLister(indexer("*v1.ReplicaSet")),
+		StatefulSets:           appslisters.NewStatefulSetLister(indexer("*v1.StatefulSet")),
+		Jobs:                   batchlisters.NewJobLister(indexer("*v1.Job")),
+		CronJobs:               batchlisters.NewCronJobLister(indexer("*v1.CronJob")),
+		PodDisruptionBudgets:   policylisters.NewPodDisruptionBudgetLister(indexer("*v1.PodDisruptionBudget")),
+		ReplicationControllers: corelisters.NewReplicationControllerLister(indexer("*v1.ReplicationController")),
+		Nodes:                  corelisters.NewNodeLister(indexer("Node")),
+	}
+}
+
+// makeNode returns a node carrying its hostname label and the labels.
+func makeNode(name string, labels map[string]string) *v1.Node {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelHostname: name}}}
+	for key, val := range labels {
+		node.Labels[key] = val
+	}
+	return node
+}
+
+// makeNodes returns the nodes of the names.
+func makeNodes(names ...string) []*v1.Node {
+	nodes := make([]*v1.Node, len(names))
+	for i, name := range names {
+		nodes[i] = makeNode(name, nil)
+	}
+	return nodes
+}
+
+// testUID returns the UID of the test object of the name.
+func testUID(name string) types.UID {
+	return types.UID("uid-" + name)
+}
+
+// makeDeployment returns a Deployment in the test namespace.
+func makeDeployment(name string, replicas int32, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name), Annotations: annotations},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+	}
+}
+
+// makeReplicaSet returns the ReplicaSet of the Deployment's current revision, with the
+// pod-template-hash "hash".
+func makeReplicaSet(deploy *appsv1.Deployment) *appsv1.ReplicaSet {
+	name := deploy.Name + "-hash"
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name),
+			Labels:          map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "hash"},
+			OwnerReferences: []metav1.OwnerReference{ownerRef(DeploymentType, deploy.Name)}},
+		Spec: appsv1.ReplicaSetSpec{Replicas: deploy.Spec.Replicas},
+	}
+}
+
+// ownerRef returns a controller reference to the test object of the built-in kind and name.
+func ownerRef(kind ControllerType, name string) metav1.OwnerReference {
+	apiVersion := "v1"
+	if group := builtinControllerGroups[kind]; group != "" {
+		apiVersion = group + "/v1"
+	}
+	return metav1.OwnerReference{APIVersion: apiVersion, Kind: string(kind), Name: name, UID: testUID(name), Controller: ptr.To(true)}
+}
+
+// makePod returns a pod in the test namespace owned by the owner, Running on the node or Pending
+// if nodeName is empty.
+func makePod(name, nodeName string, owner metav1.OwnerReference) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name),
+			OwnerReferences: []metav1.OwnerReference{owner}},
+		Spec:   v1.PodSpec{NodeName: nodeName},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if nodeName == "" {
+		pod.Status.Phase = v1.PodPending
+	}
+	return pod
+}
+
+// makeDeploymentPods returns the Deployment, its ReplicaSet and a pod of it on each of the nodes.
+func makeDeploymentPods(deploy *appsv1.Deployment, nodeNames ...string) []runtime.Object {
+	rs := makeReplicaSet(deploy)
+	objs := []runtime.Object{deploy, rs}
+	for i, nodeName := range nodeNames {
+		objs = append(objs, makePod(fmt.Sprintf("%s-%d", deploy.Name, i), nodeName, ownerRef(ReplicaSetType, rs.Name)))
+	}
+	return objs
+}
+
+// preFilter runs PreFilter for the pod in a new cycle state.
+func preFilter(t testing.TB, p *ControllerSpreadFilter, pod *v1.Pod) (*framework.CycleState, *framework.Status) {
+	t.Helper()
+	state := framework.NewCycleState()
+	_, status := p.PreFilter(t.Context(), state, pod)
+	return state, status
+}
+
+// filterStatuses runs PreFilter and then Filter on every node for the pod, and returns the
+// Filter status per node. A pod skipped by PreFilter has a nil status on every node.
+func filterStatuses(t testing.TB, p *ControllerSpreadFilter, pod *v1.Pod) map[string]*framework.Status {
+	t.Helper()
+	state, status := preFilter(t, p, pod)
+	skipped := status.Code() == framework.Skip
+	if !skipped && !status.IsSuccess() {
+		t.Fatalf("PreFilter: %v", status)
+	}
+	nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		t.Fatalf("listing nodes: %v", err)
+	}
+	statuses := make(map[string]*framework.Status, len(nodeInfos))
+	for _, nodeInfo := range nodeInfos {
+		if skipped {
+			statuses[nodeInfo.Node().Name] = nil
+			continue
+		}
+		statuses[nodeInfo.Node().Name] = p.Filter(t.Context(), state, pod, nodeInfo)
+	}
+	return statuses
+}
+
+// filterNodes returns the sorted names of the nodes passing Filter for the pod, see
+// filterStatuses.
+func filterNodes(t testing.TB, p *ControllerSpreadFilter, pod *v1.Pod) []string {
+	t.Helper()
+	var feasible []string
+	for nodeName, status := range filterStatuses(t, p, pod) {
+		if status.IsSuccess() {
+			feasible = append(feasible, nodeName)
+		}
+	}
+	sort.Strings(feasible)
+	return feasible
+}
+
+// scoreNodes runs PreFilter, PreScore, Score and NormalizeScore for the pod on every node, and
+// returns the normalized score per node.
+func scoreNodes(t testing.TB, p *ControllerSpreadFilter, pod *v1.Pod) map[string]int64 {
+	t.Helper()
+	state, status := preFilter(t, p, pod)
+	if !status.IsSuccess() && status.Code() != framework.Skip {
+		t.Fatalf("PreFilter: %v", status)
+	}
+	nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		t.Fatalf("listing nodes: %v", err)
+	}
+	if status := p.PreScore(t.Context(), state, pod, nodeInfos); !status.IsSuccess() {
+		t.Fatalf("PreScore: %v", status)
+	}
+	scores := make(framework.NodeScoreList, 0, len(nodeInfos))
+	for _, nodeInfo := range nodeInfos {
+		score, status := p.Score(t.Context(), state, pod, nodeInfo.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("Score(%s): %v", nodeInfo.Node().Name, status)
+		}
+		scores = append(scores, framework.NodeScore{Name: nodeInfo.Node().Name, Score: score})
+	}
+	if status := p.NormalizeScore(t.Context(), state, pod, scores); !status.IsSuccess() {
+		t.Fatalf("NormalizeScore: %v", status)
+	}
+	got := make(map[string]int64, len(scores))
+	for _, score := range scores {
+		got[score.Name] = score.Score
+	}
+	return got
+}
+
+func TestNewWithListers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	deploy := makeDeployment("web", 3, nil)
+	objs := makeDeploymentPods(deploy, "node-a")
+	pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+
+	tests := []struct {
+		name    string
+		args    ControllerSpreadArgs
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "spreads from the injected listers",
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name: "event-driven counts fall back to listing",
+			args: ControllerSpreadArgs{EventDrivenCounts: true},
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:    "namespace selector without a namespace lister",
+			args:    ControllerSpreadArgs{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"spread": "true"}}},
+			wantErr: true,
+		},
+		{
+			name:    "HPA awareness without an HPA lister",
+			args:    ControllerSpreadArgs{HPAAware: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without WithInformerFactory, any use of the handle's informer factory panics.
+			fh := newTestFramework(t, nodes, nil)
+			p, err := NewWithListersContext(t.Context(), &tt.args, fh, newTestListers(nodes, append(objs, pod)...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewWithListersContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			csf := p.(*ControllerSpreadFilter)
+			if csf.specs != nil || csf.nodeTopology != nil || csf.scaleUps != nil || csf.counter != nil {
+				t.Errorf("NewWithListersContext() set up informer-backed caches")
+			}
+			if diff := cmp.Diff(tt.want, filterNodes(t, csf, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContextualLogging(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name           string
+		args           ControllerSpreadArgs
+		annotations    map[string]string
+		podAnnotations map[string]string
+		filter         bool
+		reserve        bool
+		want           string
+	}{
+		{
+			name:        "invalid annotation in PreFilter",
+			annotations: map[string]string{maxSkewAnnotationKey: "many"},
+			want:        `"msg"="Ignoring invalid annotation" "cycle"="test" "annotation"="controller-spread-scheduler/max-skew"`,
+		},
+		{
+			name:        "min-hosts clamped in PreFilter",
+			annotations: map[string]string{minHostsAnnotationKey: "5"},
+			want:        `"msg"="min-hosts annotation exceeds the desired count, requiring the desired count instead" "cycle"="test"`,
+		},
+		{
+			name:    "placement assumed in Reserve",
+			reserve: true,
+			want:    `"msg"="Assuming placement of pod until it is bound" "cycle"="test" "pod"={"name"="web-new" "namespace"="default"} "node"="node-b"`,
+		},
+		{
+			name:           "invalid pod annotation in Filter",
+			podAnnotations: map[string]string{disableAnnotationKey: "maybe"},
+			filter:         true,
+			want:           `"msg"="Ignoring invalid annotation" "cycle"="test" "annotation"="controller-spread-scheduler/disable"`,
+		},
+		{
+			name:        "invalid topology key in PreFilter",
+			annotations: map[string]string{topologyKeyAnnotationKey: "rack:a"},
+			want:        `"msg"="Ignoring invalid topology key, treating every node as its own domain" "cycle"="test" "annotation"="controller-spread-scheduler/topology-key"`,
+		},
+		{
+			name: "node without topology label in PreFilter",
+			args: ControllerSpreadArgs{TopologyKeys: []string{v1.LabelTopologyZone}},
+			want: `"msg"="Could not resolve node topology label, treating it as its own domain" "cycle"="test" "node"="node-a"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var lines []string
+			logger := funcr.New(func(prefix, args string) {
+				mu.Lock()
+				defer mu.Unlock()
+				lines = append(lines, args)
+			}, funcr.Options{Verbosity: 5})
+			ctx := klog.NewContext(t.Context(), logger.WithValues("cycle", "test"))
+
+			deploy := makeDeployment("web", 3, tt.annotations)
+			objs := makeDeploymentPods(deploy, "node-a")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Annotations = tt.podAnnotations
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+			state := framework.NewCycleState()
+			if _, status := p.PreFilter(ctx, state, pod); !status.IsSuccess() {
+				t.Fatalf("PreFilter: %v", status)
+			}
+			if tt.filter {
+				nodeInfo, err := p.handle.SnapshotSharedLister().NodeInfos().Get("node-b")
+				if err != nil {
+					t.Fatalf("getting node-b: %v", err)
+				}
+				p.Filter(ctx, state, pod, nodeInfo)
+			}
+			if tt.reserve {
+				if status := p.Reserve(ctx, state, pod, "node-b"); !status.IsSuccess() {
+					t.Fatalf("Reserve: %v", status)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, line := range lines {
+				if strings.Contains(line, tt.want) {
+					return
+				}
+			}
+			t.Errorf("no log line contains %s, got:\n%s", tt.want, strings.Join(lines, "\n"))
+		})
+	}
+}
+
+func TestFilterWithoutPreFilter(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name      string
+		replicas  int32
+		pod       *v1.Pod
+		want      []string
+		wantState bool
+	}{
+		{
+			name:      "spread pod",
+			replicas:  3,
+			pod:       makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash")),
+			want:      []string{"node-b", "node-c"},
+			wantState: true,
+		},
+		{
+			name:     "single replica",
+			replicas: 1,
+			pod:      makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash")),
+			want:     []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:     "pod without a controller",
+			replicas: 3,
+			pod:      &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: testNamespace, UID: testUID("bare")}},
+			want:     []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", tt.replicas, nil), "node-a")
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, tt.pod)...)
+			nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+			if err != nil {
+				t.Fatalf("listing nodes: %v", err)
+			}
+			state := framework.NewCycleState()
+			var feasible []string
+			for _, nodeInfo := range nodeInfos {
+				status := p.Filter(t.Context(), state, tt.pod, nodeInfo)
+				if status.IsSuccess() {
+					feasible = append(feasible, nodeInfo.Node().Name)
+				} else if status.Code() != framework.Unschedulable {
+					t.Errorf("Filter(%s) = %v, want Success or Unschedulable", nodeInfo.Node().Name, status)
+				}
+			}
+			sort.Strings(feasible)
+			if diff := cmp.Diff(tt.want, feasible); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+			if _, err := getPreFilterState(state); (err == nil) != tt.wantState {
+				t.Errorf("PreFilter state stored = %v, want %v", err == nil, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestParseMinHostsAnnotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		val     string
+		desired int32
+		want    int32
+		wantErr bool
+	}{
+		{
+			name:    "integer",
+			val:     "3",
+			desired: 10,
+			want:    3,
+		},
+		{
+			name:    "percentage",
+			val:     "50%",
+			desired: 10,
+			want:    5,
+		},
+		{
+			name:    "percentage rounded up",
+			val:     "33%",
+			desired: 10,
+			want:    4,
+		},
+		{
+			name:    "percentage raised to two hosts",
+			val:     "10%",
+			desired: 5,
+			want:    2,
+		},
+		{
+			name:    "percentage of a single replica",
+			val:     "100%",
+			desired: 1,
+			want:    2,
+		},
+		{
+			name:    "zero percent",
+			val:     "0%",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+		{
+			name:    "percentage above 100",
+			val:     "150%",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+		{
+			name:    "fractional percentage",
+			val:     "12.5%",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+		{
+			name:    "single host",
+			val:     "1",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			val:     "three",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+		{
+			name:    "above int32",
+			val:     "4294967296",
+			desired: 10,
+			want:    defaultMinHosts,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMinHostsAnnotation(tt.val, tt.desired, defaultMinHosts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMinHostsAnnotation(%q) error = %v, wantErr %v", tt.val, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMinHostsAnnotation(%q) = %d, want %d", tt.val, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPodMinHosts(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name                  string
+		controllerAnnotations map[string]string
+		podAnnotations        map[string]string
+		want                  []string
+	}{
+		{
+			name:                  "controller annotation",
+			controllerAnnotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:                  []string{"node-c"},
+		},
+		{
+			name:           "pod annotation",
+			podAnnotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:           []string{"node-c"},
+		},
+		{
+			name:                  "pod annotation over the controller's",
+			controllerAnnotations: map[string]string{minHostsAnnotationKey: "3"},
+			podAnnotations:        map[string]string{minHostsAnnotationKey: "2"},
+			want:                  []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, tt.controllerAnnotations), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Annotations = tt.podAnnotations
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDefaultMinHostsFor(t *testing.T) {
+	args := &ControllerSpreadArgs{
+		DefaultMinHosts:   2,
+		NamespaceDefaults: map[string]int32{"prod": 4},
+		TypeDefaults:      map[ControllerType]int32{StatefulSetType: 3},
+	}
+	tests := []struct {
+		name           string
+		namespace      string
+		controllerType ControllerType
+		want           int32
+	}{
+		{
+			name:           "namespace default",
+			namespace:      "prod",
+			controllerType: DeploymentType,
+			want:           4,
+		},
+		{
+			name:           "namespace default over the type default",
+			namespace:      "prod",
+			controllerType: StatefulSetType,
+			want:           4,
+		},
+		{
+			name:           "type default",
+			namespace:      "dev",
+			controllerType: StatefulSetType,
+			want:           3,
+		},
+		{
+			name:           "cluster default",
+			namespace:      "dev",
+			controllerType: DeploymentType,
+			want:           2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := &ControllerSpreadFilter{args: args}
+			if got := csf.defaultMinHostsFor(tt.namespace, tt.controllerType); got != tt.want {
+				t.Errorf("defaultMinHostsFor(%q, %s) = %d, want %d", tt.namespace, tt.controllerType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterNamespaceDefaults(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		args        ControllerSpreadArgs
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name: "cluster default",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "namespace default",
+			args: ControllerSpreadArgs{NamespaceDefaults: map[string]int32{testNamespace: 3}},
+			want: []string{"node-c"},
+		},
+		{
+			name:        "annotation over the namespace default",
+			args:        ControllerSpreadArgs{NamespaceDefaults: map[string]int32{testNamespace: 3}},
+			annotations: map[string]string{minHostsAnnotationKey: "2"},
+			want:        []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "default of another namespace",
+			args: ControllerSpreadArgs{NamespaceDefaults: map[string]int32{"prod": 3}},
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, tt.annotations), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterTypeDefaults(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "cluster default",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "type default",
+			args: ControllerSpreadArgs{TypeDefaults: map[ControllerType]int32{StatefulSetType: 3}},
+			want: []string{"node-c"},
+		},
+		{
+			name: "default of another type",
+			args: ControllerSpreadArgs{TypeDefaults: map[ControllerType]int32{DeploymentType: 3}},
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := ownerRef(StatefulSetType, "db")
+			pod := makePod("db-2", "", owner)
+			p := newTestPlugin(t, &tt.args, nodes, makeStatefulSet("db", 3, 0, "db-1", "db-1", nil),
+				makePod("db-0", "node-a", owner), makePod("db-1", "node-b", owner), pod)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReportInvalidMinHosts(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name     string
+		minHosts string
+		want     []string
+		// wantReported is the increase of invalid_annotation_total for the min-hosts annotation.
+		wantReported float64
+	}{
+		{
+			name:     "valid value",
+			minHosts: "3",
+			want:     []string{"node-c"},
+		},
+		{
+			name:         "invalid value",
+			minHosts:     "three",
+			want:         []string{"node-a", "node-b", "node-c"},
+			wantReported: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: tt.minHosts}), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+			counter := invalidAnnotations.WithLabelValues(p.Name(), minHostsAnnotationKey)
+			before, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading invalid_annotation_total: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+			after, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading invalid_annotation_total: %v", err)
+			}
+			if got := after - before; got != tt.wantReported {
+				t.Errorf("invalid_annotation_total increased by %v, want %v", got, tt.wantReported)
+			}
+		})
+	}
+}
+
+func TestReportMinHostsClamped(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name     string
+		minHosts string
+		want     []string
+		// wantReported is the increase of minhosts_clamped_total over two scheduling cycles.
+		wantReported float64
+	}{
+		{
+			name:     "min-hosts within the desired count",
+			minHosts: "2",
+			want:     []string{"node-b", "node-c"},
+		},
+		{
+			name:         "min-hosts above the desired count",
+			minHosts:     "5",
+			want:         []string{"node-b", "node-c"},
+			wantReported: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 2, map[string]string{minHostsAnnotationKey: tt.minHosts}), "node-a")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+			counter := minHostsClamped.WithLabelValues(p.Name())
+			before, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading minhosts_clamped_total: %v", err)
+			}
+
+			for range 2 {
+				if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+					t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+				}
+			}
+			after, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading minhosts_clamped_total: %v", err)
+			}
+			if got := after - before; got != tt.wantReported {
+				t.Errorf("minhosts_clamped_total increased by %v, want %v", got, tt.wantReported)
+			}
+		})
+	}
+}
+
+func TestFilterMinHostsPercentage(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c", "node-d")
+	tests := []struct {
+		name     string
+		minHosts string
+		want     []string
+	}{
+		{
+			name:     "percentage above the current spread",
+			minHosts: "75%",
+			want:     []string{"node-c", "node-d"},
+		},
+		{
+			name:     "percentage met by the current spread",
+			minHosts: "50%",
+			want:     []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 4, map[string]string{minHostsAnnotationKey: tt.minHosts}), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListControllerPodsContext(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	tests := []struct {
+		name     string
+		ctx      func(context.Context) context.Context
+		wantPods int
+		wantErr  error
+	}{
+		{
+			name:     "live context",
+			ctx:      func(ctx context.Context) context.Context { return ctx },
+			wantPods: 2,
+		},
+		{
+			name: "cancelled context",
+			ctx: func(ctx context.Context) context.Context {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				return ctx
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "deadline passed",
+			ctx: func(ctx context.Context) context.Context {
+				ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+				t.Cleanup(cancel)
+				return ctx
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, nil), "node-a", "node-b")
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, objs...)
+			controller := ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))}
+
+			pods, err := p.listControllerPods(tt.ctx(t.Context()), testNamespace, controller)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("listControllerPods() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(pods) != tt.wantPods {
+				t.Errorf("listControllerPods() returned %d pods, want %d", len(pods), tt.wantPods)
+			}
+		})
+	}
+}
+
+func TestReplicationControllers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name     string
+		replicas *int32
+		want     []string
+	}{
+		{
+			name:     "replicas set",
+			replicas: ptr.To[int32](3),
+			want:     []string{"node-b", "node-c"},
+		},
+		{
+			name: "replicas defaulted to one",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &v1.ReplicationController{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: testNamespace, UID: testUID("legacy"),
+					Annotations: map[string]string{minHostsAnnotationKey: "3"}},
+				Spec: v1.ReplicationControllerSpec{Replicas: tt.replicas},
+			}
+			peer := makePod("legacy-0", "node-a", ownerRef(ReplicationControllerType, "legacy"))
+			pod := makePod("legacy-new", "", ownerRef(ReplicationControllerType, "legacy"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, rc, peer, pod)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetOwnerInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		ownerRefs []metav1.OwnerReference
+		want      ControllerInfo
+		wantOK    bool
+	}{
+		{
+			name:      "built-in Job",
+			ownerRefs: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "batch", UID: "uid-batch"}},
+			want:      ControllerInfo{Type: JobType, Name: "batch", UID: "uid-batch"},
+			wantOK:    true,
+		},
+		{
+			name:      "built-in ReplicationController",
+			ownerRefs: []metav1.OwnerReference{{APIVersion: "v1", Kind: "ReplicationController", Name: "legacy", UID: "uid-legacy"}},
+			want:      ControllerInfo{Type: ReplicationControllerType, Name: "legacy", UID: "uid-legacy"},
+			wantOK:    true,
+		},
+		{
+			name:      "CRD reusing a built-in kind",
+			ownerRefs: []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Job", Name: "batch", UID: "uid-batch"}},
+		},
+		{
+			name: "CRD before the built-in controller",
+			ownerRefs: []metav1.OwnerReference{
+				{APIVersion: "example.com/v1", Kind: "Deployment", Name: "web", UID: "uid-crd"},
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-hash", UID: "uid-web-hash"},
+			},
+			want:   ControllerInfo{Type: ReplicaSetType, Name: "web-hash", UID: "uid-web-hash"},
+			wantOK: true,
+		},
+		{
+			name:      "invalid apiVersion",
+			ownerRefs: []metav1.OwnerReference{{APIVersion: "apps/v1/beta", Kind: "ReplicaSet", Name: "web-hash", UID: "uid-web-hash"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := getOwnerInfo(tt.ownerRefs, nil)
+			if ok != tt.wantOK {
+				t.Errorf("getOwnerInfo() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("getOwnerInfo() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGroupJobsByCronJob(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "Jobs spread on their own",
+			want: []string{"node-a", "node-c"},
+		},
+		{
+			name: "Jobs grouped by their CronJob",
+			args: ControllerSpreadArgs{GroupJobsByCronJob: true},
+			want: []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{
+				makeCronJob("nightly", 3, map[string]string{minHostsAnnotationKey: "3"}),
+				makeJob("nightly-1", 3, "nightly", nil),
+				makePod("nightly-1-a", "node-a", ownerRef(JobType, "nightly-1")),
+				makeJob("nightly-2", 3, "nightly", nil),
+				makePod("nightly-2-a", "node-b", ownerRef(JobType, "nightly-2")),
+			}
+			pod := makePod("nightly-2-b", "", ownerRef(JobType, "nightly-2"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterWithoutNode(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	tests := []struct {
+		name     string
+		nodeInfo *framework.NodeInfo
+	}{
+		{
+			name: "nil NodeInfo",
+		},
+		{
+			name:     "NodeInfo of a deleted node",
+			nodeInfo: framework.NewNodeInfo(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, nil), "node-a")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+			state, status := preFilter(t, p, pod)
+			if !status.IsSuccess() {
+				t.Fatalf("PreFilter: %v", status)
+			}
+
+			if status := p.Filter(t.Context(), state, pod, tt.nodeInfo); status.Code() != framework.Error {
+				t.Errorf("Filter() = %v, want Error", status)
+			}
+		})
+	}
+}
+
+func TestStrictSpread(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c", "node-d")
+	tests := []struct {
+		name      string
+		strict    string
+		peerNodes []string
+		want      []string
+	}{
+		{
+			name:      "min-hosts met",
+			peerNodes: []string{"node-a", "node-b"},
+			want:      []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name:      "strict",
+			strict:    "true",
+			peerNodes: []string{"node-a", "node-b"},
+			want:      []string{"node-c", "node-d"},
+		},
+		{
+			name:      "strict with every desired pod placed",
+			strict:    "true",
+			peerNodes: []string{"node-a", "node-b", "node-c", "node-d"},
+			want:      []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name:      "strict disabled",
+			strict:    "false",
+			peerNodes: []string{"node-a", "node-b"},
+			want:      []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name:      "invalid strict value",
+			strict:    "yes please",
+			peerNodes: []string{"node-a", "node-b"},
+			want:      []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{minHostsAnnotationKey: "2"}
+			if tt.strict != "" {
+				annotations[strictAnnotationKey] = tt.strict
+			}
+			objs := makeDeploymentPods(makeDeployment("web", 4, annotations), tt.peerNodes...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsActivePod(t *testing.T) {
+	tests := []struct {
+		name          string
+		countedPhases []v1.PodPhase
+		phase         v1.PodPhase
+		want          bool
+	}{
+		{
+			name:  "running",
+			phase: v1.PodRunning,
+			want:  true,
+		},
+		{
+			name:  "pending",
+			phase: v1.PodPending,
+			want:  true,
+		},
+		{
+			name:  "succeeded",
+			phase: v1.PodSucceeded,
+		},
+		{
+			name:  "failed",
+			phase: v1.PodFailed,
+		},
+		{
+			name:          "succeeded with the phase counted",
+			countedPhases: []v1.PodPhase{v1.PodRunning, v1.PodSucceeded},
+			phase:         v1.PodSucceeded,
+			want:          true,
+		},
+		{
+			name:          "pending without the phase counted",
+			countedPhases: []v1.PodPhase{v1.PodRunning},
+			phase:         v1.PodPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &ControllerSpreadArgs{CountedPhases: tt.countedPhases}
+			SetDefaults_ControllerSpreadArgs(args)
+			csf := &ControllerSpreadFilter{countedPhases: newCountedPhases(args.CountedPhases)}
+			pod := makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Status.Phase = tt.phase
+			if got := csf.isActivePod(pod); got != tt.want {
+				t.Errorf("isActivePod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterFinishedPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name  string
+		phase v1.PodPhase
+		want  []string
+	}{
+		{
+			name:  "running peer",
+			phase: v1.PodRunning,
+			want:  []string{"node-c"},
+		},
+		{
+			name:  "succeeded peer",
+			phase: v1.PodSucceeded,
+			want:  []string{"node-b", "node-c"},
+		},
+		{
+			name:  "failed peer",
+			phase: v1.PodFailed,
+			want:  []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a", "node-b")
+			objs[3].(*v1.Pod).Status.Phase = tt.phase
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterTerminatingPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		terminating bool
+		want        []string
+	}{
+		{
+			name: "running peer",
+			want: []string{"node-c"},
+		},
+		{
+			name:        "terminating peer",
+			terminating: true,
+			want:        []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a", "node-b")
+			if tt.terminating {
+				objs[3].(*v1.Pod).DeletionTimestamp = ptr.To(metav1.Now())
+			}
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterPendingPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		// peerNodes are the nodes of the peers, empty for a pending peer.
+		peerNodes []string
+		want      []string
+	}{
+		{
+			name:      "only pending peers",
+			peerNodes: []string{"", ""},
+			want:      []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:      "scheduled and pending peers",
+			peerNodes: []string{"node-a", ""},
+			want:      []string{"node-b", "node-c"},
+		},
+		{
+			name:      "scheduled peers",
+			peerNodes: []string{"node-a", "node-b"},
+			want:      []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), tt.peerNodes...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// benchmarkFixture returns nodes and the pods of Deployments of the controller size in the test
+// namespace, namespacePods in total, spread round-robin on the nodes, and a pending pod of the
+// first Deployment.
+func benchmarkFixture(nodeCount, namespacePods, controllerSize int) ([]*v1.Node, []runtime.Object, *v1.Pod) {
+	nodeNames := make([]string, nodeCount)
+	for i := range nodeNames {
+		nodeNames[i] = fmt.Sprintf("node-%d", i)
+	}
+	var objs []runtime.Object
+	for placed := 0; placed < namespacePods; placed += controllerSize {
+		deploy := makeDeployment(fmt.Sprintf("deploy-%d", placed/controllerSize), int32(controllerSize+1), nil)
+		podNodes := make([]string, controllerSize)
+		if remaining := namespacePods - placed; remaining < controllerSize {
+			podNodes = podNodes[:remaining]
+		}
+		for i := range podNodes {
+			podNodes[i] = nodeNames[(placed+i)%nodeCount]
+		}
+		objs = append(objs, makeDeploymentPods(deploy, podNodes...)...)
+	}
+	pod := makePod("deploy-0-new", "", ownerRef(ReplicaSetType, "deploy-0-hash"))
+	return makeNodes(nodeNames...), append(objs, pod), pod
+}
+
+// Benchmark_Filter measures a scheduling cycle, PreFilter and Filter on every node, and the peer
+// listing and node counting stages of PreFilter as the namespace and controller grow.
+func Benchmark_Filter(b *testing.B) {
+	for _, bm := range []struct {
+		nodes          int
+		namespacePods  int
+		controllerSize int
+	}{
+		{nodes: 100, namespacePods: 100, controllerSize: 10},
+		{nodes: 100, namespacePods: 1000, controllerSize: 10},
+		{nodes: 100, namespacePods: 1000, controllerSize: 100},
+		{nodes: 500, namespacePods: 5000, controllerSize: 10},
+		{nodes: 500, namespacePods: 5000, controllerSize: 500},
+	} {
+		name := fmt.Sprintf("nodes=%d/namespacePods=%d/controllerSize=%d", bm.nodes, bm.namespacePods, bm.controllerSize)
+		b.Run(name, func(b *testing.B) {
+			nodes, objs, pod := benchmarkFixture(bm.nodes, bm.namespacePods, bm.controllerSize)
+			p := newTestPlugin(b, &ControllerSpreadArgs{}, nodes, objs...)
+			nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+			if err != nil {
+				b.Fatalf("listing nodes: %v", err)
+			}
+			controller, ok := p.resolveTopOwner(klog.Background(), pod)
+			if !ok {
+				b.Fatalf("no controller for pod %s", pod.Name)
+			}
+
+			b.Run("cycle", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					state := framework.NewCycleState()
+					if _, status := p.PreFilter(b.Context(), state, pod); !status.IsSuccess() {
+						b.Fatalf("PreFilter: %v", status)
+					}
+					for _, nodeInfo := range nodeInfos {
+						p.Filter(b.Context(), state, pod, nodeInfo)
+					}
+				}
+				b.ReportMetric(float64(len(nodeInfos)), "nodes/op")
+			})
+			b.Run("listPeers", func(b *testing.B) {
+				b.ReportAllocs()
+				var peers []*v1.Pod
+				for range b.N {
+					if peers, err = p.listPeers(b.Context(), pod, controller, false); err != nil {
+						b.Fatalf("listPeers: %v", err)
+					}
+				}
+				b.ReportMetric(float64(len(peers)), "peers/op")
+			})
+			b.Run("peerNodeCounts", func(b *testing.B) {
+				peers, err := p.listPeers(b.Context(), pod, controller, false)
+				if err != nil {
+					b.Fatalf("listPeers: %v", err)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					p.peerNodeCounts(controller.UID, pod, peers)
+				}
+			})
+		})
+	}
+}
+
+// countingPodLister is a PodLister that counts the namespace List calls.
+type countingPodLister struct {
+	corelisters.PodLister
+	lists *int
+}
+
+func (l countingPodLister) Pods(namespace string) corelisters.PodNamespaceLister {
+	return countingPodNamespaceLister{PodNamespaceLister: l.PodLister.Pods(namespace), lists: l.lists}
+}
+
+type countingPodNamespaceLister struct {
+	corelisters.PodNamespaceLister
+	lists *int
+}
+
+func (l countingPodNamespaceLister) List(selector labels.Selector) ([]*v1.Pod, error) {
+	*l.lists++
+	return l.PodNamespaceLister.List(selector)
+}
+
+// BenchmarkFilterMemoization compares the pod lister calls of a cycle whose Filter calls share
+// the controller state memoized by PreFilter with one that builds the state for every node.
+func BenchmarkFilterMemoization(b *testing.B) {
+	nodes, objs, pod := benchmarkFixture(100, 1000, 10)
+	var pods []*v1.Pod
+	for _, obj := range objs {
+		if p, ok := obj.(*v1.Pod); ok {
+			pods = append(pods, p)
+		}
+	}
+	for _, bm := range []struct {
+		name string
+		// memoized runs PreFilter once per cycle, otherwise every Filter call gets a new
+		// cycle state and computes the state itself.
+		memoized bool
+		// maxLists bounds the pod lister calls per cycle.
+		maxLists int
+	}{
+		{name: "memoized", memoized: true, maxLists: 1},
+		{name: "per-node", maxLists: len(nodes)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var lists int
+			listers := newTestListers(nodes, objs...)
+			listers.Pods = countingPodLister{PodLister: listers.Pods, lists: &lists}
+			fh := newTestFramework(b, nodes, pods)
+			plugin, err := NewWithListersContext(b.Context(), &ControllerSpreadArgs{}, fh, listers)
+			if err != nil {
+				b.Fatalf("NewWithListersContext: %v", err)
+			}
+			p := plugin.(*ControllerSpreadFilter)
+			nodeInfos, err := fh.SnapshotSharedLister().NodeInfos().List()
+			if err != nil {
+				b.Fatalf("listing nodes: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				state := framework.NewCycleState()
+				if bm.memoized {
+					if _, status := p.PreFilter(b.Context(), state, pod); !status.IsSuccess() {
+						b.Fatalf("PreFilter: %v", status)
+					}
+				}
+				for _, nodeInfo := range nodeInfos {
+					if !bm.memoized {
+						state = framework.NewCycleState()
+					}
+					p.Filter(b.Context(), state, pod, nodeInfo)
+				}
+			}
+			b.StopTimer()
+			perCycle := float64(lists) / float64(b.N)
+			b.ReportMetric(perCycle, "podLists/op")
+			if perCycle > float64(bm.maxLists) {
+				b.Errorf("pod lister calls per cycle = %v, want at most %d", perCycle, bm.maxLists)
+			}
+		})
+	}
+}
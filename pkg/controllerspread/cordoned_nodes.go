@@ -0,0 +1,23 @@
+// pkg/controllerspread/cordoned_nodes.go
+//
+// Cordoned nodes in spread accounting. A cordoned node (spec.unschedulable) is usually being
+// drained, so its pods are about to move and only pin the spread until they do. With
+// IgnoreCordonedNodes set in the plugin args, the pods on cordoned nodes are not counted, so the
+// spread is rebuilt on the remaining nodes while the drain is in progress. Cordoned nodes are no
+// candidates either way, as the scheduler does not place pods on them.
+package controllerspread
+
+// withoutCordonedNodes removes the cordoned nodes from the per-node pod counts if
+// IgnoreCordonedNodes is set. Nodes that cannot be looked up are kept.
+func (csf *ControllerSpreadFilter) withoutCordonedNodes(nodeCounts map[string]int) map[string]int {
+	if !csf.args.IgnoreCordonedNodes {
+		return nodeCounts
+	}
+	for nodeName := range nodeCounts {
+		node, err := csf.nodeLister.Get(nodeName)
+		if err == nil && node.Spec.Unschedulable {
+			delete(nodeCounts, nodeName)
+		}
+	}
+	return nodeCounts
+}
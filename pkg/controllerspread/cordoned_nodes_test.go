@@ -0,0 +1,71 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterCordonedNodes(t *testing.T) {
+	cordoned := makeNode("node-a", nil)
+	cordoned.Spec.Unschedulable = true
+	nodes := []*v1.Node{cordoned, makeNode("node-b", nil), makeNode("node-c", nil)}
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		// want includes node-a, which NodeUnschedulable rejects for being cordoned.
+		want []string
+	}{
+		{
+			name: "peers on cordoned nodes counted",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "peers on cordoned nodes ignored",
+			args: ControllerSpreadArgs{IgnoreCordonedNodes: true},
+			want: []string{"node-a", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "2"}), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithoutCordonedNodes(t *testing.T) {
+	cordoned := makeNode("node-a", nil)
+	cordoned.Spec.Unschedulable = true
+	nodes := []*v1.Node{cordoned, makeNode("node-b", nil)}
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want map[string]int
+	}{
+		{
+			name: "cordoned nodes counted",
+			want: map[string]int{"node-a": 1, "node-b": 2, "node-gone": 1},
+		},
+		{
+			name: "cordoned nodes ignored",
+			args: ControllerSpreadArgs{IgnoreCordonedNodes: true},
+			want: map[string]int{"node-b": 2, "node-gone": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &tt.args, nodes)
+			got := p.withoutCordonedNodes(map[string]int{"node-a": 1, "node-b": 2, "node-gone": 1})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withoutCordonedNodes() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// pkg/controllerspread/cronjob_min_hosts.go
+//
+// Inheritance of the min-hosts annotation by the Jobs of a CronJob. A CronJob propagates the
+// annotations of its jobTemplate to the Jobs it creates, but Jobs created by other means, or
+// before the annotation was added, lack it. A Job without its own min-hosts annotation therefore
+// takes it from the CronJob that controls it: from the jobTemplate metadata, or else from the
+// CronJob itself.
+package controllerspread
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// jobAnnotations returns the annotations of the Job, with the min-hosts annotation of its
+// controlling CronJob if the Job does not carry one. The lister's map is not modified.
+func (csf *ControllerSpreadFilter) jobAnnotations(logger klog.Logger, job *batchv1.Job) map[string]string {
+	if _, exists := job.Annotations[minHostsAnnotationKey]; exists {
+		return job.Annotations
+	}
+	val, ok := csf.cronJobMinHosts(logger, job)
+	if !ok {
+		return job.Annotations
+	}
+	annotations := make(map[string]string, len(job.Annotations)+1)
+	for key, value := range job.Annotations {
+		annotations[key] = value
+	}
+	annotations[minHostsAnnotationKey] = val
+	return annotations
+}
+
+// cronJobMinHosts returns the min-hosts annotation of the CronJob controlling the Job, if any.
+func (csf *ControllerSpreadFilter) cronJobMinHosts(logger klog.Logger, job *batchv1.Job) (string, bool) {
+	ownerRef := metav1.GetControllerOf(job)
+	if ownerRef == nil || !isBuiltinOwner(*ownerRef, CronJobType) {
+		return "", false
+	}
+	cj, err := csf.cronJobLister.CronJobs(job.Namespace).Get(ownerRef.Name)
+	if err != nil || cj.UID != ownerRef.UID {
+		// The CronJob is gone or was recreated; the Job keeps the default.
+		return "", false
+	}
+	val, exists := cj.Spec.JobTemplate.Annotations[minHostsAnnotationKey]
+	if !exists {
+		val, exists = cj.Annotations[minHostsAnnotationKey]
+	}
+	if exists {
+		logger.V(4).Info("Job inherits min-hosts from its CronJob", "job", klog.KObj(job), "cronJob", cj.Name, "value", val)
+	}
+	return val, exists
+}
@@ -0,0 +1,118 @@
+package controllerspread
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+func TestJobAnnotations(t *testing.T) {
+	nodes := makeNodes("node-a")
+	templated := makeCronJob("nightly", 3, map[string]string{minHostsAnnotationKey: "2"})
+	templated.Spec.JobTemplate.Annotations = map[string]string{minHostsAnnotationKey: "3"}
+	recreated := makeCronJob("nightly", 3, map[string]string{minHostsAnnotationKey: "3"})
+	recreated.UID = testUID("nightly-recreated")
+	tests := []struct {
+		name string
+		objs []runtime.Object
+		job  *batchv1.Job
+		want map[string]string
+	}{
+		{
+			name: "Job annotation",
+			objs: []runtime.Object{templated},
+			job:  makeJob("nightly-1", 3, "nightly", map[string]string{minHostsAnnotationKey: "4"}),
+			want: map[string]string{minHostsAnnotationKey: "4"},
+		},
+		{
+			name: "jobTemplate annotation",
+			objs: []runtime.Object{templated},
+			job:  makeJob("nightly-1", 3, "nightly", map[string]string{"team": "data"}),
+			want: map[string]string{"team": "data", minHostsAnnotationKey: "3"},
+		},
+		{
+			name: "CronJob annotation",
+			objs: []runtime.Object{makeCronJob("nightly", 3, map[string]string{minHostsAnnotationKey: "3"})},
+			job:  makeJob("nightly-1", 3, "nightly", nil),
+			want: map[string]string{minHostsAnnotationKey: "3"},
+		},
+		{
+			name: "CronJob without annotation",
+			objs: []runtime.Object{makeCronJob("nightly", 3, nil)},
+			job:  makeJob("nightly-1", 3, "nightly", nil),
+		},
+		{
+			name: "CronJob recreated",
+			objs: []runtime.Object{recreated},
+			job:  makeJob("nightly-1", 3, "nightly", nil),
+		},
+		{
+			name: "CronJob deleted",
+			job:  makeJob("nightly-1", 3, "nightly", nil),
+		},
+		{
+			name: "Job without CronJob",
+			objs: []runtime.Object{templated},
+			job:  makeJob("batch", 3, "", nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, tt.objs...)
+			before := maps.Clone(tt.job.Annotations)
+			got := p.jobAnnotations(klog.Background(), tt.job)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("jobAnnotations() (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(before, tt.job.Annotations); diff != "" {
+				t.Errorf("Job annotations modified (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterCronJobMinHosts(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name               string
+		cronJobAnnotations map[string]string
+		jobAnnotations     map[string]string
+		want               []string
+	}{
+		{
+			name: "default min-hosts",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:               "inherited from the CronJob",
+			cronJobAnnotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:               []string{"node-c"},
+		},
+		{
+			name:               "Job annotation over the CronJob's",
+			cronJobAnnotations: map[string]string{minHostsAnnotationKey: "3"},
+			jobAnnotations:     map[string]string{minHostsAnnotationKey: "2"},
+			want:               []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{
+				makeCronJob("nightly", 3, tt.cronJobAnnotations),
+				makeJob("nightly-1", 3, "nightly", tt.jobAnnotations),
+				makePod("nightly-1-a", "node-a", ownerRef(JobType, "nightly-1")),
+				makePod("nightly-1-b", "node-b", ownerRef(JobType, "nightly-1")),
+			}
+			pod := makePod("nightly-1-c", "", ownerRef(JobType, "nightly-1"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
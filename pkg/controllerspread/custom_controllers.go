@@ -0,0 +1,153 @@
+// pkg/controllerspread/custom_controllers.go
+//
+// Support for user-defined controllers. Each CustomControllerConfig in the plugin args names a
+// controller kind (typically a CRD such as an Argo Rollouts Rollout) and the field holding its
+// desired replica count. Objects are read through a dynamic informer.
+package controllerspread
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// defaultReplicasField is the field read for the desired replica count of a custom controller.
+	defaultReplicasField = "spec.replicas"
+)
+
+// CustomControllerConfig describes a user-defined controller kind.
+type CustomControllerConfig struct {
+	// APIVersion is the group/version of the controller, e.g. "argoproj.io/v1alpha1".
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the controller as it appears in owner references, e.g. "Rollout".
+	Kind string `json:"kind"`
+	// Resource is the plural resource name of the controller, e.g. "rollouts".
+	Resource string `json:"resource"`
+	// ReplicasField is the dot-separated path of the desired replica count. Defaults to "spec.replicas".
+	ReplicasField string `json:"replicasField,omitempty"`
+}
+
+// customController is a configured custom controller with its lister.
+type customController struct {
+	config        CustomControllerConfig
+	replicasField []string
+	lister        cache.GenericLister
+	hasSynced     cache.InformerSynced
+
+	// informer, scales and groupResource are set for controllers read through the scale
+	// subresource, see addScaleControllers. Their lister serves object metadata only.
+	informer      cache.SharedIndexInformer
+	scales        scale.ScalesGetter
+	groupResource schema.GroupResource
+}
+
+// newCustomControllers sets up a dynamic informer for each configured custom controller and,
+// with OpenKruise set, each OpenKruise workload. Custom controllers are keyed by kind and
+// OpenKruise workloads by controller type. The informers run for the lifetime of the scheduler
+// process.
+func newCustomControllers(args *ControllerSpreadArgs, handle framework.Handle) (map[string]*customController, error) {
+	configs := make(map[string]CustomControllerConfig, len(args.CustomControllers))
+	for _, config := range args.CustomControllers {
+		configs[config.Kind] = config
+	}
+	if args.OpenKruise {
+		for t, config := range openKruiseControllers {
+			configs[string(t)] = config
+		}
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(handle.KubeConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+
+	customControllers := make(map[string]*customController, len(configs))
+	for key, config := range configs {
+		// The configs were validated by ValidateControllerSpreadArgs.
+		gv, err := schema.ParseGroupVersion(config.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid apiVersion %q for customControllers kind %q: %v", config.APIVersion, config.Kind, err)
+		}
+		informer := informerFactory.ForResource(gv.WithResource(config.Resource))
+		customControllers[key] = &customController{
+			config:        config,
+			replicasField: strings.Split(config.ReplicasField, "."),
+			lister:        informer.Lister(),
+			hasSynced:     informer.Informer().HasSynced,
+		}
+	}
+
+	informerFactory.Start(wait.NeverStop)
+	return customControllers, nil
+}
+
+// isOwner reports whether the owner reference, whose kind is that of the controller, refers to it.
+// Scale controllers match any version of their API group.
+func (cc *customController) isOwner(ownerRef metav1.OwnerReference) bool {
+	if cc.scales == nil {
+		return cc.config.APIVersion == ownerRef.APIVersion
+	}
+	gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	return err == nil && gv.Group == cc.groupResource.Group
+}
+
+// isBuiltinControllerType reports whether the type is one of the natively supported controllers.
+func isBuiltinControllerType(t ControllerType) bool {
+	_, builtin := builtinControllerGroups[t]
+	return builtin || t == LabelGroupType
+}
+
+// get returns the controller object from the informer cache. For scale controllers it holds the
+// object's metadata only.
+func (cc *customController) get(namespace string, controller ControllerInfo) (*unstructured.Unstructured, error) {
+	obj, err := cc.lister.ByNamespace(namespace).Get(controller.Name)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := obj.(*metav1.PartialObjectMetadata); ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(m)
+		if err != nil {
+			return nil, err
+		}
+		obj = &unstructured.Unstructured{Object: content}
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T for %s %s/%s", obj, controller.Type, namespace, controller.Name)
+	}
+	if string(u.GetUID()) != controller.UID {
+		return nil, fmt.Errorf("%w: %s %s/%s has UID %s, expected %s", errInvalidSpec, controller.Type, namespace, controller.Name, u.GetUID(), controller.UID)
+	}
+	return u, nil
+}
+
+// desiredReplicas reads the desired replica count from the configured field, or from the scale
+// subresource for scale controllers; defaults to 1 when the field is unset.
+func (cc *customController) desiredReplicas(obj *unstructured.Unstructured) (int32, error) {
+	if cc.scales != nil {
+		return cc.scaleReplicas(obj)
+	}
+	replicas, found, err := unstructured.NestedInt64(obj.Object, cc.replicasField...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: reading %s of %s %s/%s: %v", errInvalidSpec, strings.Join(cc.replicasField, "."), cc.config.Kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	if !found {
+		return 1, nil
+	}
+	return int32(replicas), nil
+}
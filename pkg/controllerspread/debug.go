@@ -0,0 +1,280 @@
+// pkg/controllerspread/debug.go
+//
+// Optional read-only HTTP endpoint for troubleshooting. When DebugEndpoint is set in the plugin
+// args, the plugin serves the node distribution last computed by PreFilter for each controller
+// and the in-flight placements recorded by Reserve as JSON. Only object names, UIDs, node names
+// and counts are exposed.
+//
+// The endpoint is served once per process and address: the plugin instances of all scheduler
+// profiles with the same DebugEndpoint share one server, which is shut down when the context of
+// the last of them is done.
+package controllerspread
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// debugStatePath is the path of the spread state on the debug endpoint.
+	debugStatePath = "/debug/controllerspread"
+
+	// trackedSpreadTTL is how long the spread of a controller is reported after the last
+	// scheduling cycle of one of its pods.
+	trackedSpreadTTL = 10 * time.Minute
+
+	// trackedSpreadPruneInterval is how often the spreads older than trackedSpreadTTL are dropped.
+	trackedSpreadPruneInterval = time.Minute
+
+	// debugShutdownTimeout is how long the debug endpoint waits for in-flight requests on
+	// shutdown.
+	debugShutdownTimeout = 5 * time.Second
+)
+
+var (
+	// debugServersMu guards debugServers and the plugins of each server.
+	debugServersMu sync.Mutex
+	// debugServers are the debug endpoints served by the process, by address.
+	debugServers = make(map[string]*debugServer)
+)
+
+// debugServer is a debug endpoint serving the spread state of the plugin instances registered
+// on it.
+type debugServer struct {
+	server  *http.Server
+	plugins map[*ControllerSpreadFilter]bool
+}
+
+// trackedSpread is the spread of a controller as last computed by PreFilter.
+type trackedSpread struct {
+	Namespace  string         `json:"namespace"`
+	Type       ControllerType `json:"type"`
+	Name       string         `json:"name"`
+	UID        string         `json:"uid"`
+	NodeCounts map[string]int `json:"nodeCounts"`
+	Levels     []trackedLevel `json:"levels"`
+	OnePerNode bool           `json:"onePerNode,omitempty"`
+	ObservedAt time.Time      `json:"observedAt"`
+}
+
+// trackedLevel is the spread of a controller at one topology level.
+type trackedLevel struct {
+	TopologyKey  string         `json:"topologyKey"`
+	Required     int32          `json:"required"`
+	DomainCounts map[string]int `json:"domainCounts"`
+}
+
+// trackedPlacement is an assumed placement recorded by Reserve.
+type trackedPlacement struct {
+	PodUID   string    `json:"podUID"`
+	GroupKey string    `json:"groupKey"`
+	NodeName string    `json:"nodeName"`
+	Expires  time.Time `json:"expires"`
+}
+
+// debugState is the JSON document served on the debug endpoint.
+type debugState struct {
+	Controllers       []trackedSpread    `json:"controllers"`
+	AssumedPlacements []trackedPlacement `json:"assumedPlacements"`
+}
+
+// spreadTracker keeps the last computed spread per controller group for the debug endpoint.
+type spreadTracker struct {
+	mu      sync.Mutex
+	entries map[string]trackedSpread
+}
+
+// newSpreadTracker returns an empty spreadTracker that drops the spreads older than
+// trackedSpreadTTL every trackedSpreadPruneInterval until ctx is done.
+func newSpreadTracker(ctx context.Context) *spreadTracker {
+	t := &spreadTracker{entries: make(map[string]trackedSpread)}
+	go wait.UntilWithContext(ctx, func(context.Context) { t.prune(time.Now()) }, trackedSpreadPruneInterval)
+	return t
+}
+
+// record stores the spread of the state's group. A nil tracker records nothing, which is the case
+// when the debug endpoint is disabled.
+func (t *spreadTracker) record(namespace string, s *controllerSpreadState, now time.Time) {
+	if t == nil {
+		return
+	}
+	entry := trackedSpread{
+		Namespace:  namespace,
+		Type:       s.controller.Type,
+		Name:       s.controller.Name,
+		UID:        s.groupKey,
+		NodeCounts: make(map[string]int, len(s.nodeCounts)),
+		Levels:     make([]trackedLevel, len(s.levels)),
+		OnePerNode: s.onePerNode,
+		ObservedAt: now,
+	}
+	for node, count := range s.nodeCounts {
+		entry.NodeCounts[node] = count
+	}
+	for i, level := range s.levels {
+		entry.Levels[i] = trackedLevel{TopologyKey: level.key, Required: level.required, DomainCounts: make(map[string]int, len(level.domainCounts))}
+		for domain, count := range level.domainCounts {
+			entry.Levels[i].DomainCounts[domain] = count
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[s.groupKey] = entry
+}
+
+// prune drops the spreads older than trackedSpreadTTL.
+func (t *spreadTracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, e := range t.entries {
+		if now.Sub(e.ObservedAt) > trackedSpreadTTL {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// list returns the tracked spreads sorted by namespace, type and name.
+func (t *spreadTracker) list() []trackedSpread {
+	t.mu.Lock()
+	result := make([]trackedSpread, 0, len(t.entries))
+	for _, e := range t.entries {
+		result = append(result, e)
+	}
+	t.mu.Unlock()
+
+	sortTrackedSpreads(result)
+	return result
+}
+
+// sortTrackedSpreads sorts the spreads by namespace, type and name.
+func sortTrackedSpreads(spreads []trackedSpread) {
+	sort.Slice(spreads, func(i, j int) bool {
+		if spreads[i].Namespace != spreads[j].Namespace {
+			return spreads[i].Namespace < spreads[j].Namespace
+		}
+		if spreads[i].Type != spreads[j].Type {
+			return spreads[i].Type < spreads[j].Type
+		}
+		return spreads[i].Name < spreads[j].Name
+	})
+}
+
+// list returns the unexpired assumed placements sorted by pod UID.
+func (a *assumedPods) list(now time.Time) []trackedPlacement {
+	a.mu.Lock()
+	result := make([]trackedPlacement, 0, len(a.placements))
+	for podUID, placement := range a.placements {
+		if now.After(placement.expires) {
+			continue
+		}
+		result = append(result, trackedPlacement{
+			PodUID:   string(podUID),
+			GroupKey: placement.groupKey,
+			NodeName: placement.nodeName,
+			Expires:  placement.expires,
+		})
+	}
+	a.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].PodUID < result[j].PodUID })
+	return result
+}
+
+// serveDebugEndpoint serves the spread state of the plugin on addr until ctx is done. The server
+// on addr is started by the first plugin instance and shared by the others. It returns an error if
+// the server cannot listen on addr, e.g. because the address is in use.
+func (csf *ControllerSpreadFilter) serveDebugEndpoint(ctx context.Context, addr string) error {
+	debugServersMu.Lock()
+	defer debugServersMu.Unlock()
+	s, ok := debugServers[addr]
+	if !ok {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("listening on debug endpoint %s: %w", addr, err)
+		}
+		s = &debugServer{plugins: make(map[*ControllerSpreadFilter]bool)}
+		mux := http.NewServeMux()
+		mux.HandleFunc(debugStatePath, s.handleDebugState)
+		s.server = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		debugServers[addr] = s
+		go func() {
+			klog.InfoS("Serving controller spread debug endpoint", "address", listener.Addr(), "path", debugStatePath)
+			if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				klog.ErrorS(err, "Controller spread debug endpoint stopped", "address", addr)
+				// Later plugin instances start a new server rather than attach to this one.
+				debugServersMu.Lock()
+				if debugServers[addr] == s {
+					delete(debugServers, addr)
+				}
+				debugServersMu.Unlock()
+			}
+		}()
+	}
+	s.plugins[csf] = true
+	go func() {
+		<-ctx.Done()
+		s.unregister(addr, csf)
+	}()
+	return nil
+}
+
+// unregister removes the plugin from the server on addr, and shuts the server down once no plugin
+// is left.
+func (s *debugServer) unregister(addr string, csf *ControllerSpreadFilter) {
+	debugServersMu.Lock()
+	delete(s.plugins, csf)
+	last := len(s.plugins) == 0
+	if last && debugServers[addr] == s {
+		delete(debugServers, addr)
+	}
+	debugServersMu.Unlock()
+	if !last {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), debugShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		klog.ErrorS(err, "Error shutting down controller spread debug endpoint", "address", addr)
+	}
+}
+
+// handleDebugState writes the tracked spreads and assumed placements of the registered plugins
+// as JSON.
+func (s *debugServer) handleDebugState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	debugServersMu.Lock()
+	plugins := make([]*ControllerSpreadFilter, 0, len(s.plugins))
+	for csf := range s.plugins {
+		plugins = append(plugins, csf)
+	}
+	debugServersMu.Unlock()
+
+	state := debugState{Controllers: []trackedSpread{}, AssumedPlacements: []trackedPlacement{}}
+	now := time.Now()
+	for _, csf := range plugins {
+		state.Controllers = append(state.Controllers, csf.tracker.list()...)
+		state.AssumedPlacements = append(state.AssumedPlacements, csf.assumed.list(now)...)
+	}
+	sortTrackedSpreads(state.Controllers)
+	sort.Slice(state.AssumedPlacements, func(i, j int) bool {
+		return state.AssumedPlacements[i].PodUID < state.AssumedPlacements[j].PodUID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		klog.ErrorS(err, "Error writing controller spread debug state")
+	}
+}
@@ -0,0 +1,138 @@
+package controllerspread
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+)
+
+// trackedState returns the state of a controller of the name for spreadTracker.record.
+func trackedState(name string) *controllerSpreadState {
+	return &controllerSpreadState{
+		controller: ControllerInfo{Type: DeploymentType, Name: name, UID: string(testUID(name))},
+		groupKey:   string(testUID(name)),
+		nodeCounts: map[string]int{"node-a": 1},
+	}
+}
+
+func TestSpreadTrackerPrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		ages map[string]time.Duration
+		want []string
+	}{
+		{
+			name: "recent spreads",
+			ages: map[string]time.Duration{"api": time.Minute, "web": trackedSpreadTTL},
+			want: []string{"api", "web"},
+		},
+		{
+			name: "expired spread",
+			ages: map[string]time.Duration{"api": time.Minute, "web": trackedSpreadTTL + time.Second},
+			want: []string{"api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newSpreadTracker(t.Context())
+			for name, age := range tt.ages {
+				tracker.record(testNamespace, trackedState(name), now.Add(-age))
+			}
+			tracker.prune(now)
+			var got []string
+			for _, spread := range tracker.list() {
+				got = append(got, spread.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("tracked spreads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServeDebugEndpoint(t *testing.T) {
+	const addr = "127.0.0.1:0"
+	ctxs := make([]context.Context, 2)
+	cancels := make([]context.CancelFunc, 2)
+	for i, name := range []string{"web", "api"} {
+		ctxs[i], cancels[i] = context.WithCancel(t.Context())
+		defer cancels[i]()
+		csf := &ControllerSpreadFilter{tracker: newSpreadTracker(ctxs[i]), assumed: newAssumedPods()}
+		csf.tracker.record(testNamespace, trackedState(name), time.Now())
+		if err := csf.serveDebugEndpoint(ctxs[i], addr); err != nil {
+			t.Fatalf("serveDebugEndpoint: %v", err)
+		}
+	}
+
+	debugServersMu.Lock()
+	s := debugServers[addr]
+	plugins := len(s.plugins)
+	debugServersMu.Unlock()
+	if plugins != 2 {
+		t.Fatalf("plugins served on %s = %d, want 2", addr, plugins)
+	}
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, debugStatePath, nil))
+	var state debugState
+	if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+		t.Fatalf("decoding debug state: %v", err)
+	}
+	var got []string
+	for _, spread := range state.Controllers {
+		got = append(got, spread.Name)
+	}
+	if diff := cmp.Diff([]string{"api", "web"}, got); diff != "" {
+		t.Errorf("served controllers (-want,+got):\n%s", diff)
+	}
+
+	served := func() bool {
+		debugServersMu.Lock()
+		defer debugServersMu.Unlock()
+		return debugServers[addr] == s
+	}
+	cancels[0]()
+	time.Sleep(10 * time.Millisecond)
+	if !served() {
+		t.Errorf("debug endpoint shut down while a plugin still uses it")
+	}
+	cancels[1]()
+	if err := wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		return !served(), nil
+	}); err != nil {
+		t.Errorf("debug endpoint not shut down after the last plugin's context was done")
+	}
+}
+
+func TestServeDebugEndpointAddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	addr := listener.Addr().String()
+
+	csf := &ControllerSpreadFilter{tracker: newSpreadTracker(t.Context()), assumed: newAssumedPods()}
+	if err := csf.serveDebugEndpoint(t.Context(), addr); err == nil {
+		t.Fatalf("serveDebugEndpoint() on an address in use succeeded, want an error")
+	}
+	debugServersMu.Lock()
+	_, served := debugServers[addr]
+	debugServersMu.Unlock()
+	if served {
+		t.Errorf("debug endpoint on %s registered after failing to listen", addr)
+	}
+
+	nodes := makeNodes("node-a")
+	if _, err := newWithListers(t.Context(), &ControllerSpreadArgs{DebugEndpoint: addr}, newTestFramework(t, nodes, nil), ptr.To(newTestListers(nodes))); err == nil {
+		t.Errorf("newWithListers() with the debug endpoint on an address in use succeeded, want an error")
+	}
+}
@@ -0,0 +1,221 @@
+// pkg/controllerspread/domain_extractor.go
+//
+// Pluggable topology schemes. Every topology level maps a node to a domain string through a
+// DomainExtractor. A topology key without a colon names a node label, as label keys cannot
+// contain a colon; a key of the form "<name>:<param>" selects the extractor registered under
+// name with RegisterDomainExtractor, e.g. "taint:example.com/maintenance-domain" or "zone:".
+// Builds of the scheduler can register their own schemes before the scheduler starts.
+package controllerspread
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DomainExtractor maps a node to its topology domain.
+type DomainExtractor interface {
+	// Domain returns the domain of the node, or "" if the node has none, in which case the node
+	// is treated as its own domain.
+	Domain(node *v1.Node) string
+}
+
+// DomainExtractorFactory builds the extractor of a "<name>:<param>" topology key from its param.
+type DomainExtractorFactory func(param string) (DomainExtractor, error)
+
+// Names of the built-in extractors.
+const (
+	labelDomainExtractor    = "label"
+	taintDomainExtractor    = "taint"
+	nodePoolDomainExtractor = "node-pool"
+	hostnameDomainExtractor = "hostname"
+	zoneDomainExtractor     = "zone"
+)
+
+var (
+	// domainExtractorsMu guards domainExtractors.
+	domainExtractorsMu sync.RWMutex
+	// domainExtractors are the topology schemes selectable by name in topology keys.
+	domainExtractors = map[string]DomainExtractorFactory{
+		labelDomainExtractor:    newLabelDomain,
+		taintDomainExtractor:    newTaintDomain,
+		nodePoolDomainExtractor: newNodePoolDomain,
+		hostnameDomainExtractor: newHostnameDomain,
+		zoneDomainExtractor:     newZoneDomain,
+	}
+)
+
+// RegisterDomainExtractor makes the topology scheme of the factory selectable by name in topology
+// keys of the form "<name>:<param>". It returns an error if the name is empty, contains a colon or
+// is already registered.
+func RegisterDomainExtractor(name string, factory DomainExtractorFactory) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid domain extractor name %q", name)
+	}
+	domainExtractorsMu.Lock()
+	defer domainExtractorsMu.Unlock()
+	if _, ok := domainExtractors[name]; ok {
+		return fmt.Errorf("domain extractor %q already registered", name)
+	}
+	domainExtractors[name] = factory
+	return nil
+}
+
+// nodePoolLabels are the node labels naming the pool of a node on common managed platforms.
+var nodePoolLabels = []string{
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"kubernetes.azure.com/agentpool",
+	"karpenter.sh/nodepool",
+}
+
+// labelDomain groups nodes by the value of a node label.
+type labelDomain struct {
+	key string
+}
+
+func (d labelDomain) Domain(node *v1.Node) string {
+	return node.Labels[d.key]
+}
+
+func newLabelDomain(param string) (DomainExtractor, error) {
+	if errs := validation.IsQualifiedName(param); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid label key %q: %s", param, strings.Join(errs, "; "))
+	}
+	return labelDomain{key: param}, nil
+}
+
+// taintDomain groups nodes by the value of the taint with a key, regardless of its effect, with
+// all nodes without the taint in untaintedDomain.
+type taintDomain struct {
+	key string
+}
+
+func (d taintDomain) Domain(node *v1.Node) string {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == d.key {
+			return taint.Value
+		}
+	}
+	return untaintedDomain
+}
+
+func newTaintDomain(param string) (DomainExtractor, error) {
+	if errs := validation.IsQualifiedName(param); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid taint key %q: %s", param, strings.Join(errs, "; "))
+	}
+	return taintDomain{key: param}, nil
+}
+
+// nodePoolDomain groups nodes by their pool: the value of the first of the labels the node
+// carries.
+type nodePoolDomain struct {
+	keys []string
+}
+
+func (d nodePoolDomain) Domain(node *v1.Node) string {
+	for _, key := range d.keys {
+		if val := node.Labels[key]; val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// newNodePoolDomain returns the pool extractor of the label key param, or of nodePoolLabels if
+// param is empty.
+func newNodePoolDomain(param string) (DomainExtractor, error) {
+	if param == "" {
+		return nodePoolDomain{keys: nodePoolLabels}, nil
+	}
+	if errs := validation.IsQualifiedName(param); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid node pool label key %q: %s", param, strings.Join(errs, "; "))
+	}
+	return nodePoolDomain{keys: []string{param}}, nil
+}
+
+// hostnameDomain makes every node its own domain, identified by the node name rather than the
+// hostname label, which need not be unique.
+type hostnameDomain struct{}
+
+func (hostnameDomain) Domain(node *v1.Node) string {
+	return node.Name
+}
+
+func newHostnameDomain(param string) (DomainExtractor, error) {
+	if param != "" {
+		return nil, fmt.Errorf("%s takes no parameter", hostnameDomainExtractor)
+	}
+	return hostnameDomain{}, nil
+}
+
+// zoneDomain groups nodes by zone, falling back to the deprecated beta zone label for nodes
+// without the topology.kubernetes.io/zone label.
+type zoneDomain struct{}
+
+func (zoneDomain) Domain(node *v1.Node) string {
+	if val := node.Labels[v1.LabelTopologyZone]; val != "" {
+		return val
+	}
+	return node.Labels[v1.LabelFailureDomainBetaZone]
+}
+
+func newZoneDomain(param string) (DomainExtractor, error) {
+	if param != "" {
+		return nil, fmt.Errorf("%s takes no parameter", zoneDomainExtractor)
+	}
+	return zoneDomain{}, nil
+}
+
+// newDomainExtractor parses a topology key into its extractor: a node label for keys without a
+// colon, the registered extractor of the name before the colon otherwise.
+func newDomainExtractor(topologyKey string) (DomainExtractor, error) {
+	name, param, scheme := strings.Cut(topologyKey, ":")
+	if !scheme {
+		return newLabelDomain(topologyKey)
+	}
+	domainExtractorsMu.RLock()
+	factory, ok := domainExtractors[name]
+	domainExtractorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown domain extractor %q", name)
+	}
+	return factory(param)
+}
+
+// maxResolvedDomainExtractors bounds resolvedDomainExtractors, as topology keys come from
+// controller annotations too.
+const maxResolvedDomainExtractors = 256
+
+var (
+	// resolvedDomainExtractorsMu guards resolvedDomainExtractors.
+	resolvedDomainExtractorsMu sync.RWMutex
+	// resolvedDomainExtractors caches the extractor of each valid topology key seen.
+	resolvedDomainExtractors = make(map[string]DomainExtractor)
+)
+
+// domainExtractorFor returns the extractor of the topology key. Keys that do not parse, e.g. an
+// invalid topology-key annotation, are not cached and are looked up as a node label, which no
+// node carries, so every node is its own domain; topologyLevels logs them once per cycle.
+func domainExtractorFor(topologyKey string) DomainExtractor {
+	resolvedDomainExtractorsMu.RLock()
+	d, ok := resolvedDomainExtractors[topologyKey]
+	resolvedDomainExtractorsMu.RUnlock()
+	if ok {
+		return d
+	}
+	d, err := newDomainExtractor(topologyKey)
+	if err != nil {
+		return labelDomain{key: topologyKey}
+	}
+	resolvedDomainExtractorsMu.Lock()
+	defer resolvedDomainExtractorsMu.Unlock()
+	if len(resolvedDomainExtractors) >= maxResolvedDomainExtractors {
+		clear(resolvedDomainExtractors)
+	}
+	resolvedDomainExtractors[topologyKey] = d
+	return d
+}
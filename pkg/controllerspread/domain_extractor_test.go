@@ -0,0 +1,184 @@
+package controllerspread
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+func TestNewDomainExtractor(t *testing.T) {
+	node := makeNode("node-a", map[string]string{
+		"rack":                        "rack-1",
+		v1.LabelFailureDomainBetaZone: "zone-beta",
+		"karpenter.sh/nodepool":       "pool-k",
+		"example.com/pool":            "pool-x",
+	})
+	node.Spec.Taints = []v1.Taint{{Key: "example.com/maintenance-domain", Value: "md-1", Effect: v1.TaintEffectPreferNoSchedule}}
+	tests := []struct {
+		name        string
+		topologyKey string
+		want        string
+		// wantErr is a substring of the expected error, or empty if the key is valid.
+		wantErr string
+	}{
+		{name: "plain label", topologyKey: "rack", want: "rack-1"},
+		{name: "missing label", topologyKey: v1.LabelTopologyRegion},
+		{name: "label scheme", topologyKey: "label:rack", want: "rack-1"},
+		{name: "taint", topologyKey: "taint:example.com/maintenance-domain", want: "md-1"},
+		{name: "missing taint", topologyKey: "taint:example.com/other", want: untaintedDomain},
+		{name: "node pool from platform labels", topologyKey: "node-pool:", want: "pool-k"},
+		{name: "node pool from label", topologyKey: "node-pool:example.com/pool", want: "pool-x"},
+		{name: "hostname", topologyKey: "hostname:", want: "node-a"},
+		{name: "zone falls back to beta label", topologyKey: "zone:", want: "zone-beta"},
+		{name: "invalid label key", topologyKey: "rack name", wantErr: `invalid label key "rack name"`},
+		{name: "invalid taint key", topologyKey: "taint:", wantErr: `invalid taint key ""`},
+		{name: "hostname with parameter", topologyKey: "hostname:rack", wantErr: "hostname takes no parameter"},
+		{name: "zone with parameter", topologyKey: "zone:rack", wantErr: "zone takes no parameter"},
+		{name: "unknown scheme", topologyKey: "rack:a", wantErr: `unknown domain extractor "rack"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newDomainExtractor(tt.topologyKey)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newDomainExtractor() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newDomainExtractor() error = %v", err)
+			}
+			if got := d.Domain(node); got != tt.want {
+				t.Errorf("Domain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZoneDomainPrefersZoneLabel(t *testing.T) {
+	node := makeNode("node-a", map[string]string{v1.LabelTopologyZone: "zone-a", v1.LabelFailureDomainBetaZone: "zone-beta"})
+	if got := (zoneDomain{}).Domain(node); got != "zone-a" {
+		t.Errorf("Domain() = %q, want %q", got, "zone-a")
+	}
+}
+
+// rackDomain groups nodes by the prefix of their name before the first dash.
+type rackDomain struct{}
+
+func (rackDomain) Domain(node *v1.Node) string {
+	rack, _, _ := strings.Cut(node.Name, "-")
+	return rack
+}
+
+func TestRegisteredDomainExtractor(t *testing.T) {
+	if err := RegisterDomainExtractor("test-rack", func(string) (DomainExtractor, error) { return rackDomain{}, nil }); err != nil {
+		t.Fatalf("RegisterDomainExtractor() error = %v", err)
+	}
+	t.Cleanup(func() {
+		domainExtractorsMu.Lock()
+		defer domainExtractorsMu.Unlock()
+		delete(domainExtractors, "test-rack")
+	})
+
+	nodes := makeNodes("r1-a", "r1-b", "r2-a", "r2-b")
+	objs := makeDeploymentPods(makeDeployment("web", 4, map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "2"}), "r1-a")
+	pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+	p := newTestPlugin(t, &ControllerSpreadArgs{TopologyKeys: []string{"test-rack:", "hostname:"}}, nodes, append(objs, pod)...)
+
+	if diff := cmp.Diff([]string{"r2-a", "r2-b"}, filterNodes(t, p, pod)); diff != "" {
+		t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+	}
+}
+
+func TestRegisterDomainExtractorErrors(t *testing.T) {
+	factory := func(string) (DomainExtractor, error) { return rackDomain{}, nil }
+	tests := []struct {
+		name    string
+		extName string
+		wantErr string
+	}{
+		{name: "empty name", wantErr: `invalid domain extractor name ""`},
+		{name: "name with colon", extName: "rack:a", wantErr: `invalid domain extractor name "rack:a"`},
+		{name: "built-in name", extName: zoneDomainExtractor, wantErr: `domain extractor "zone" already registered`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterDomainExtractor(tt.extName, factory); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("RegisterDomainExtractor() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDomainExtractorForInvalidKey(t *testing.T) {
+	node := makeNode("node-a", map[string]string{"rack": "rack-1"})
+	if got := topologyDomain(klog.Background(), node, "rack:a"); got != "node-a" {
+		t.Errorf("topologyDomain() = %q, want the node name %q", got, "node-a")
+	}
+	resolvedDomainExtractorsMu.RLock()
+	defer resolvedDomainExtractorsMu.RUnlock()
+	if _, ok := resolvedDomainExtractors["rack:a"]; ok {
+		t.Errorf("invalid topology key cached")
+	}
+}
+
+func TestDomainExtractorCacheBounded(t *testing.T) {
+	for i := range maxResolvedDomainExtractors + 10 {
+		domainExtractorFor(fmt.Sprintf("example.com/rack-%d", i))
+	}
+	resolvedDomainExtractorsMu.RLock()
+	defer resolvedDomainExtractorsMu.RUnlock()
+	if got := len(resolvedDomainExtractors); got > maxResolvedDomainExtractors {
+		t.Errorf("cached %d extractors, want at most %d", got, maxResolvedDomainExtractors)
+	}
+}
+
+func TestFilterDomainExtractors(t *testing.T) {
+	poolLabel := "cloud.google.com/gke-nodepool"
+	nodes := []*v1.Node{
+		makeNode("node-a1", map[string]string{poolLabel: "pool-a", v1.LabelFailureDomainBetaZone: "zone-a"}),
+		makeNode("node-a2", map[string]string{poolLabel: "pool-a", v1.LabelFailureDomainBetaZone: "zone-a"}),
+		makeNode("node-b1", map[string]string{poolLabel: "pool-b", v1.LabelFailureDomainBetaZone: "zone-b"}),
+		makeNode("node-b2", map[string]string{poolLabel: "pool-b", v1.LabelFailureDomainBetaZone: "zone-b"}),
+	}
+	tests := []struct {
+		name        string
+		args        ControllerSpreadArgs
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "node pools and hostnames",
+			args:        ControllerSpreadArgs{TopologyKeys: []string{"node-pool:", "hostname:"}},
+			annotations: map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "2"},
+			want:        []string{"node-b1", "node-b2"},
+		},
+		{
+			name:        "zones from beta label",
+			args:        ControllerSpreadArgs{TopologyKeys: []string{"zone:"}},
+			annotations: map[string]string{minHostsAnnotationKey: "2"},
+			want:        []string{"node-b1", "node-b2"},
+		},
+		{
+			name:        "hostnames",
+			args:        ControllerSpreadArgs{TopologyKeys: []string{"hostname:"}},
+			annotations: map[string]string{minHostsAnnotationKey: "4"},
+			want:        []string{"node-a2", "node-b1", "node-b2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 4, tt.annotations), "node-a1")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
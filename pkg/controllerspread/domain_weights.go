@@ -0,0 +1,140 @@
+// pkg/controllerspread/domain_weights.go
+//
+// Weighted topology domains for Score. When DomainWeightsConfigMap is set in the plugin args,
+// the referenced ConfigMap names a topology key and assigns a relative weight to each of its
+// domains (e.g. zones of different capacity). Score then favors domains whose pod count is
+// low relative to their weight, so bigger domains receive proportionally more pods. Filter is
+// not affected. The ConfigMap is watched and changes apply to the next scheduling cycle.
+package controllerspread
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// domainWeightsTopologyKey is the ConfigMap data key naming the node label whose domains are
+	// weighted. All other data keys are domain values with their weight.
+	domainWeightsTopologyKey = "topologyKey"
+
+	// defaultDomainWeight is the weight of domains that are not listed in the ConfigMap.
+	defaultDomainWeight = 1
+)
+
+// ConfigMapReference identifies a ConfigMap.
+type ConfigMapReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// domainWeights is a parsed domain weights ConfigMap.
+type domainWeights struct {
+	// topologyKey is the node label whose domains are weighted.
+	topologyKey string
+	// weights is the weight per domain value.
+	weights map[string]int64
+}
+
+// weight returns the weight of the domain.
+func (w *domainWeights) weight(domain string) int64 {
+	if weight, ok := w.weights[domain]; ok {
+		return weight
+	}
+	return defaultDomainWeight
+}
+
+// domainWeightsLoader keeps the domain weights up to date with the watched ConfigMap.
+type domainWeightsLoader struct {
+	ref ConfigMapReference
+	// hasSynced reports whether the ConfigMap informer has synced.
+	hasSynced cache.InformerSynced
+
+	mu      sync.RWMutex
+	current *domainWeights
+}
+
+// newDomainWeightsLoader starts watching the referenced ConfigMap. It returns nil if no
+// ConfigMap is configured. The informer runs for the lifetime of the scheduler process.
+func newDomainWeightsLoader(ref *ConfigMapReference, handle framework.Handle) *domainWeightsLoader {
+	if ref == nil {
+		return nil
+	}
+	l := &domainWeightsLoader{ref: *ref}
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(handle.ClientSet(), 0,
+		informers.WithNamespace(ref.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", ref.Name).String()
+		}))
+	informer := informerFactory.Core().V1().ConfigMaps().Informer()
+	l.hasSynced = informer.HasSynced
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    l.update,
+		UpdateFunc: func(_, newObj interface{}) { l.update(newObj) },
+		DeleteFunc: func(interface{}) { l.set(nil) },
+	}); err != nil {
+		klog.ErrorS(err, "Failed to watch domain weights ConfigMap", "configMap", klog.KRef(ref.Namespace, ref.Name))
+		return nil
+	}
+	informerFactory.Start(wait.NeverStop)
+	return l
+}
+
+// update parses the ConfigMap. An invalid ConfigMap is logged and disables the weights.
+func (l *domainWeightsLoader) update(obj interface{}) {
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	weights, err := parseDomainWeights(cm)
+	if err != nil {
+		klog.ErrorS(err, "Ignoring invalid domain weights ConfigMap", "configMap", klog.KObj(cm))
+	}
+	l.set(weights)
+}
+
+// set replaces the current weights.
+func (l *domainWeightsLoader) set(weights *domainWeights) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.current = weights
+}
+
+// get returns the current weights, or nil if none are loaded. A nil loader has no weights.
+func (l *domainWeightsLoader) get() *domainWeights {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.current
+}
+
+// parseDomainWeights parses the topology key and the positive integer weights of the ConfigMap.
+func parseDomainWeights(cm *v1.ConfigMap) (*domainWeights, error) {
+	topologyKey := cm.Data[domainWeightsTopologyKey]
+	if topologyKey == "" {
+		return nil, fmt.Errorf("missing %q", domainWeightsTopologyKey)
+	}
+	weights := &domainWeights{topologyKey: topologyKey, weights: make(map[string]int64, len(cm.Data))}
+	for domain, val := range cm.Data {
+		if domain == domainWeightsTopologyKey {
+			continue
+		}
+		weight, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("weight of domain %q must be a positive integer, got %q", domain, val)
+		}
+		weights.weights[domain] = weight
+	}
+	return weights, nil
+}
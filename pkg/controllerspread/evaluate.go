@@ -0,0 +1,205 @@
+// pkg/controllerspread/evaluate.go
+//
+// Pure spread decision logic. evaluateSpread decides on a candidate node from the precomputed
+// distribution alone, without listers or framework types, so that Filter and offline what-if
+// analysis (EvaluateSpread) share the same rules.
+package controllerspread
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PodPlacement is an existing pod of a controller. NodeName is empty for pending pods that are
+// not yet bound to a node.
+type PodPlacement struct {
+	Name     string
+	NodeName string
+}
+
+// Reason codes prefixing the messages of statuses that reject a node, so that tools parsing
+// scheduler output can match them.
+const (
+	// ReasonSpreadConstraintViolated marks a node that violates the controller's spread constraint.
+	ReasonSpreadConstraintViolated = "SpreadConstraintViolated"
+	// ReasonExternalPolicyRejected marks a node rejected by the external spread policy.
+	ReasonExternalPolicyRejected = "ExternalPolicyRejected"
+)
+
+// Rules of the spread constraint that may reject a node, reported in Decision.Rule.
+const (
+	// RuleOnePerNode allows at most one pod per node, e.g. for DaemonSets.
+	RuleOnePerNode = "OnePerNode"
+	// RuleMaxPodsPerNode caps the pods per node (max-pods-per-node annotation).
+	RuleMaxPodsPerNode = "MaxPodsPerNode"
+	// RuleMinDomains requires a new domain while the spread is below its minimum.
+	RuleMinDomains = "MinDomains"
+	// RuleMaxSkew caps the skew between domains (max-skew annotation).
+	RuleMaxSkew = "MaxSkew"
+	// RuleReserveDomains keeps free domains for the unplaced pods (reserve-domains annotation).
+	RuleReserveDomains = "ReserveDomains"
+)
+
+// Decision is the outcome of the spread check for a candidate node.
+type Decision struct {
+	// Allowed reports whether the pod may be placed on the candidate node.
+	Allowed bool
+	// Reason explains a rejection.
+	Reason string
+	// Rule is the rule that rejected the node.
+	Rule string
+	// TopologyKey is the topology key of the level the rule was checked at.
+	TopologyKey string
+	// Required is the limit or minimum of the rule, and Current the value the placement was
+	// checked against: the pods on the node, the number of domains, or the skew.
+	Required int
+	Current  int
+}
+
+// Message formats a rejection with its reason code and structured fields, followed by the
+// human-readable reason, e.g.
+// "SpreadConstraintViolated: rule=MinDomains topologyKey=kubernetes.io/hostname required=3 current=2; must ...".
+func (d Decision) Message() string {
+	return fmt.Sprintf("%s: rule=%s topologyKey=%s required=%d current=%d; %s",
+		ReasonSpreadConstraintViolated, d.Rule, d.TopologyKey, d.Required, d.Current, d.Reason)
+}
+
+// RequiredHosts returns the minimum number of distinct domains the pods of a controller with the
+// annotations and the desired replica count must span: the min-hosts annotation, or
+// defaultMinHosts without one, capped at the desired count. It returns an error, and the
+// requirement for defaultMinHosts, if the annotation is invalid.
+func RequiredHosts(annotations map[string]string, desired, defaultMinHosts int32) (int32, error) {
+	minHosts := defaultMinHosts
+	var err error
+	if val, exists := annotations[minHostsAnnotationKey]; exists {
+		minHosts, err = parseMinHostsAnnotation(val, desired, defaultMinHosts)
+	}
+	return min(desired, minHosts), err
+}
+
+// StrictSpread reports whether a controller with the annotations requires each of its pods, up to
+// the desired count, on a distinct domain of the last level. Unlike the min-hosts requirement, the
+// strict requirement is not lowered to the feasible number of domains. Invalid values are ignored.
+func StrictSpread(annotations map[string]string) bool {
+	strict, err := strconv.ParseBool(annotations[strictAnnotationKey])
+	return err == nil && strict
+}
+
+// TopologyKey returns the node label across which the pods of a controller with the annotations
+// are spread if it selects one with the topology-key annotation, and defaultKey otherwise.
+func TopologyKey(annotations map[string]string, defaultKey string) string {
+	if val := annotations[topologyKeyAnnotationKey]; val != "" {
+		return val
+	}
+	return defaultKey
+}
+
+// EvaluateSpread decides whether a pod of the controller may be placed on the candidate node,
+// given the desired replica count, the minimum number of hosts (e.g. from the min-hosts
+// annotation) and the placements of its existing pods. It applies the same rules as Filter for
+// a controller spread across hostnames, without consulting the cluster.
+func EvaluateSpread(controller ControllerInfo, desired, minHosts int32, existing []PodPlacement, candidate string) Decision {
+	if desired <= 1 {
+		return Decision{Allowed: true}
+	}
+	nodeCounts := make(map[string]int)
+	for _, p := range existing {
+		if p.NodeName != "" {
+			nodeCounts[p.NodeName]++
+		}
+	}
+	s := &controllerSpreadState{
+		controller:     controller,
+		scheduledPeers: sumCounts(nodeCounts),
+		nodeCounts:     nodeCounts,
+		levels:         []topologyLevel{{key: defaultTopologyKey, required: min(desired, minHosts), domainCounts: nodeCounts}},
+		onePerNode:     controller.Type == DaemonSetType,
+	}
+	return evaluateSpread(s, candidate, []string{candidate})
+}
+
+// evaluateSpread decides whether the pod may be placed on the candidate node.
+// candidateDomains holds the domain of the candidate node at each of the state's levels.
+func evaluateSpread(s *controllerSpreadState, candidate string, candidateDomains []string) Decision {
+	controller := s.controller
+
+	if s.onePerNode {
+		if s.nodeCounts[candidate] > 0 {
+			return Decision{Reason: fmt.Sprintf("node already runs a pod of %s %s", controller.Type, controller.Name),
+				Rule: RuleOnePerNode, TopologyKey: defaultTopologyKey, Required: 1, Current: s.nodeCounts[candidate]}
+		}
+		return Decision{Allowed: true}
+	}
+
+	if s.maxPodsPerNode > 0 && s.nodeCounts[candidate]+1 > int(s.maxPodsPerNode) {
+		return Decision{Reason: fmt.Sprintf("must not schedule more than %d pods per node", s.maxPodsPerNode),
+			Rule: RuleMaxPodsPerNode, TopologyKey: defaultTopologyKey, Required: int(s.maxPodsPerNode), Current: s.nodeCounts[candidate]}
+	}
+
+	// With reserve-domains, the free domains left for the unplaced pods replace the minimum of
+	// occupied domains. Unlike the minimum, it constrains the first placement too.
+	if s.reserveDomains > 0 {
+		if s.scheduledPeers < int(s.spreadAfter) {
+			return Decision{Allowed: true}
+		}
+		if decision := reserveDomainsDecision(s, candidateDomains[len(candidateDomains)-1]); !decision.Allowed {
+			return decision
+		}
+		return maxSkewDecision(s, candidateDomains)
+	}
+
+	// Only peers bound to a node (or assumed onto one) occupy a domain. Pending peers without a
+	// node are part of s.controllerPods but must not prevent the first placement. With a
+	// spread-after warmup, the first spreadAfter placements are unconstrained as well.
+	if s.scheduledPeers == 0 || s.scheduledPeers < int(s.spreadAfter) {
+		return Decision{Allowed: true}
+	}
+
+	// While the spread of a level is below its minimum, each new pod must land in a domain of
+	// that level that does not yet run a peer. Once the minimum is reached, any domain is
+	// acceptable. Every level must be satisfied, or with anyLevel, at least one of them.
+	var unmet []Decision
+	for i, level := range s.levels {
+		_, occupied := level.domainCounts[candidateDomains[i]]
+		if !occupied || len(level.domainCounts) >= int(level.required) {
+			if s.anyLevel {
+				unmet = nil
+				break
+			}
+			continue
+		}
+		decision := Decision{Reason: fmt.Sprintf("must schedule across at least %d distinct nodes", level.required),
+			Rule: RuleMinDomains, TopologyKey: level.key, Required: int(level.required), Current: len(level.domainCounts)}
+		if level.key != defaultTopologyKey {
+			decision.Reason = fmt.Sprintf("must schedule across at least %d distinct %s domains", level.required, level.key)
+		}
+		if !s.anyLevel {
+			return decision
+		}
+		unmet = append(unmet, decision)
+	}
+	if len(unmet) > 0 {
+		// The first level is reported, with the alternatives the pod could have met.
+		decision := unmet[0]
+		for _, alternative := range unmet[1:] {
+			decision.Reason += " or " + strings.TrimPrefix(alternative.Reason, "must schedule ")
+		}
+		return decision
+	}
+
+	return maxSkewDecision(s, candidateDomains)
+}
+
+// maxSkewDecision rejects the placement if it would make the skew of any level exceed maxSkew.
+func maxSkewDecision(s *controllerSpreadState, candidateDomains []string) Decision {
+	if s.maxSkew > 0 {
+		for i, level := range s.levels {
+			if skew := skewAfterPlacement(level, candidateDomains[i]); skew > int(s.maxSkew) {
+				return Decision{Reason: fmt.Sprintf("placement would make the %s skew %d, exceeding max skew %d", level.key, skew, s.maxSkew),
+					Rule: RuleMaxSkew, TopologyKey: level.key, Required: int(s.maxSkew), Current: skew}
+			}
+		}
+	}
+	return Decision{Allowed: true}
+}
@@ -0,0 +1,90 @@
+// pkg/controllerspread/events.go
+//
+// Kubernetes Events for pods rejected by the spread constraint. Filter runs once per candidate
+// node, so the event is emitted from PostFilter, once the scheduling attempt failed, and
+// summarizes the nodes the constraint rejected. A pod is retried many times while it stays
+// pending, so events are emitted at most once per eventDedupeInterval for each pod.
+package controllerspread
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// failedSpreadReason is the reason of events emitted when a pod is rejected for spread.
+	failedSpreadReason = "FailedSpread"
+
+	// eventDedupeInterval is how long further events for the same pod are suppressed.
+	eventDedupeInterval = 5 * time.Minute
+)
+
+// spreadEventRecorder emits FailedSpread events, suppressing duplicates.
+type spreadEventRecorder struct {
+	recorder events.EventRecorder
+
+	mu sync.Mutex
+	// recent is the time of the last event emitted for each pod.
+	recent map[types.UID]time.Time
+	swept  time.Time
+}
+
+// newSpreadEventRecorder returns a spreadEventRecorder that emits events through the recorder.
+func newSpreadEventRecorder(recorder events.EventRecorder) *spreadEventRecorder {
+	return &spreadEventRecorder{recorder: recorder, recent: make(map[types.UID]time.Time)}
+}
+
+// recordFailedSpread emits a Warning event on the pod unless an event was emitted for it within
+// eventDedupeInterval. Expired entries of other pods are dropped at most once per interval.
+func (r *spreadEventRecorder) recordFailedSpread(pod *v1.Pod, message string, now time.Time) {
+	if r.recorder == nil {
+		return
+	}
+
+	r.mu.Lock()
+	if last, ok := r.recent[pod.UID]; ok && now.Sub(last) < eventDedupeInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.recent[pod.UID] = now
+	if now.Sub(r.swept) >= eventDedupeInterval {
+		r.sweepLocked(now)
+	}
+	r.mu.Unlock()
+
+	r.recorder.Eventf(pod, nil, v1.EventTypeWarning, failedSpreadReason, "Scheduling", message)
+}
+
+// sweepLocked drops the entries older than eventDedupeInterval.
+func (r *spreadEventRecorder) sweepLocked(now time.Time) {
+	for uid, last := range r.recent {
+		if now.Sub(last) >= eventDedupeInterval {
+			delete(r.recent, uid)
+		}
+	}
+	r.swept = now
+}
+
+// failedSpreadMessage summarizes the nodes the named plugin rejected for the FailedSpread event:
+// their number, the rejection of the first of them by name, and the current spread of the levels.
+func failedSpreadMessage(filteredNodeStatusMap framework.NodeToStatusMap, plugin string, levels []topologyLevel) string {
+	var rejected []string
+	for nodeName, status := range filteredNodeStatusMap {
+		if status.Plugin() == plugin {
+			rejected = append(rejected, nodeName)
+		}
+	}
+	if len(rejected) == 0 {
+		return ""
+	}
+	sort.Strings(rejected)
+	return fmt.Sprintf("%d of %d nodes rejected by the spread constraint, e.g. %s: %s; current spread is %s", len(rejected),
+		len(filteredNodeStatusMap), rejected[0], filteredNodeStatusMap[rejected[0]].Message(), describeSpread(levels))
+}
@@ -0,0 +1,146 @@
+package controllerspread
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestRecordFailedSpread(t *testing.T) {
+	start := time.Now()
+	type record struct {
+		pod     string
+		message string
+		after   time.Duration
+	}
+	tests := []struct {
+		name       string
+		records    []record
+		wantEvents int
+		wantRecent int
+	}{
+		{
+			name:       "first rejection",
+			records:    []record{{pod: "a", message: "rejected"}},
+			wantEvents: 1,
+			wantRecent: 1,
+		},
+		{
+			name: "other message for the same pod within the interval",
+			records: []record{
+				{pod: "a", message: "rejected on node-a"},
+				{pod: "a", message: "rejected on node-b", after: time.Minute},
+			},
+			wantEvents: 1,
+			wantRecent: 1,
+		},
+		{
+			name: "same pod after the interval",
+			records: []record{
+				{pod: "a", message: "rejected"},
+				{pod: "a", message: "rejected", after: eventDedupeInterval},
+			},
+			wantEvents: 2,
+			wantRecent: 1,
+		},
+		{
+			name: "other pods",
+			records: []record{
+				{pod: "a", message: "rejected"},
+				{pod: "b", message: "rejected"},
+			},
+			wantEvents: 2,
+			wantRecent: 2,
+		},
+		{
+			name: "expired pods are dropped once an interval passed",
+			records: []record{
+				{pod: "a", message: "rejected"},
+				{pod: "b", message: "rejected", after: time.Minute},
+				{pod: "c", message: "rejected", after: eventDedupeInterval + time.Second},
+			},
+			wantEvents: 3,
+			wantRecent: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := events.NewFakeRecorder(10)
+			r := newSpreadEventRecorder(recorder)
+			for _, rec := range tt.records {
+				pod := makePod(rec.pod, "", ownerRef(ReplicaSetType, "web"))
+				pod.UID = types.UID(rec.pod)
+				r.recordFailedSpread(pod, rec.message, start.Add(rec.after))
+			}
+			if got := len(recorder.Events); got != tt.wantEvents {
+				t.Errorf("recorded %d events, want %d", got, tt.wantEvents)
+			}
+			if got := len(r.recent); got != tt.wantRecent {
+				t.Errorf("remembered %d pods, want %d", got, tt.wantRecent)
+			}
+		})
+	}
+}
+
+func TestPostFilterRecordsFailedSpread(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name       string
+		replicas   int32
+		peerNodes  []string
+		wantEvents int
+	}{
+		{
+			name:       "spread met, nodes rejected by other plugins",
+			replicas:   3,
+			peerNodes:  []string{"node-a", "node-b", "node-c"},
+			wantEvents: 0,
+		},
+		{
+			name:       "nodes rejected by the spread",
+			replicas:   4,
+			peerNodes:  []string{"node-a", "node-b"},
+			wantEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := makeDeployment("web", tt.replicas, map[string]string{minHostsAnnotationKey: "3"})
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, makeDeploymentPods(deploy, tt.peerNodes...)...)
+			recorder := p.handle.EventRecorder().(*events.FakeRecorder)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+
+			for attempt := 0; attempt < 2; attempt++ {
+				state, status := preFilter(t, p, pod)
+				if !status.IsSuccess() {
+					t.Fatalf("PreFilter: %v", status)
+				}
+				nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+				if err != nil {
+					t.Fatalf("listing nodes: %v", err)
+				}
+				recorded := len(recorder.Events)
+				statuses := make(framework.NodeToStatusMap)
+				for _, nodeInfo := range nodeInfos {
+					status := p.Filter(t.Context(), state, pod, nodeInfo)
+					if !status.IsSuccess() {
+						statuses[nodeInfo.Node().Name] = status.WithPlugin(p.Name())
+					} else {
+						// Rejected by another plugin, e.g. for lack of resources.
+						statuses[nodeInfo.Node().Name] = framework.NewStatus(framework.Unschedulable, "Insufficient cpu").WithPlugin("NodeResourcesFit")
+					}
+				}
+				if got := len(recorder.Events) - recorded; got != 0 {
+					t.Fatalf("Filter recorded %d events", got)
+				}
+				p.PostFilter(t.Context(), state, pod, statuses)
+			}
+			if got := len(recorder.Events); got != tt.wantEvents {
+				t.Errorf("recorded %d events, want %d", got, tt.wantEvents)
+			}
+		})
+	}
+}
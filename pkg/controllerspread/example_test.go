@@ -0,0 +1,82 @@
+package controllerspread_test
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/controller-spread-scheduler/pkg/controllerspread"
+)
+
+// snapshot is a framework.SharedLister over a fixed set of nodes.
+type snapshot struct {
+	nodeInfos tf.NodeInfoLister
+}
+
+func (s snapshot) NodeInfos() framework.NodeInfoLister       { return s.nodeInfos }
+func (s snapshot) StorageInfos() framework.StorageInfoLister { return nil }
+
+// A Deployment of 2 replicas, one of which runs on node-a, may only place the other on node-b.
+func ExampleNewWithListers() {
+	ctx := context.Background()
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			_ = indexer.Add(obj)
+		}
+		return indexer
+	}
+
+	nodeA := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{v1.LabelHostname: "node-a"}}}
+	nodeB := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{v1.LabelHostname: "node-b"}}}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f", Namespace: "default", UID: "web-5d8f-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "web-uid"}}}}
+	owner := []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f", UID: "web-5d8f-uid"}}
+	running := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f-1", Namespace: "default", UID: "pod-1", OwnerReferences: owner},
+		Spec: v1.PodSpec{NodeName: "node-a"}, Status: v1.PodStatus{Phase: v1.PodRunning}}
+	pending := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f-2", Namespace: "default", UID: "pod-2", OwnerReferences: owner},
+		Status: v1.PodStatus{Phase: v1.PodPending}}
+
+	listers := controllerspread.Listers{
+		Pods:        corelisters.NewPodLister(newIndexer(running, pending)),
+		Deployments: appslisters.NewDeploymentLister(newIndexer(deploy)),
+		ReplicaSets: appslisters.NewReplicaSetLister(newIndexer(rs)),
+		Nodes:       corelisters.NewNodeLister(newIndexer(nodeA, nodeB)),
+	}
+	nodeInfos := tf.BuildNodeInfos([]*v1.Node{nodeA, nodeB})
+	nodeInfos[0].AddPod(running)
+	handle, err := frameworkruntime.NewFramework(ctx, nil, nil, frameworkruntime.WithSnapshotSharedLister(snapshot{nodeInfos: nodeInfos}))
+	if err != nil {
+		panic(err)
+	}
+
+	plugin, err := controllerspread.NewWithListers(&controllerspread.ControllerSpreadArgs{}, handle, listers)
+	if err != nil {
+		panic(err)
+	}
+	state := framework.NewCycleState()
+	if _, status := plugin.(framework.PreFilterPlugin).PreFilter(ctx, state, pending); !status.IsSuccess() {
+		panic(status.AsError())
+	}
+	for _, nodeInfo := range nodeInfos {
+		status := plugin.(framework.FilterPlugin).Filter(ctx, state, pending, nodeInfo)
+		fmt.Printf("%s: %s\n", nodeInfo.Node().Name, status.Code())
+	}
+	// Output:
+	// node-a: Unschedulable
+	// node-b: Success
+}
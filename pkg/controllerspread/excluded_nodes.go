@@ -0,0 +1,32 @@
+// pkg/controllerspread/excluded_nodes.go
+//
+// Nodes excluded from spread accounting. Nodes matching ExcludedNodeSelector in the plugin args,
+// e.g. build or CI nodes, are not valid spread domains: pods on them are not counted toward the
+// spread of their controller, their domains are not eligible, and placing a pod on one is not
+// checked against the spread.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// isExcludedNode reports whether the node matches ExcludedNodeSelector.
+func (csf *ControllerSpreadFilter) isExcludedNode(node *v1.Node) bool {
+	return csf.excludedNodes != nil && csf.excludedNodes.Matches(labels.Set(node.Labels))
+}
+
+// withoutExcludedNodes removes the excluded nodes from the per-node pod counts. Nodes that cannot
+// be resolved through the node lister are kept.
+func (csf *ControllerSpreadFilter) withoutExcludedNodes(nodeCounts map[string]int) map[string]int {
+	if csf.excludedNodes == nil {
+		return nodeCounts
+	}
+	for nodeName := range nodeCounts {
+		node, err := csf.nodeLister.Get(nodeName)
+		if err == nil && csf.isExcludedNode(node) {
+			delete(nodeCounts, nodeName)
+		}
+	}
+	return nodeCounts
+}
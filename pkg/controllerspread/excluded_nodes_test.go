@@ -0,0 +1,89 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExcludedNodes(t *testing.T) {
+	ciSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"node-role.example.com/ci": "true"}}
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+		// wantLowest reports whether the CI node gets the lowest score.
+		wantLowest bool
+	}{
+		{
+			name: "CI node counted",
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:       "CI node excluded",
+			args:       ControllerSpreadArgs{ExcludedNodeSelector: ciSelector},
+			want:       []string{"node-b", "node-c", "node-ci"},
+			wantLowest: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []*v1.Node{
+				makeNode("node-a", nil),
+				makeNode("node-b", nil),
+				makeNode("node-c", nil),
+				makeNode("node-ci", map[string]string{"node-role.example.com/ci": "true"}),
+			}
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a", "node-ci")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+			scores := scoreNodes(t, p, pod)
+			lowest := true
+			for name, score := range scores {
+				if name != "node-ci" && score <= scores["node-ci"] {
+					lowest = false
+				}
+			}
+			if lowest != tt.wantLowest {
+				t.Errorf("node-ci scored lowest = %v, want %v, scores: %v", lowest, tt.wantLowest, scores)
+			}
+		})
+	}
+}
+
+func TestWithoutExcludedNodes(t *testing.T) {
+	nodes := []*v1.Node{
+		makeNode("node-a", nil),
+		makeNode("node-ci", map[string]string{"node-role.example.com/ci": "true"}),
+	}
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		want     map[string]int
+	}{
+		{
+			name: "no selector",
+			want: map[string]int{"node-a": 1, "node-ci": 2, "node-gone": 1},
+		},
+		{
+			name:     "selector",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"node-role.example.com/ci": "true"}},
+			want:     map[string]int{"node-a": 1, "node-gone": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{ExcludedNodeSelector: tt.selector}, nodes)
+			got := p.withoutExcludedNodes(map[string]int{"node-a": 1, "node-ci": 2, "node-gone": 1})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withoutExcludedNodes() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
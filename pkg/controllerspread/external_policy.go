@@ -0,0 +1,164 @@
+// pkg/controllerspread/external_policy.go
+//
+// Optional delegation of the spread decision to an external placement service. When
+// ExternalPolicyEndpoint is set in the plugin args, Filter POSTs the pod's controller, the
+// candidate node, the current distribution and the plugin's own verdict to the endpoint as JSON,
+// and honors the verdict it returns.
+package controllerspread
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// FailurePolicy controls how errors of the external policy endpoint are handled.
+type FailurePolicy string
+
+const (
+	// FailurePolicyIgnore accepts the node when the endpoint cannot be reached or answers
+	// invalidly (fail open).
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+	// FailurePolicyFail rejects the node when the endpoint cannot be reached or answers
+	// invalidly (fail closed).
+	FailurePolicyFail FailurePolicy = "Fail"
+
+	// defaultExternalPolicyTimeout bounds each call to the external policy endpoint.
+	defaultExternalPolicyTimeout = time.Second
+
+	// maxExternalPolicyResponseBytes bounds the size of a response read from the endpoint.
+	maxExternalPolicyResponseBytes = 64 << 10
+)
+
+// ExternalPolicyRequest is the JSON body sent to the external policy endpoint.
+type ExternalPolicyRequest struct {
+	// Namespace and Pod identify the pod being scheduled.
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// Controller is the group of peers of the pod.
+	Controller ExternalPolicyController `json:"controller"`
+	// CandidateNode is the node being filtered.
+	CandidateNode string `json:"candidateNode"`
+	// NodeCounts is the number of peers per node, including in-flight placements.
+	NodeCounts map[string]int `json:"nodeCounts"`
+	// Levels is the current and required spread per topology level.
+	Levels []ExternalPolicyLevel `json:"levels"`
+	// Allowed and Reason are the verdict of the plugin's own spread check.
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ExternalPolicyController identifies the controller of the pod being scheduled.
+type ExternalPolicyController struct {
+	Type ControllerType `json:"type"`
+	Name string         `json:"name"`
+	UID  string         `json:"uid"`
+}
+
+// ExternalPolicyLevel is the spread of the controller at one topology level.
+type ExternalPolicyLevel struct {
+	TopologyKey  string         `json:"topologyKey"`
+	Required     int32          `json:"required"`
+	DomainCounts map[string]int `json:"domainCounts"`
+}
+
+// ExternalPolicyResponse is the JSON body expected from the external policy endpoint.
+type ExternalPolicyResponse struct {
+	// Allowed is the final verdict for the candidate node.
+	Allowed bool `json:"allowed"`
+	// Reason explains a rejection; it is surfaced in the pod's scheduling status.
+	Reason string `json:"reason,omitempty"`
+}
+
+// externalPolicy is a client of the external policy endpoint.
+type externalPolicy struct {
+	plugin        string
+	endpoint      string
+	failurePolicy FailurePolicy
+	client        *http.Client
+}
+
+// newExternalPolicy returns a client of the endpoint, or nil if no endpoint is configured.
+func newExternalPolicy(args *ControllerSpreadArgs) *externalPolicy {
+	if args.ExternalPolicyEndpoint == "" {
+		return nil
+	}
+	return &externalPolicy{
+		plugin:        args.PluginName,
+		endpoint:      args.ExternalPolicyEndpoint,
+		failurePolicy: args.ExternalPolicyFailurePolicy,
+		client:        &http.Client{Timeout: args.ExternalPolicyTimeout.Duration},
+	}
+}
+
+// evaluate asks the endpoint for the verdict on the node, given the plugin's own status.
+func (e *externalPolicy) evaluate(ctx context.Context, pod *v1.Pod, s *controllerSpreadState, nodeName string, local *framework.Status) *framework.Status {
+	request := ExternalPolicyRequest{
+		Namespace:     pod.Namespace,
+		Pod:           pod.Name,
+		Controller:    ExternalPolicyController{Type: s.controller.Type, Name: s.controller.Name, UID: s.controller.UID},
+		CandidateNode: nodeName,
+		NodeCounts:    s.nodeCounts,
+		Levels:        make([]ExternalPolicyLevel, len(s.levels)),
+		Allowed:       local.IsSuccess(),
+		Reason:        local.Message(),
+	}
+	for i, level := range s.levels {
+		request.Levels[i] = ExternalPolicyLevel{TopologyKey: level.key, Required: level.required, DomainCounts: level.domainCounts}
+	}
+
+	response, err := e.call(ctx, &request)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "External spread policy failed", "endpoint", e.endpoint, "pod", klog.KObj(pod), "node", nodeName,
+			"failurePolicy", e.failurePolicy)
+		externalPolicyErrors.WithLabelValues(e.plugin).Inc()
+		if e.failurePolicy == FailurePolicyFail {
+			return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("%s: external spread policy failed: %v", ReasonExternalPolicyRejected, err))
+		}
+		return framework.NewStatus(framework.Success)
+	}
+	if !response.Allowed {
+		reason := response.Reason
+		if reason == "" {
+			reason = "rejected by external spread policy"
+		}
+		return framework.NewStatus(framework.Unschedulable, ReasonExternalPolicyRejected+": "+reason)
+	}
+	return framework.NewStatus(framework.Success)
+}
+
+// call POSTs the request to the endpoint and decodes its response.
+func (e *externalPolicy) call(ctx context.Context, request *ExternalPolicyRequest) (*ExternalPolicyResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := e.client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", httpResponse.Status)
+	}
+
+	var response ExternalPolicyResponse
+	if err := json.NewDecoder(io.LimitReader(httpResponse.Body, maxExternalPolicyResponseBytes)).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &response, nil
+}
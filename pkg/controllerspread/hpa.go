@@ -0,0 +1,70 @@
+// pkg/controllerspread/hpa.go
+//
+// HPA-aware desired count for ControllerSpreadFilter. While a HorizontalPodAutoscaler scales a
+// controller, the controller's replica count lags the autoscaler's decision, so the min-hosts
+// clamp would use a stale count. With HPAAware set, the desired count is the larger of the
+// controller's replica count and the desired replicas of an HPA targeting it.
+package controllerspread
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+// scalableControllerTypes are the built-in controller types an HPA can target.
+var scalableControllerTypes = map[ControllerType]bool{
+	DeploymentType:            true,
+	ReplicaSetType:            true,
+	StatefulSetType:           true,
+	ReplicationControllerType: true,
+}
+
+// hpaDesiredReplicas returns the larger of desired and the desired replicas of an HPA targeting
+// the controller. It returns desired unchanged when HPAAware is not set or no HPA targets the
+// controller.
+func (csf *ControllerSpreadFilter) hpaDesiredReplicas(logger klog.Logger, namespace string, controller ControllerInfo, desired int32) int32 {
+	if csf.hpaLister == nil {
+		return desired
+	}
+	target, ok := csf.scaleTarget(controller)
+	if !ok {
+		return desired
+	}
+	hpas, err := csf.hpaLister.HorizontalPodAutoscalers(namespace).List(labels.Everything())
+	if err != nil {
+		logger.V(4).Info("Could not list HorizontalPodAutoscalers", "namespace", namespace, "err", err)
+		return desired
+	}
+	for _, hpa := range hpas {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind != target.Kind || ref.Name != controller.Name {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil || gv.Group != target.Group {
+			continue
+		}
+		if hpa.Status.DesiredReplicas > desired {
+			logger.V(4).Info("Using desired replicas of HorizontalPodAutoscaler", "hpa", klog.KObj(hpa),
+				"controller", controller.Name, "replicas", desired, "hpaDesiredReplicas", hpa.Status.DesiredReplicas)
+			desired = hpa.Status.DesiredReplicas
+		}
+	}
+	return desired
+}
+
+// scaleTarget returns the API group and kind of the controller if an HPA can target it.
+func (csf *ControllerSpreadFilter) scaleTarget(controller ControllerInfo) (schema.GroupKind, bool) {
+	if scalableControllerTypes[controller.Type] {
+		return schema.GroupKind{Group: builtinControllerGroups[controller.Type], Kind: string(controller.Type)}, true
+	}
+	if cc, ok := csf.customControllers[string(controller.Type)]; ok {
+		gv, err := schema.ParseGroupVersion(cc.config.APIVersion)
+		if err != nil {
+			return schema.GroupKind{}, false
+		}
+		return schema.GroupKind{Group: gv.Group, Kind: cc.config.Kind}, true
+	}
+	return schema.GroupKind{}, false
+}
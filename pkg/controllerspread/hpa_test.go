@@ -0,0 +1,121 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	autoscalinglisters "k8s.io/client-go/listers/autoscaling/v2"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// makeHPA returns a HorizontalPodAutoscaler in the test namespace scaling the target to the
+// desired replicas.
+func makeHPA(apiVersion, kind, name string, desired int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: apiVersion, Kind: kind, Name: name},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{DesiredReplicas: desired},
+	}
+}
+
+func TestHPADesiredReplicas(t *testing.T) {
+	web := ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))}
+	tests := []struct {
+		name       string
+		hpaAware   bool
+		hpa        *autoscalingv2.HorizontalPodAutoscaler
+		controller ControllerInfo
+		want       int32
+	}{
+		{
+			name:       "HPA scaling up",
+			hpaAware:   true,
+			hpa:        makeHPA("apps/v1", "Deployment", "web", 6),
+			controller: web,
+			want:       6,
+		},
+		{
+			name:       "HPA scaling down",
+			hpaAware:   true,
+			hpa:        makeHPA("apps/v1", "Deployment", "web", 2),
+			controller: web,
+			want:       3,
+		},
+		{
+			name:       "HPA awareness disabled",
+			hpa:        makeHPA("apps/v1", "Deployment", "web", 6),
+			controller: web,
+			want:       3,
+		},
+		{
+			name:       "HPA of another controller",
+			hpaAware:   true,
+			hpa:        makeHPA("apps/v1", "StatefulSet", "web", 6),
+			controller: web,
+			want:       3,
+		},
+		{
+			name:       "HPA of a kind of another API group",
+			hpaAware:   true,
+			hpa:        makeHPA("example.com/v1", "Deployment", "web", 6),
+			controller: web,
+			want:       3,
+		},
+		{
+			name:       "controller an HPA cannot target",
+			hpaAware:   true,
+			hpa:        makeHPA("batch/v1", "Job", "batch", 6),
+			controller: ControllerInfo{Type: JobType, Name: "batch", UID: string(testUID("batch"))},
+			want:       3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := &ControllerSpreadFilter{}
+			if tt.hpaAware {
+				indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+				_ = indexer.Add(tt.hpa)
+				csf.hpaLister = autoscalinglisters.NewHorizontalPodAutoscalerLister(indexer)
+			}
+			if got := csf.hpaDesiredReplicas(klog.Background(), testNamespace, tt.controller, 3); got != tt.want {
+				t.Errorf("hpaDesiredReplicas() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterHPAAware(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "replicas of the Deployment",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "desired replicas of the HPA",
+			args: ControllerSpreadArgs{HPAAware: true},
+			want: []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 1, map[string]string{minHostsAnnotationKey: "3"}), "node-a")
+			objs = append(objs, makeHPA("apps/v1", "Deployment", "web", 3))
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// pkg/controllerspread/image_scope.go
+//
+// Per-image spreading for blue/green releases. With the "controller-spread-scheduler/group-by-image"
+// annotation on the controller, the peers of a pod are only the pods of the controller that run
+// the same image in their primary container, so each image version is spread on its own. The
+// primary container is the first one, or the container named by the
+// "controller-spread-scheduler/group-by-image-container" annotation.
+package controllerspread
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key on the controller grouping its pods by the image of their primary container.
+	groupByImageAnnotationKey = "controller-spread-scheduler/group-by-image"
+
+	// Annotation key on the controller naming the primary container for group-by-image.
+	groupByImageContainerAnnotationKey = "controller-spread-scheduler/group-by-image-container"
+)
+
+// imageGroup is the primary container and its image that peers must share. The zero value means
+// the pods are not grouped by image.
+type imageGroup struct {
+	container string
+	image     string
+}
+
+// imageGroupOf returns the image group of the pod if its controller groups pods by image.
+func imageGroupOf(logger klog.Logger, pod *v1.Pod, annotations map[string]string, controller ControllerInfo) (imageGroup, bool) {
+	val, exists := annotations[groupByImageAnnotationKey]
+	if !exists {
+		return imageGroup{}, false
+	}
+	groupByImage, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", groupByImageAnnotationKey, "value", val, "controller", controller.Name)
+		return imageGroup{}, false
+	}
+	if !groupByImage {
+		return imageGroup{}, false
+	}
+	container := annotations[groupByImageContainerAnnotationKey]
+	image, ok := primaryImage(pod, container)
+	if !ok {
+		logger.V(2).Info("Pod has no container to group by image", "pod", klog.KObj(pod), "container", container, "controller", controller.Name)
+		return imageGroup{}, false
+	}
+	return imageGroup{container: container, image: image}, true
+}
+
+// primaryImage returns the image of the named container of the pod, or of its first container
+// if the name is empty.
+func primaryImage(pod *v1.Pod, container string) (string, bool) {
+	for _, c := range pod.Spec.Containers {
+		if container == "" || c.Name == container {
+			return c.Image, true
+		}
+	}
+	return "", false
+}
+
+// withImage returns the pods whose primary container runs the image of the group.
+func withImage(pods []*v1.Pod, group imageGroup) []*v1.Pod {
+	var result []*v1.Pod
+	for _, p := range pods {
+		if image, ok := primaryImage(p, group.container); ok && image == group.image {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// withContainers sets the containers of the pod to one per image, named after the image, and
+// returns the pod.
+func withContainers(pod *v1.Pod, images ...string) *v1.Pod {
+	for _, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: image, Image: image})
+	}
+	return pod
+}
+
+func TestImageGroupOf(t *testing.T) {
+	controller := ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        imageGroup
+		wantOK      bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:        "first container",
+			annotations: map[string]string{groupByImageAnnotationKey: "true"},
+			want:        imageGroup{image: "web:v2"},
+			wantOK:      true,
+		},
+		{
+			name:        "named container",
+			annotations: map[string]string{groupByImageAnnotationKey: "true", groupByImageContainerAnnotationKey: "proxy:v1"},
+			want:        imageGroup{container: "proxy:v1", image: "proxy:v1"},
+			wantOK:      true,
+		},
+		{
+			name:        "missing container",
+			annotations: map[string]string{groupByImageAnnotationKey: "true", groupByImageContainerAnnotationKey: "sidecar"},
+		},
+		{
+			name:        "disabled",
+			annotations: map[string]string{groupByImageAnnotationKey: "false"},
+		},
+		{
+			name:        "invalid value",
+			annotations: map[string]string{groupByImageAnnotationKey: "by image"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := withContainers(makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash")), "web:v2", "proxy:v1")
+			got, ok := imageGroupOf(klog.Background(), pod, tt.annotations, controller)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("imageGroupOf() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFilterGroupByImage(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "images spread together",
+			annotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:        []string{"node-c"},
+		},
+		{
+			name:        "images spread on their own",
+			annotations: map[string]string{minHostsAnnotationKey: "3", groupByImageAnnotationKey: "true"},
+			want:        []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := ownerRef(ReplicaSetType, "web-hash")
+			objs := makeDeploymentPods(makeDeployment("web", 3, tt.annotations))
+			objs = append(objs,
+				withContainers(makePod("web-0", "node-a", owner), "web:v2"),
+				withContainers(makePod("web-1", "node-b", owner), "web:v1"))
+			pod := withContainers(makePod("web-new", "", owner), "web:v2")
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// pkg/controllerspread/indexed_job.go
+//
+// Indexed Job support for ControllerSpreadFilter. Pods of an Indexed Job with different
+// completion indices may share a node, but pods with the same completion index (e.g. a retry
+// racing its predecessor) are kept on distinct nodes.
+package controllerspread
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// completionIndexOf returns the completion index of the pod if it belongs to an Indexed Job.
+func (csf *ControllerSpreadFilter) completionIndexOf(pod *v1.Pod, controller ControllerInfo) (string, bool) {
+	if controller.Type != JobType {
+		return "", false
+	}
+	index, ok := pod.Annotations[batchv1.JobCompletionIndexAnnotation]
+	if !ok {
+		return "", false
+	}
+	job, err := csf.jobLister.Jobs(pod.Namespace).Get(controller.Name)
+	if err != nil || job.Spec.CompletionMode == nil || *job.Spec.CompletionMode != batchv1.IndexedCompletion {
+		return "", false
+	}
+	return index, true
+}
+
+// withCompletionIndex returns the pods with the given completion index.
+func withCompletionIndex(pods []*v1.Pod, index string) []*v1.Pod {
+	var result []*v1.Pod
+	for _, p := range pods {
+		if p.Annotations[batchv1.JobCompletionIndexAnnotation] == index {
+			result = append(result, p)
+		}
+	}
+	return result
+}
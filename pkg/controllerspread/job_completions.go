@@ -0,0 +1,29 @@
+// pkg/controllerspread/job_completions.go
+//
+// Desired count of Jobs. A Job's desired count is its parallelism, so a Job that runs its
+// completions one at a time is not spread. With JobCompletionsWindow set in the plugin args, the
+// desired count is max(parallelism, min(completions, JobCompletionsWindow)): up to that many
+// completions are spread as well, even though they run over time rather than at once.
+package controllerspread
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// jobDesiredReplicas returns the desired count of a Job spec: its parallelism, defaulting to 1
+// when unset, raised to the completions within the window when JobCompletionsWindow is set.
+func (csf *ControllerSpreadFilter) jobDesiredReplicas(spec batchv1.JobSpec) int32 {
+	desired := int32(1)
+	if spec.Parallelism != nil {
+		desired = *spec.Parallelism
+	}
+	window := csf.args.JobCompletionsWindow
+	if window == 0 || spec.Completions == nil {
+		return desired
+	}
+	completions := min(*spec.Completions, window)
+	if completions > desired {
+		return completions
+	}
+	return desired
+}
@@ -0,0 +1,92 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestJobDesiredReplicas(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int32
+		spec   batchv1.JobSpec
+		want   int32
+	}{
+		{
+			name: "parallelism unset",
+			want: 1,
+		},
+		{
+			name: "completions without a window",
+			spec: batchv1.JobSpec{Parallelism: ptr.To[int32](2), Completions: ptr.To[int32](10)},
+			want: 2,
+		},
+		{
+			name:   "completions within the window",
+			window: 5,
+			spec:   batchv1.JobSpec{Parallelism: ptr.To[int32](2), Completions: ptr.To[int32](4)},
+			want:   4,
+		},
+		{
+			name:   "completions capped at the window",
+			window: 5,
+			spec:   batchv1.JobSpec{Parallelism: ptr.To[int32](2), Completions: ptr.To[int32](10)},
+			want:   5,
+		},
+		{
+			name:   "parallelism above the window",
+			window: 5,
+			spec:   batchv1.JobSpec{Parallelism: ptr.To[int32](8), Completions: ptr.To[int32](10)},
+			want:   8,
+		},
+		{
+			name:   "completions unset",
+			window: 5,
+			spec:   batchv1.JobSpec{Parallelism: ptr.To[int32](2)},
+			want:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := &ControllerSpreadFilter{args: &ControllerSpreadArgs{JobCompletionsWindow: tt.window}}
+			if got := csf.jobDesiredReplicas(tt.spec); got != tt.want {
+				t.Errorf("jobDesiredReplicas() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterJobCompletions(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "completions not spread",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "completions spread",
+			args: ControllerSpreadArgs{JobCompletionsWindow: 3},
+			want: []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := makeJob("batch", 1, "", map[string]string{minHostsAnnotationKey: "3"})
+			job.Spec.Completions = ptr.To[int32](3)
+			peer := makePod("batch-0", "node-a", ownerRef(JobType, "batch"))
+			pod := makePod("batch-1", "", ownerRef(JobType, "batch"))
+			p := newTestPlugin(t, &tt.args, nodes, job, peer, pod)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+// pkg/controllerspread/job_suspend.go
+//
+// Support for suspended Jobs. The Job controller deletes the active pods of a suspended Job and
+// creates no new ones, so pods of a suspended Job are neither spread nor counted as peers.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// isSuspendedJob reports whether the named Job exists and is suspended.
+func (csf *ControllerSpreadFilter) isSuspendedJob(namespace, name string) bool {
+	job, err := csf.jobLister.Jobs(namespace).Get(name)
+	if err != nil {
+		return false
+	}
+	return job.Spec.Suspend != nil && *job.Spec.Suspend
+}
+
+// ownedBySuspendedJob reports whether the pod is owned by a suspended Job.
+func (csf *ControllerSpreadFilter) ownedBySuspendedJob(pod *v1.Pod) bool {
+	for _, ownerRef := range pod.OwnerReferences {
+		if isBuiltinOwner(ownerRef, JobType) && csf.isSuspendedJob(pod.Namespace, ownerRef.Name) {
+			return true
+		}
+	}
+	return false
+}
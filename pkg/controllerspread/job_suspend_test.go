@@ -0,0 +1,97 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+// makeJob returns a Job in the test namespace of the parallelism, owned by the CronJob of the
+// name if cronJob is not empty.
+func makeJob(name string, parallelism int32, cronJob string, annotations map[string]string) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name), Annotations: annotations},
+		Spec:       batchv1.JobSpec{Parallelism: ptr.To(parallelism)},
+	}
+	if cronJob != "" {
+		job.OwnerReferences = []metav1.OwnerReference{ownerRef(CronJobType, cronJob)}
+	}
+	return job
+}
+
+// makeCronJob returns a CronJob in the test namespace whose Jobs have the parallelism.
+func makeCronJob(name string, parallelism int32, annotations map[string]string) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name), Annotations: annotations},
+		Spec: batchv1.CronJobSpec{
+			Schedule:    "@hourly",
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Parallelism: ptr.To(parallelism)}},
+		},
+	}
+}
+
+func TestSuspendedJobs(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		objs func() []runtime.Object
+		pod  string
+		// podJob is the Job owning the pod being scheduled.
+		podJob string
+		want   []string
+	}{
+		{
+			name: "active Job",
+			objs: func() []runtime.Object {
+				return []runtime.Object{
+					makeJob("batch", 3, "", map[string]string{minHostsAnnotationKey: "3"}),
+					makePod("batch-0", "node-a", ownerRef(JobType, "batch")),
+				}
+			},
+			podJob: "batch",
+			want:   []string{"node-b", "node-c"},
+		},
+		{
+			name: "suspended Job",
+			objs: func() []runtime.Object {
+				job := makeJob("batch", 3, "", map[string]string{minHostsAnnotationKey: "3"})
+				job.Spec.Suspend = ptr.To(true)
+				return []runtime.Object{job, makePod("batch-0", "node-a", ownerRef(JobType, "batch"))}
+			},
+			podJob: "batch",
+			want:   []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "suspended Job of the same CronJob",
+			args: ControllerSpreadArgs{GroupJobsByCronJob: true},
+			objs: func() []runtime.Object {
+				suspended := makeJob("nightly-1", 3, "nightly", nil)
+				suspended.Spec.Suspend = ptr.To(true)
+				return []runtime.Object{
+					makeCronJob("nightly", 3, map[string]string{minHostsAnnotationKey: "3"}),
+					suspended,
+					makePod("nightly-1-a", "node-a", ownerRef(JobType, "nightly-1")),
+					makeJob("nightly-2", 3, "nightly", nil),
+					makePod("nightly-2-a", "node-b", ownerRef(JobType, "nightly-2")),
+				}
+			},
+			podJob: "nightly-2",
+			want:   []string{"node-a", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("new", "", ownerRef(JobType, tt.podJob))
+			p := newTestPlugin(t, &tt.args, nodes, append(tt.objs(), pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
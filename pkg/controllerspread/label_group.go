@@ -0,0 +1,70 @@
+// pkg/controllerspread/label_group.go
+//
+// Label-based grouping for ControllerSpreadFilter. Pods without a common controller (e.g. bare
+// pods templated by Helm) can opt into spreading with the "controller-spread-scheduler/group-label"
+// annotation, which names a pod label whose value identifies the group of peers.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key on the pod naming the label whose value groups the pod with its peers.
+	groupLabelAnnotationKey = "controller-spread-scheduler/group-label"
+
+	// Annotation key on the pod for the desired size of its label group.
+	groupSizeAnnotationKey = "controller-spread-scheduler/group-size"
+)
+
+// resolveGroup returns the group of peers of the pod: its label group when the pod carries the
+// group-label annotation and the named label, or its top-level controller otherwise.
+func (csf *ControllerSpreadFilter) resolveGroup(logger klog.Logger, pod *v1.Pod) (ControllerInfo, bool) {
+	if key := pod.Annotations[groupLabelAnnotationKey]; key != "" {
+		if value, ok := pod.Labels[key]; ok {
+			return ControllerInfo{Type: LabelGroupType, UID: labelGroupUID(pod.Namespace, key, value), Name: value, GroupLabel: key}, true
+		}
+	}
+	return csf.resolveTopOwner(logger, pod)
+}
+
+// labelGroupUID returns the identifier of the label group in the namespace, which stands in for
+// the controller UID. It is qualified by the namespace like controllerGroupKey, as label groups
+// of the same label value in different namespaces are distinct.
+func labelGroupUID(namespace, key, value string) string {
+	return namespace + "/" + key + "=" + value
+}
+
+// getGroupSpec returns the desired count and the annotations of the pod's group. For a label
+// group, the desired count is the group-size annotation, or else the number of pods carrying the
+// label, and the annotations are those of the pod.
+func (csf *ControllerSpreadFilter) getGroupSpec(ctx context.Context, pod *v1.Pod, controller ControllerInfo) (int32, map[string]string, error) {
+	logger := klog.FromContext(ctx)
+	if controller.Type != LabelGroupType {
+		return csf.getControllerSpec(logger, pod.Namespace, controller)
+	}
+
+	if val, exists := pod.Annotations[groupSizeAnnotationKey]; exists {
+		if parsed, err := strconv.ParseInt(val, 10, 32); err == nil && parsed > 0 {
+			return int32(parsed), pod.Annotations, nil
+		}
+		return 0, nil, fmt.Errorf("%w: invalid %s annotation %q", errInvalidSpec, groupSizeAnnotationKey, val)
+	}
+
+	peers, err := csf.listControllerPods(ctx, pod.Namespace, controller)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int32(len(withoutPod(peers, pod)) + 1), pod.Annotations, nil
+}
+
+// labelGroupSelector selects the pods of a label group.
+func labelGroupSelector(controller ControllerInfo) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{controller.GroupLabel: controller.Name})
+}
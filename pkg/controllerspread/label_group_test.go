@@ -0,0 +1,120 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// makeLabelGroupPod returns a bare pod of the label group app=value in the namespace, Running on
+// the node or Pending if nodeName is empty.
+func makeLabelGroupPod(namespace, name, value, nodeName string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: testUID(namespace + "-" + name),
+			Labels:      map[string]string{"app": value},
+			Annotations: map[string]string{groupLabelAnnotationKey: "app", groupSizeAnnotationKey: "2"}},
+		Spec:   v1.PodSpec{NodeName: nodeName},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if nodeName == "" {
+		pod.Status.Phase = v1.PodPending
+	}
+	return pod
+}
+
+func TestResolveGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want ControllerInfo
+	}{
+		{
+			name: "label group",
+			pod:  makeLabelGroupPod("team-a", "web-1", "web", ""),
+			want: ControllerInfo{Type: LabelGroupType, UID: "team-a/app=web", Name: "web", GroupLabel: "app"},
+		},
+		{
+			name: "same label value in another namespace",
+			pod:  makeLabelGroupPod("team-b", "web-1", "web", ""),
+			want: ControllerInfo{Type: LabelGroupType, UID: "team-b/app=web", Name: "web", GroupLabel: "app"},
+		},
+		{
+			name: "owner reference without the group label",
+			pod: func() *v1.Pod {
+				pod := makePod("web-1", "", ownerRef(StatefulSetType, "web"))
+				pod.Annotations = map[string]string{groupLabelAnnotationKey: "app"}
+				return pod
+			}(),
+			want: ControllerInfo{Type: StatefulSetType, UID: string(testUID("web")), Name: "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nil)
+			got, ok := p.resolveGroup(klog.Background(), tt.pod)
+			if !ok {
+				t.Fatalf("resolveGroup() found no group")
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("resolveGroup() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLabelGroupFilter(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	tests := []struct {
+		name    string
+		objs    []runtime.Object
+		reserve *v1.Pod
+		pod     *v1.Pod
+		want    []string
+	}{
+		{
+			name: "peer of the group",
+			objs: []runtime.Object{makeLabelGroupPod("team-a", "web-1", "web", "node-a")},
+			pod:  makeLabelGroupPod("team-a", "web-2", "web", ""),
+			want: []string{"node-b"},
+		},
+		{
+			name: "same label value in another namespace is no peer",
+			objs: []runtime.Object{makeLabelGroupPod("team-b", "web-1", "web", "node-a")},
+			pod:  makeLabelGroupPod("team-a", "web-2", "web", ""),
+			want: []string{"node-a", "node-b"},
+		},
+		{
+			name:    "assumed placement of the group in another namespace is no peer",
+			reserve: makeLabelGroupPod("team-b", "web-1", "web", ""),
+			pod:     makeLabelGroupPod("team-a", "web-2", "web", ""),
+			want:    []string{"node-a", "node-b"},
+		},
+		{
+			name:    "assumed placement of the group",
+			reserve: makeLabelGroupPod("team-a", "web-1", "web", ""),
+			pod:     makeLabelGroupPod("team-a", "web-2", "web", ""),
+			want:    []string{"node-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, tt.objs...)
+			if tt.reserve != nil {
+				state, status := preFilter(t, p, tt.reserve)
+				if !status.IsSuccess() {
+					t.Fatalf("PreFilter: %v", status)
+				}
+				if status := p.Reserve(t.Context(), state, tt.reserve, "node-a"); !status.IsSuccess() {
+					t.Fatalf("Reserve: %v", status)
+				}
+			}
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, tt.pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
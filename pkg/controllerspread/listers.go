@@ -0,0 +1,85 @@
+// pkg/controllerspread/listers.go
+//
+// Injectable listers. The plugin reads pods, controllers and nodes through listers, which New takes
+// from the shared informers of the framework handle. NewWithListers lets callers such as unit
+// tests supply their own, e.g. listers over an indexer filled with fixtures, so that no informer
+// has to be started and synced and the handle needs no SharedInformerFactory:
+//
+//	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+//	indexer.Add(pod)
+//	indexer.Add(deployment)
+//	listers := controllerspread.Listers{
+//		Pods:        corelisters.NewPodLister(indexer),
+//		Deployments: appslisters.NewDeploymentLister(indexer),
+//		...
+//	}
+//	plugin, err := controllerspread.NewWithListers(&controllerspread.ControllerSpreadArgs{}, handle, listers)
+package controllerspread
+
+import (
+	"context"
+
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	autoscalinglisters "k8s.io/client-go/listers/autoscaling/v2"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Listers are the listers the plugin reads pods, controllers and nodes from. Namespaces is only
+// read with a NamespaceSelector and HorizontalPodAutoscalers only with HPAAware; they may be nil
+// otherwise.
+type Listers struct {
+	Pods                   corelisters.PodLister
+	DaemonSets             appslisters.DaemonSetLister
+	Deployments            appslisters.DeploymentLister
+	ReplicaSets            appslisters.ReplicaSetLister
+	StatefulSets           appslisters.StatefulSetLister
+	Jobs                   batchlisters.JobLister
+	CronJobs               batchlisters.CronJobLister
+	PodDisruptionBudgets   policylisters.PodDisruptionBudgetLister
+	ReplicationControllers corelisters.ReplicationControllerLister
+	Nodes                  corelisters.NodeLister
+
+	Namespaces               corelisters.NamespaceLister
+	HorizontalPodAutoscalers autoscalinglisters.HorizontalPodAutoscalerLister
+}
+
+// ListersFromHandle returns the listers of the shared informers of the handle. Namespaces and
+// HorizontalPodAutoscalers are left nil, so that their informers are only started when the args
+// need them.
+func ListersFromHandle(handle framework.Handle) Listers {
+	factory := handle.SharedInformerFactory()
+	return Listers{
+		Pods:                   factory.Core().V1().Pods().Lister(),
+		DaemonSets:             factory.Apps().V1().DaemonSets().Lister(),
+		Deployments:            factory.Apps().V1().Deployments().Lister(),
+		ReplicaSets:            factory.Apps().V1().ReplicaSets().Lister(),
+		StatefulSets:           factory.Apps().V1().StatefulSets().Lister(),
+		Jobs:                   factory.Batch().V1().Jobs().Lister(),
+		CronJobs:               factory.Batch().V1().CronJobs().Lister(),
+		PodDisruptionBudgets:   factory.Policy().V1().PodDisruptionBudgets().Lister(),
+		ReplicationControllers: factory.Core().V1().ReplicationControllers().Lister(),
+		Nodes:                  factory.Core().V1().Nodes().Lister(),
+	}
+}
+
+// NewWithListers returns a ControllerSpreadFilter for the args, which are defaulted in place and
+// validated, that reads from the given listers instead of the shared informers of the handle. The
+// caller is responsible for the listers being up to date: the plugin does not wait for informer
+// caches to sync and lists pods without the pod owner index. The caches fed by informer events are
+// disabled as well: controller specs and node labels are read on every cycle, scale-ups are not
+// tracked for the scale-up grace period, peers lost to node failures are not tracked for
+// enforce-on-reschedule, and EventDrivenCounts falls back to listing. The handle still provides
+// the node snapshot, the event recorder and the clientset, but not its SharedInformerFactory. The
+// background work of the plugin runs for the life of the process; NewWithListersContext stops it.
+func NewWithListers(args *ControllerSpreadArgs, handle framework.Handle, listers Listers) (framework.Plugin, error) {
+	return NewWithListersContext(context.Background(), args, handle, listers)
+}
+
+// NewWithListersContext is NewWithListers with the background work of the plugin stopped when ctx
+// is done.
+func NewWithListersContext(ctx context.Context, args *ControllerSpreadArgs, handle framework.Handle, listers Listers) (framework.Plugin, error) {
+	return newWithListers(ctx, args, handle, &listers)
+}
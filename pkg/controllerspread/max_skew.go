@@ -0,0 +1,49 @@
+// pkg/controllerspread/max_skew.go
+//
+// Maximum skew constraint for ControllerSpreadFilter, modelled on pod topology spread. With the
+// "controller-spread-scheduler/max-skew" annotation on the controller, a node is rejected if
+// placing the pod there would make the difference between the pod counts of its domain and of
+// the least-loaded eligible domain exceed the limit, at any topology level.
+package controllerspread
+
+import (
+	"math"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key for the maximum skew of the controller's pods between topology domains.
+	maxSkewAnnotationKey = "controller-spread-scheduler/max-skew"
+)
+
+// parseMaxSkewAnnotation returns the max-skew annotation value, or 0 (no limit) if it is absent
+// or not a positive integer.
+func parseMaxSkewAnnotation(logger klog.Logger, annotations map[string]string, controller ControllerInfo) int32 {
+	val, exists := annotations[maxSkewAnnotationKey]
+	if !exists {
+		return 0
+	}
+	parsed, ok := parseMaxPodsPerNodeAnnotation(val)
+	if !ok {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", maxSkewAnnotationKey, "value", val, "controller", controller.Name)
+		return 0
+	}
+	return parsed
+}
+
+// skewAfterPlacement returns the skew of the level if the pod were placed in the candidate domain:
+// the pod count of the candidate domain after the placement minus the smallest pod count of the
+// eligible domains.
+func skewAfterPlacement(level topologyLevel, candidateDomain string) int {
+	minCount := math.MaxInt
+	for domain := range level.eligibleDomains {
+		if count := level.domainCounts[domain]; count < minCount {
+			minCount = count
+		}
+	}
+	if minCount == math.MaxInt {
+		minCount = 0
+	}
+	return level.domainCounts[candidateDomain] + 1 - minCount
+}
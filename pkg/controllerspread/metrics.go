@@ -0,0 +1,154 @@
+// pkg/controllerspread/metrics.go
+//
+// Prometheus metrics for ControllerSpreadFilter. The metrics are registered in the
+// component-base legacy registry so that they are served on the scheduler's /metrics endpoint.
+// Every metric carries a plugin label with the plugin name, see ControllerSpreadArgs.PluginName,
+// so that plugin instances of several scheduler profiles can be told apart.
+package controllerspread
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// metricsSubsystem is the subsystem name of all metrics exposed by this plugin.
+	metricsSubsystem = "controllerspread"
+)
+
+// Below are possible values for the result label.
+const (
+	resultSuccess       = "success"
+	resultUnschedulable = "unschedulable"
+	resultError         = "error"
+)
+
+var (
+	filterDecisions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "filter_decisions_total",
+			Help:           "Number of Filter decisions, by result and controller type.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin", "result", "controller_type"})
+
+	filterDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "filter_duration_seconds",
+			Help:           "Duration of Filter calls in seconds.",
+			Buckets:        metrics.ExponentialBuckets(0.00001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	observedRejections = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "observed_rejections_total",
+			Help:           "Number of nodes that would have been rejected in Observe mode, by controller type.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin", "controller_type"})
+
+	controllerPodsScanned = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "controller_pods",
+			Help:           "Number of controller pods found by the most recent pod listing.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	externalPolicyErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "external_policy_errors_total",
+			Help:           "Number of failed calls to the external spread policy endpoint.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	circuitBreakerOpen = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "circuit_breaker_open",
+			Help:           "Whether the rejection circuit breaker is open (1) and the spread constraint is not enforced.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	invalidAnnotations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "invalid_annotation_total",
+			Help:           "Number of invalid controller annotation values replaced by their default, by annotation.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin", "annotation"})
+
+	podIndexFallbacks = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "pod_index_fallbacks_total",
+			Help:           "Number of pod listings that fell back to the full namespace because the pod owner index was stale.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	minHostsClamped = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "minhosts_clamped_total",
+			Help:           "Number of controllers whose min-hosts annotation exceeds their desired count and is lowered to it.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin"})
+
+	consistentReads = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "consistent_reads_total",
+			Help:           "Number of controller pod listings requested from the API server, by result.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"plugin", "result"})
+
+	metricsList = []metrics.Registerable{
+		filterDecisions,
+		filterDuration,
+		observedRejections,
+		controllerPodsScanned,
+		externalPolicyErrors,
+		circuitBreakerOpen,
+		invalidAnnotations,
+		podIndexFallbacks,
+		minHostsClamped,
+		consistentReads,
+	}
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics registers the plugin metrics. It is safe to call multiple times, e.g. when
+// several scheduler profiles instantiate the plugin.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		for _, metric := range metricsList {
+			legacyregistry.MustRegister(metric)
+		}
+	})
+}
+
+// observeFilter records the decision and the duration of a Filter call of the named plugin.
+func observeFilter(plugin string, controllerType ControllerType, status *framework.Status, startTime time.Time) {
+	filterDecisions.WithLabelValues(plugin, statusResult(status), string(controllerType)).Inc()
+	filterDuration.WithLabelValues(plugin).Observe(time.Since(startTime).Seconds())
+}
+
+// statusResult maps a framework status to the value of the result label.
+func statusResult(status *framework.Status) string {
+	switch status.Code() {
+	case framework.Success:
+		return resultSuccess
+	case framework.Unschedulable, framework.UnschedulableAndUnresolvable:
+		return resultUnschedulable
+	default:
+		return resultError
+	}
+}
@@ -0,0 +1,39 @@
+// pkg/controllerspread/namespace_scope.go
+//
+// Namespace scoping for ControllerSpreadFilter. When NamespaceSelector is set in the plugin args,
+// only pods in namespaces whose labels match it are subject to spreading.
+package controllerspread
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// namespaceScope decides whether a namespace is subject to spreading.
+type namespaceScope struct {
+	// selector is the compiled NamespaceSelector; nil selects all namespaces.
+	selector labels.Selector
+	lister   corelisters.NamespaceLister
+
+	logLookupFailure sync.Once
+}
+
+// inScope reports whether pods in the namespace are subject to spreading. If the namespace
+// cannot be looked up, it returns the error, which is handled according to the OnError policy,
+// and logs the failure once.
+func (ns *namespaceScope) inScope(logger klog.Logger, namespace string) (bool, error) {
+	if ns == nil || ns.selector == nil {
+		return true, nil
+	}
+	obj, err := ns.lister.Get(namespace)
+	if err != nil {
+		ns.logLookupFailure.Do(func() {
+			logger.Error(err, "Could not look up namespace for namespaceSelector", "namespace", namespace)
+		})
+		return false, err
+	}
+	return ns.selector.Matches(labels.Set(obj.Labels)), nil
+}
@@ -0,0 +1,60 @@
+// pkg/controllerspread/node_pool.go
+//
+// Per-pool spreading for multi-tenant clusters. With the "controller-spread-scheduler/node-pool-label"
+// annotation on the controller, naming a node label such as "cloud.google.com/gke-nodepool", the
+// spread is computed within the pod's target pool rather than cluster-wide: the pool is the value
+// the pod's nodeSelector requires for that label, and only peers on nodes of the same pool count.
+// Peers in other pools are spread independently by their own pods. Pods that do not select a pool
+// through their nodeSelector are spread cluster-wide.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key naming the node label whose values define node pools.
+	nodePoolLabelAnnotationKey = "controller-spread-scheduler/node-pool-label"
+)
+
+// nodePool is a node label key and the value of the pool's nodes. The zero value means the
+// spread is not scoped to a pool.
+type nodePool struct {
+	key   string
+	value string
+}
+
+// nodePoolOf returns the pool the pod targets if its controller spreads per node pool.
+func nodePoolOf(logger klog.Logger, pod *v1.Pod, annotations map[string]string, controller ControllerInfo) (nodePool, bool) {
+	key, exists := annotations[nodePoolLabelAnnotationKey]
+	if !exists {
+		return nodePool{}, false
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", nodePoolLabelAnnotationKey, "value", key, "controller", controller.Name)
+		return nodePool{}, false
+	}
+	value, ok := pod.Spec.NodeSelector[key]
+	if !ok {
+		logger.V(4).Info("Pod does not select a node pool, spreading cluster-wide", "pod", klog.KObj(pod), "nodePoolLabel", key)
+		return nodePool{}, false
+	}
+	return nodePool{key: key, value: value}, true
+}
+
+// withinNodePool removes the nodes outside the pool from the per-node pod counts. Nodes that
+// cannot be resolved through the node lister are kept.
+func (csf *ControllerSpreadFilter) withinNodePool(nodeCounts map[string]int, pool nodePool) map[string]int {
+	if pool.key == "" {
+		return nodeCounts
+	}
+	for nodeName := range nodeCounts {
+		node, err := csf.nodeLister.Get(nodeName)
+		if err == nil && node.Labels[pool.key] != pool.value {
+			delete(nodeCounts, nodeName)
+		}
+	}
+	return nodeCounts
+}
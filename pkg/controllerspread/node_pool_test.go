@@ -0,0 +1,91 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+func TestNodePoolOf(t *testing.T) {
+	controller := ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))}
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		nodeSelector map[string]string
+		want         nodePool
+		wantOK       bool
+	}{
+		{
+			name:         "no annotation",
+			nodeSelector: map[string]string{"pool": "a"},
+		},
+		{
+			name:         "pool selected",
+			annotations:  map[string]string{nodePoolLabelAnnotationKey: "pool"},
+			nodeSelector: map[string]string{"pool": "a"},
+			want:         nodePool{key: "pool", value: "a"},
+			wantOK:       true,
+		},
+		{
+			name:         "no pool selected",
+			annotations:  map[string]string{nodePoolLabelAnnotationKey: "pool"},
+			nodeSelector: map[string]string{"disk": "ssd"},
+		},
+		{
+			name:         "invalid label key",
+			annotations:  map[string]string{nodePoolLabelAnnotationKey: "node pool"},
+			nodeSelector: map[string]string{"node pool": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Spec.NodeSelector = tt.nodeSelector
+			got, ok := nodePoolOf(klog.Background(), pod, tt.annotations, controller)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("nodePoolOf() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFilterNodePool(t *testing.T) {
+	nodes := []*v1.Node{
+		makeNode("node-a1", map[string]string{"pool": "a"}),
+		makeNode("node-a2", map[string]string{"pool": "a"}),
+		makeNode("node-a3", map[string]string{"pool": "a"}),
+		makeNode("node-b1", map[string]string{"pool": "b"}),
+		makeNode("node-b2", map[string]string{"pool": "b"}),
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		// want includes the nodes of pool b, which NodeAffinity rejects for the pod's nodeSelector.
+		want []string
+	}{
+		{
+			name:        "cluster-wide spread",
+			annotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:        []string{"node-a1", "node-a2", "node-a3", "node-b1", "node-b2"},
+		},
+		{
+			name:        "spread within the pool",
+			annotations: map[string]string{minHostsAnnotationKey: "3", nodePoolLabelAnnotationKey: "pool"},
+			want:        []string{"node-a2", "node-a3", "node-b1", "node-b2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 6, tt.annotations), "node-a1", "node-b1", "node-b2")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Spec.NodeSelector = map[string]string{"pool": "a"}
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// pkg/controllerspread/node_topology.go
+//
+// Node label resolution for topology domains. Node labels and taints are read through an
+// informer-backed node lister, and resolved values are cached per node and topology key until
+// the informer reports an update or deletion of the node.
+package controllerspread
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// nodeLabelValue is a cached node label lookup.
+type nodeLabelValue struct {
+	value  string
+	exists bool
+}
+
+// nodeTopologyCache caches node label values keyed by node name and label key.
+type nodeTopologyCache struct {
+	mu     sync.RWMutex
+	values map[string]map[string]nodeLabelValue
+}
+
+// newNodeTopologyCache returns a cache that is invalidated by the node informer of the handle.
+func newNodeTopologyCache(handle framework.Handle) *nodeTopologyCache {
+	c := &nodeTopologyCache{values: make(map[string]map[string]nodeLabelValue)}
+	_, err := handle.SharedInformerFactory().Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) { c.invalidate(newObj) },
+		DeleteFunc: c.invalidate,
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to add node topology cache event handler")
+	}
+	return c
+}
+
+// get returns the cached label value of the node. A nil cache caches nothing.
+func (c *nodeTopologyCache) get(nodeName, key string) (nodeLabelValue, bool) {
+	if c == nil {
+		return nodeLabelValue{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[nodeName][key]
+	return v, ok
+}
+
+// set caches the label value of the node.
+func (c *nodeTopologyCache) set(nodeName, key string, v nodeLabelValue) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values[nodeName] == nil {
+		c.values[nodeName] = make(map[string]nodeLabelValue)
+	}
+	c.values[nodeName][key] = v
+}
+
+// invalidate forgets the cached label values of the node from an informer event.
+func (c *nodeTopologyCache) invalidate(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, node.Name)
+}
+
+// nodeTopologyValue returns the domain of the named node for the topology key, see
+// topologyValue, and whether the node exists and carries the label.
+func (csf *ControllerSpreadFilter) nodeTopologyValue(nodeName, key string) (string, bool) {
+	if v, ok := csf.nodeTopology.get(nodeName, key); ok {
+		return v.value, v.exists
+	}
+	var v nodeLabelValue
+	node, err := csf.nodeLister.Get(nodeName)
+	if err != nil {
+		// Do not cache misses for unknown nodes; the informer may not have seen them yet.
+		return "", false
+	}
+	v.value, v.exists = topologyValue(node, key)
+	csf.nodeTopology.set(nodeName, key, v)
+	return v.value, v.exists
+}
@@ -0,0 +1,132 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// newTopologyTestPlugin returns a plugin resolving node labels through a lister over the
+// indexer and a node topology cache.
+func newTopologyTestPlugin(indexer cache.Indexer) *ControllerSpreadFilter {
+	return &ControllerSpreadFilter{
+		nodeLister:   corelisters.NewNodeLister(indexer),
+		nodeTopology: &nodeTopologyCache{values: make(map[string]map[string]nodeLabelValue)},
+	}
+}
+
+func TestNodeTopologyValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodeName  string
+		wantValue string
+		wantOK    bool
+	}{
+		{
+			name:      "labeled node",
+			nodeName:  "node-a",
+			wantValue: "zone-a",
+			wantOK:    true,
+		},
+		{
+			name:     "node without the label",
+			nodeName: "node-b",
+		},
+		{
+			name:     "unknown node",
+			nodeName: "node-c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			_ = indexer.Add(makeNode("node-a", map[string]string{v1.LabelTopologyZone: "zone-a"}))
+			_ = indexer.Add(makeNode("node-b", nil))
+			csf := newTopologyTestPlugin(indexer)
+
+			value, ok := csf.nodeTopologyValue(tt.nodeName, v1.LabelTopologyZone)
+			if value != tt.wantValue || ok != tt.wantOK {
+				t.Errorf("nodeTopologyValue(%s) = %q, %v, want %q, %v", tt.nodeName, value, ok, tt.wantValue, tt.wantOK)
+			}
+			if _, cached := csf.nodeTopology.get(tt.nodeName, v1.LabelTopologyZone); cached != (tt.nodeName != "node-c") {
+				t.Errorf("value of %s cached = %v, want it cached for known nodes only", tt.nodeName, cached)
+			}
+		})
+	}
+}
+
+func TestNodeTopologyCacheInvalidate(t *testing.T) {
+	tests := []struct {
+		name string
+		// event is the informer event object of the relabeled node, or nil for none.
+		event interface{}
+		want  string
+	}{
+		{
+			name: "no event",
+			want: "zone-a",
+		},
+		{
+			name:  "node updated",
+			event: makeNode("node-a", map[string]string{v1.LabelTopologyZone: "zone-b"}),
+			want:  "zone-b",
+		},
+		{
+			name:  "node deleted with a stale state",
+			event: cache.DeletedFinalStateUnknown{Key: "node-a", Obj: makeNode("node-a", nil)},
+			want:  "zone-b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			_ = indexer.Add(makeNode("node-a", map[string]string{v1.LabelTopologyZone: "zone-a"}))
+			csf := newTopologyTestPlugin(indexer)
+			csf.nodeTopologyValue("node-a", v1.LabelTopologyZone)
+
+			_ = indexer.Update(makeNode("node-a", map[string]string{v1.LabelTopologyZone: "zone-b"}))
+			if tt.event != nil {
+				csf.nodeTopology.invalidate(tt.event)
+			}
+			if got, _ := csf.nodeTopologyValue("node-a", v1.LabelTopologyZone); got != tt.want {
+				t.Errorf("nodeTopologyValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountPodsPerDomain(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	_ = indexer.Add(makeNode("node-a1", map[string]string{v1.LabelTopologyZone: "zone-a"}))
+	_ = indexer.Add(makeNode("node-a2", map[string]string{v1.LabelTopologyZone: "zone-a"}))
+	_ = indexer.Add(makeNode("node-b1", map[string]string{v1.LabelTopologyZone: "zone-b"}))
+	_ = indexer.Add(makeNode("node-c1", nil))
+	tests := []struct {
+		name       string
+		nodeCounts map[string]int
+		want       map[string]int
+	}{
+		{
+			name:       "labeled nodes",
+			nodeCounts: map[string]int{"node-a1": 1, "node-a2": 2, "node-b1": 1},
+			want:       map[string]int{"zone-a": 3, "zone-b": 1},
+		},
+		{
+			name:       "unlabeled and unknown nodes",
+			nodeCounts: map[string]int{"node-a1": 1, "node-c1": 1, "node-gone": 1},
+			want:       map[string]int{"zone-a": 1, "node-c1": 1, "node-gone": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := newTopologyTestPlugin(indexer)
+			if diff := cmp.Diff(tt.want, csf.countPodsPerDomain(klog.Background(), tt.nodeCounts, v1.LabelTopologyZone)); diff != "" {
+				t.Errorf("countPodsPerDomain() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
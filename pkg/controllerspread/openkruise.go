@@ -0,0 +1,59 @@
+// pkg/controllerspread/openkruise.go
+//
+// First-class support for OpenKruise workloads. With OpenKruise set in the plugin args, pods owned
+// by a CloneSet or an Advanced StatefulSet (apps.kruise.io) are spread like those of the built-in
+// controllers, without a customControllers entry. Their spec.replicas is read through the same
+// dynamic informers as custom controllers. The Advanced StatefulSet has the kind "StatefulSet", so
+// it is told apart from the built-in StatefulSet by its API group and reported as the
+// AdvancedStatefulSet controller type.
+package controllerspread
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// openKruiseGroup is the API group of the OpenKruise workloads.
+	openKruiseGroup = "apps.kruise.io"
+)
+
+// openKruiseControllers are the OpenKruise workloads enabled by the OpenKruise arg, keyed by
+// controller type.
+var openKruiseControllers = map[ControllerType]CustomControllerConfig{
+	CloneSetType: {
+		APIVersion:    openKruiseGroup + "/v1alpha1",
+		Kind:          "CloneSet",
+		Resource:      "clonesets",
+		ReplicasField: defaultReplicasField,
+	},
+	AdvancedStatefulSetType: {
+		APIVersion:    openKruiseGroup + "/v1beta1",
+		Kind:          "StatefulSet",
+		Resource:      "statefulsets",
+		ReplicasField: defaultReplicasField,
+	},
+}
+
+// openKruiseOwnerType returns the controller type of an owner reference to an OpenKruise
+// workload. Any served version of the group matches.
+func openKruiseOwnerType(ownerRef metav1.OwnerReference) (ControllerType, bool) {
+	gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil || gv.Group != openKruiseGroup {
+		return "", false
+	}
+	for t, config := range openKruiseControllers {
+		if config.Kind == ownerRef.Kind {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// ownerKind returns the kind that owner references to a controller of the type carry.
+func ownerKind(t ControllerType) string {
+	if config, ok := openKruiseControllers[t]; ok {
+		return config.Kind
+	}
+	return string(t)
+}
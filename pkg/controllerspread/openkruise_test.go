@@ -0,0 +1,160 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// newOpenKruiseControllers returns the OpenKruise custom controllers, as set up with the OpenKruise
+// arg, listing the objects.
+func newOpenKruiseControllers(t *testing.T, objs ...*unstructured.Unstructured) map[string]*customController {
+	t.Helper()
+	customControllers := make(map[string]*customController, len(openKruiseControllers))
+	for controllerType, config := range openKruiseControllers {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			if obj.GetKind() == config.Kind {
+				if err := indexer.Add(obj); err != nil {
+					t.Fatalf("adding %s: %v", obj.GetName(), err)
+				}
+			}
+		}
+		customControllers[string(controllerType)] = &customController{
+			config:        config,
+			replicasField: []string{"spec", "replicas"},
+			lister:        cache.NewGenericLister(indexer, schema.GroupResource{Group: openKruiseGroup, Resource: config.Resource}),
+			hasSynced:     func() bool { return true },
+		}
+	}
+	return customControllers
+}
+
+// makeCloneSet returns a CloneSet of the replicas and annotations in the test namespace.
+func makeCloneSet(name string, replicas int64, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	}}
+	u.SetAPIVersion(openKruiseControllers[CloneSetType].APIVersion)
+	u.SetKind("CloneSet")
+	u.SetNamespace(testNamespace)
+	u.SetName(name)
+	u.SetUID(testUID(name))
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestOpenKruiseOwnerType(t *testing.T) {
+	tests := []struct {
+		name     string
+		ownerRef metav1.OwnerReference
+		want     ControllerType
+		wantOK   bool
+	}{
+		{
+			name:     "CloneSet",
+			ownerRef: metav1.OwnerReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet"},
+			want:     CloneSetType,
+			wantOK:   true,
+		},
+		{
+			name:     "Advanced StatefulSet",
+			ownerRef: metav1.OwnerReference{APIVersion: "apps.kruise.io/v1beta1", Kind: "StatefulSet"},
+			want:     AdvancedStatefulSetType,
+			wantOK:   true,
+		},
+		{
+			name:     "Advanced StatefulSet of another version",
+			ownerRef: metav1.OwnerReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "StatefulSet"},
+			want:     AdvancedStatefulSetType,
+			wantOK:   true,
+		},
+		{
+			name:     "built-in StatefulSet",
+			ownerRef: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		},
+		{
+			name:     "other OpenKruise kind",
+			ownerRef: metav1.OwnerReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "DaemonSet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := openKruiseOwnerType(tt.ownerRef)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("openKruiseOwnerType() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetOwnerInfoOpenKruise(t *testing.T) {
+	cloneSet := metav1.OwnerReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Name: "web", UID: "uid-web"}
+	tests := []struct {
+		name       string
+		openKruise bool
+		want       ControllerInfo
+		wantOK     bool
+	}{
+		{
+			name: "OpenKruise disabled",
+		},
+		{
+			name:       "OpenKruise enabled",
+			openKruise: true,
+			want:       ControllerInfo{Type: CloneSetType, Name: "web", UID: "uid-web"},
+			wantOK:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var customControllers map[string]*customController
+			if tt.openKruise {
+				customControllers = newOpenKruiseControllers(t)
+			}
+			got, ok := getOwnerInfo([]metav1.OwnerReference{cloneSet}, customControllers)
+			if ok != tt.wantOK {
+				t.Errorf("getOwnerInfo() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("getOwnerInfo() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterCloneSet(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	owner := metav1.OwnerReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Name: "web", UID: testUID("web"), Controller: ptr.To(true)}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name: "default min-hosts",
+			want: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:        "min-hosts annotation",
+			annotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:        []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("web-new", "", owner)
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, makePod("web-0", "node-a", owner), makePod("web-1", "node-b", owner), pod)
+			p.customControllers = newOpenKruiseControllers(t, makeCloneSet("web", 3, tt.annotations))
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// pkg/controllerspread/owner_namespace.go
+//
+// Owners in another namespace. Owner references are namespace-local: the controller is looked up
+// in the pod's namespace, and Kubernetes treats a reference to an object in another namespace as
+// unresolvable. Some CRD operators nonetheless set an owner reference to a controller in a
+// different namespace. The lookup then fails with NotFound, which would silently skip the spread
+// as if the controller had been deleted. When the controller is not found, it is looked up by UID
+// across namespaces, so that this misconfiguration is told apart and logged. The spread is still
+// skipped: peers are only counted within the pod's namespace, and a cross-namespace owner does not
+// own the pod as far as the rest of Kubernetes is concerned.
+package controllerspread
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// foreignControllerNamespace returns the namespace of the controller if it exists in a namespace
+// other than the pod's, identified by its UID.
+func (csf *ControllerSpreadFilter) foreignControllerNamespace(namespace string, controller ControllerInfo) (string, bool) {
+	var objects []metav1.Object
+	switch controller.Type {
+	case DaemonSetType:
+		list, err := csf.daemonSetLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case DeploymentType:
+		list, err := csf.deploymentLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case ReplicaSetType:
+		list, err := csf.rsLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case StatefulSetType:
+		list, err := csf.stsLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case JobType:
+		list, err := csf.jobLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case CronJobType:
+		list, err := csf.cronJobLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	case ReplicationControllerType:
+		list, err := csf.rcLister.List(labels.Everything())
+		objects = appendObjects(objects, list, err)
+	default:
+		cc, ok := csf.customControllers[string(controller.Type)]
+		if !ok {
+			return "", false
+		}
+		list, err := cc.lister.List(labels.Everything())
+		if err != nil {
+			return "", false
+		}
+		for _, obj := range list {
+			if o, ok := obj.(metav1.Object); ok {
+				objects = append(objects, o)
+			}
+		}
+	}
+	for _, o := range objects {
+		if string(o.GetUID()) == controller.UID && o.GetNamespace() != namespace {
+			return o.GetNamespace(), true
+		}
+	}
+	return "", false
+}
+
+// appendObjects appends the listed objects, or nothing if the list failed.
+func appendObjects[T metav1.Object](objects []metav1.Object, list []T, err error) []metav1.Object {
+	if err != nil {
+		return objects
+	}
+	for _, o := range list {
+		objects = append(objects, o)
+	}
+	return objects
+}
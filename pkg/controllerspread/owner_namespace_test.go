@@ -0,0 +1,108 @@
+package controllerspread
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestForeignControllerNamespace(t *testing.T) {
+	nodes := makeNodes("node-a")
+	foreign := makeDeployment("web", 3, nil)
+	foreign.Namespace = "operators"
+	tests := []struct {
+		name       string
+		objs       []runtime.Object
+		controller ControllerInfo
+		want       string
+		wantOK     bool
+	}{
+		{
+			name:       "controller in another namespace",
+			objs:       []runtime.Object{foreign},
+			controller: ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))},
+			want:       "operators",
+			wantOK:     true,
+		},
+		{
+			name:       "controller in the pod's namespace",
+			objs:       []runtime.Object{makeDeployment("web", 3, nil)},
+			controller: ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))},
+		},
+		{
+			name:       "controller deleted",
+			controller: ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))},
+		},
+		{
+			name:       "controller of another UID",
+			objs:       []runtime.Object{foreign},
+			controller: ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web-recreated"))},
+		},
+		{
+			name:       "unknown controller type",
+			objs:       []runtime.Object{foreign},
+			controller: ControllerInfo{Type: "Rollout", Name: "web", UID: string(testUID("web"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, tt.objs...)
+			got, ok := p.foreignControllerNamespace(testNamespace, tt.controller)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("foreignControllerNamespace() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPreFilterForeignController(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	foreign := makeDeployment("web", 3, nil)
+	foreign.Namespace = "operators"
+	tests := []struct {
+		name string
+		objs []runtime.Object
+		// wantLogged is whether the controller namespace is logged.
+		wantLogged bool
+	}{
+		{
+			name:       "controller in another namespace",
+			objs:       []runtime.Object{foreign},
+			wantLogged: true,
+		},
+		{
+			name: "controller deleted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var lines []string
+			logger := funcr.New(func(prefix, args string) {
+				mu.Lock()
+				defer mu.Unlock()
+				lines = append(lines, args)
+			}, funcr.Options{Verbosity: 5})
+			ctx := klog.NewContext(t.Context(), logger)
+
+			pod := makePod("web-new", "", ownerRef(DeploymentType, "web"))
+			objs := append(tt.objs, makePod("web-0", "node-a", ownerRef(DeploymentType, "web")), pod)
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, objs...)
+			if _, status := p.PreFilter(ctx, framework.NewCycleState(), pod); status.Code() != framework.Skip {
+				t.Errorf("PreFilter() = %v, want Skip", status)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			logged := slices.ContainsFunc(lines, func(line string) bool { return strings.Contains(line, `"controllerNamespace"="operators"`) })
+			if logged != tt.wantLogged {
+				t.Errorf("controller namespace logged = %v, want %v, log: %v", logged, tt.wantLogged, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// pkg/controllerspread/pdb_spread.go
+//
+// PodDisruptionBudget-aware spread for ControllerSpreadFilter. A PDB with minAvailable promises
+// that voluntary disruptions leave enough pods running, but a node or zone that goes down takes
+// all of its pods at once. With PDBAware set, the required number of domains is raised so that,
+// with the pods spread evenly, losing the pods of any single domain still leaves minAvailable of
+// the desired pods available.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+)
+
+// pdbRequiredHosts returns the number of domains the desired pods must span to keep the
+// minAvailable of every PDB selecting the pod when one domain is lost, capped at desired. It
+// returns 0 when PDBAware is not set or no PDB with minAvailable selects the pod.
+func (csf *ControllerSpreadFilter) pdbRequiredHosts(logger klog.Logger, pod *v1.Pod, desired int32) int32 {
+	if !csf.args.PDBAware || desired <= 1 {
+		return 0
+	}
+	pdbs, err := csf.pdbLister.PodDisruptionBudgets(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		logger.V(4).Info("Could not list PodDisruptionBudgets", "namespace", pod.Namespace, "err", err)
+		return 0
+	}
+	var required int32
+	for _, pdb := range pdbs {
+		if pdb.Spec.MinAvailable == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(desired), true)
+		if err != nil {
+			logger.V(2).Info("Ignoring invalid minAvailable of PodDisruptionBudget", "pdb", klog.KObj(pdb), "err", err)
+			continue
+		}
+		if hosts := hostsForMinAvailable(desired, int32(minAvailable)); hosts > required {
+			logger.V(4).Info("PodDisruptionBudget raises the required spread", "pod", klog.KObj(pod), "pdb", klog.KObj(pdb),
+				"minAvailable", minAvailable, "desired", desired, "requiredHosts", hosts)
+			required = hosts
+		}
+	}
+	return required
+}
+
+// hostsForMinAvailable returns the smallest number of domains across which desired pods, spread
+// evenly, keep minAvailable pods when the pods of one domain are lost: a domain holds at most
+// ceil(desired/hosts) pods, which must not exceed desired-minAvailable. If no pod may be lost,
+// it returns desired, one pod per domain, the most spreading can achieve.
+func hostsForMinAvailable(desired, minAvailable int32) int32 {
+	if minAvailable <= 0 {
+		return 0
+	}
+	tolerable := desired - minAvailable
+	if tolerable <= 0 {
+		return desired
+	}
+	return min(desired, (desired+tolerable-1)/tolerable)
+}
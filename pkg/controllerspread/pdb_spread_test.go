@@ -0,0 +1,121 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// makePDB returns a PodDisruptionBudget of the minAvailable selecting the pods labeled app=web.
+func makePDB(name string, minAvailable intstr.IntOrString) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name)},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: ptr.To(minAvailable),
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+}
+
+func TestHostsForMinAvailable(t *testing.T) {
+	tests := []struct {
+		name         string
+		desired      int32
+		minAvailable int32
+		want         int32
+	}{
+		{
+			name:         "no minAvailable",
+			desired:      4,
+			minAvailable: 0,
+			want:         0,
+		},
+		{
+			name:         "one pod may be lost",
+			desired:      4,
+			minAvailable: 3,
+			want:         4,
+		},
+		{
+			name:         "half the pods may be lost",
+			desired:      6,
+			minAvailable: 3,
+			want:         2,
+		},
+		{
+			name:         "uneven split",
+			desired:      10,
+			minAvailable: 7,
+			want:         4,
+		},
+		{
+			name:         "no pod may be lost",
+			desired:      3,
+			minAvailable: 3,
+			want:         3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostsForMinAvailable(tt.desired, tt.minAvailable); got != tt.want {
+				t.Errorf("hostsForMinAvailable(%d, %d) = %d, want %d", tt.desired, tt.minAvailable, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPDBAware(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c", "node-d")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		pdbs []runtime.Object
+		want []string
+	}{
+		{
+			name: "PDB ignored",
+			pdbs: []runtime.Object{makePDB("web", intstr.FromInt32(3))},
+			want: []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name: "PDB raises the required hosts",
+			args: ControllerSpreadArgs{PDBAware: true},
+			pdbs: []runtime.Object{makePDB("web", intstr.FromInt32(3))},
+			want: []string{"node-d"},
+		},
+		{
+			name: "percentage minAvailable",
+			args: ControllerSpreadArgs{PDBAware: true},
+			pdbs: []runtime.Object{makePDB("web", intstr.FromString("75%"))},
+			want: []string{"node-d"},
+		},
+		{
+			name: "PDB within the min-hosts",
+			args: ControllerSpreadArgs{PDBAware: true},
+			pdbs: []runtime.Object{makePDB("web", intstr.FromInt32(2))},
+			want: []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name: "no PDB",
+			args: ControllerSpreadArgs{PDBAware: true},
+			want: []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 4, nil), "node-a", "node-b", "node-c")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Labels = map[string]string{"app": "web"}
+			p := newTestPlugin(t, &tt.args, nodes, append(append(objs, tt.pdbs...), pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// pkg/controllerspread/peer_counter.go
+//
+// Event-driven peer placements for hot controllers. Listing and filtering the pods of a large
+// controller in every scheduling cycle is the main cost of PreFilter. With EventDrivenCounts set
+// in the plugin args, the placements of active pods are kept in memory, keyed by namespace and
+// owner UID, and updated by pod informer events, so PreFilter reads a controller's per-node
+// counts without listing. Each pod's contribution is recorded, so that updates and deletes undo
+// exactly what was added, and the map is periodically rebuilt from the informer cache in case
+// it drifted. Listing remains the fallback whenever the placements cannot answer, see
+// eventDrivenCounts.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// peerCounterReconcileInterval is how often the placements are rebuilt from the informer cache.
+	peerCounterReconcileInterval = 5 * time.Minute
+)
+
+// peerCounter is the source of the peer placements of a controller.
+type peerCounter interface {
+	// peerPlacements returns the placements of the active pods owned by any of the owner UIDs in
+	// the namespace, keyed by pod UID, and false if they are not available.
+	peerPlacements(namespace string, ownerUIDs []string) (map[types.UID]peerPlacement, bool)
+}
+
+// podRecord is the contribution of a pod to the placements.
+type podRecord struct {
+	ownerKeys []string
+	placement peerPlacement
+}
+
+// informerPeerCounter maintains the placements from pod informer events.
+type informerPeerCounter struct {
+	isActive  func(*v1.Pod) bool
+	lister    func() ([]*v1.Pod, error)
+	hasSynced cache.InformerSynced
+	// stopped is closed once the counter no longer receives events, after the context of the
+	// plugin is done.
+	stopped chan struct{}
+
+	mu sync.RWMutex
+	// pods are the recorded pods by UID.
+	pods map[types.UID]podRecord
+	// byOwner are the placements by ownerUIDIndexKey and pod UID.
+	byOwner map[string]map[types.UID]peerPlacement
+}
+
+var _ peerCounter = &informerPeerCounter{}
+
+// newInformerPeerCounter returns a counter fed by the pod informer of the handle. Only pods for
+// which isActive returns true are recorded. The counter reconciles until ctx is done, and then
+// removes its event handler.
+func newInformerPeerCounter(ctx context.Context, handle framework.Handle, isActive func(*v1.Pod) bool) (*informerPeerCounter, error) {
+	logger := klog.FromContext(ctx)
+	podInformer := handle.SharedInformerFactory().Core().V1().Pods()
+	c := &informerPeerCounter{
+		isActive:  isActive,
+		lister:    func() ([]*v1.Pod, error) { return podInformer.Lister().List(labels.Everything()) },
+		hasSynced: podInformer.Informer().HasSynced,
+		stopped:   make(chan struct{}),
+		pods:      make(map[types.UID]podRecord),
+		byOwner:   make(map[string]map[types.UID]peerPlacement),
+	}
+	registration, err := podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.upsert,
+		UpdateFunc: func(_, newObj interface{}) { c.upsert(newObj) },
+		DeleteFunc: c.delete,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("adding peer counter event handler: %w", err)
+	}
+	go func() {
+		defer close(c.stopped)
+		wait.Until(func() { c.reconcile(logger) }, peerCounterReconcileInterval, ctx.Done())
+		if err := podInformer.Informer().RemoveEventHandler(registration); err != nil {
+			logger.Error(err, "Failed to remove peer counter event handler")
+		}
+	}()
+	return c, nil
+}
+
+// upsert records the pod from an informer add or update event, replacing its previous record.
+func (c *informerPeerCounter) upsert(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgetLocked(pod.UID)
+	c.recordLocked(pod)
+}
+
+// delete forgets the pod from an informer delete event.
+func (c *informerPeerCounter) delete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgetLocked(pod.UID)
+}
+
+// recordLocked adds the pod if it is active and bound or nominated to a node.
+func (c *informerPeerCounter) recordLocked(pod *v1.Pod) {
+	nodeName := placedNodeName(pod)
+	if nodeName == "" || len(pod.OwnerReferences) == 0 || !c.isActive(pod) {
+		return
+	}
+	record := podRecord{placement: peerPlacement{nodeName: nodeName, bound: pod.Spec.NodeName != ""}}
+	for _, ownerRef := range pod.OwnerReferences {
+		key := ownerUIDIndexKey(pod.Namespace, string(ownerRef.UID))
+		if c.byOwner[key] == nil {
+			c.byOwner[key] = make(map[types.UID]peerPlacement)
+		}
+		c.byOwner[key][pod.UID] = record.placement
+		record.ownerKeys = append(record.ownerKeys, key)
+	}
+	c.pods[pod.UID] = record
+}
+
+// forgetLocked removes the recorded contribution of the pod, if any.
+func (c *informerPeerCounter) forgetLocked(uid types.UID) {
+	record, ok := c.pods[uid]
+	if !ok {
+		return
+	}
+	for _, key := range record.ownerKeys {
+		delete(c.byOwner[key], uid)
+		if len(c.byOwner[key]) == 0 {
+			delete(c.byOwner, key)
+		}
+	}
+	delete(c.pods, uid)
+}
+
+// reconcile rebuilds the placements from the informer cache, correcting any drift. The cache is
+// listed under the lock: the store is updated before the events are delivered, so events still
+// pending delivery re-apply the same or newer state.
+func (c *informerPeerCounter) reconcile(logger klog.Logger) {
+	if !c.hasSynced() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pods, err := c.lister()
+	if err != nil {
+		logger.Error(err, "Could not list pods to reconcile peer placements")
+		return
+	}
+	previous := c.pods
+	c.pods = make(map[types.UID]podRecord, len(previous))
+	c.byOwner = make(map[string]map[types.UID]peerPlacement, len(c.byOwner))
+	for _, pod := range pods {
+		c.recordLocked(pod)
+	}
+	drifted := 0
+	for uid, record := range c.pods {
+		if old, ok := previous[uid]; !ok || old.placement != record.placement {
+			drifted++
+		}
+	}
+	for uid := range previous {
+		if _, ok := c.pods[uid]; !ok {
+			drifted++
+		}
+	}
+	if drifted != 0 {
+		logger.V(2).Info("Corrected drifted peer placements", "pods", drifted)
+	}
+}
+
+// peerPlacements implements peerCounter. The placements are unavailable until the pod informer
+// has synced.
+func (c *informerPeerCounter) peerPlacements(namespace string, ownerUIDs []string) (map[types.UID]peerPlacement, bool) {
+	if !c.hasSynced() {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	placements := make(map[types.UID]peerPlacement)
+	for _, uid := range ownerUIDs {
+		for podUID, placement := range c.byOwner[ownerUIDIndexKey(namespace, uid)] {
+			placements[podUID] = placement
+		}
+	}
+	return placements, true
+}
+
+// eventDrivenCounts returns the per-node counts of the pod's peers from the event-driven
+// placements. It returns false, and the caller lists the controller's pods instead, when
+// EventDrivenCounts is not set or the placements cannot answer exactly: for peers narrowed
+// below the whole controller (groupKey differs from the controller UID), for Jobs and CronJobs,
+// whose suspended Jobs are excluded by listing, for label groups, with a SpreadGate, which is
+// read from the listed pods, with a RequireResource, which narrows the peers, and while placements of the controller are still assumed.
+func (csf *ControllerSpreadFilter) eventDrivenCounts(pod *v1.Pod, controller ControllerInfo, groupKey string) (map[string]int, bool) {
+	if csf.counter == nil || groupKey != controller.UID || csf.args.SpreadGate != "" || csf.args.RequireResource != "" {
+		return nil, false
+	}
+	switch controller.Type {
+	case JobType, CronJobType, LabelGroupType:
+		return nil, false
+	}
+	if csf.assumed.hasGroup(groupKey, time.Now()) {
+		return nil, false
+	}
+	ownerUIDs, err := csf.ownerUIDsOf(pod.Namespace, controller)
+	if err != nil {
+		return nil, false
+	}
+	placements, ok := csf.counter.peerPlacements(pod.Namespace, ownerUIDs)
+	if !ok {
+		return nil, false
+	}
+	delete(placements, pod.UID)
+	return countPlacements(placements), true
+}
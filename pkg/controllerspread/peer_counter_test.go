@@ -0,0 +1,71 @@
+package controllerspread
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestInformerPeerCounterStopsWithContext(t *testing.T) {
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	fh := newTestFramework(t, nil, nil, frameworkruntime.WithClientSet(client), frameworkruntime.WithInformerFactory(informerFactory))
+	ctx, cancel := context.WithCancel(t.Context())
+	c, err := newInformerPeerCounter(ctx, fh, func(*v1.Pod) bool { return true })
+	if err != nil {
+		t.Fatalf("newInformerPeerCounter: %v", err)
+	}
+	// delivered receives the pod names of the add events, once the counter would have received them.
+	delivered := make(chan string, 2)
+	if _, err := informerFactory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { delivered <- obj.(*v1.Pod).Name },
+	}); err != nil {
+		t.Fatalf("adding event handler: %v", err)
+	}
+	informerFactory.Start(t.Context().Done())
+	informerFactory.WaitForCacheSync(t.Context().Done())
+
+	owner := string(testUID("web-hash"))
+	placed := func() int {
+		placements, _ := c.peerPlacements(testNamespace, []string{owner})
+		return len(placements)
+	}
+	create := func(name string) {
+		t.Helper()
+		pod := makePod(name, "node-a", ownerRef(ReplicaSetType, "web-hash"))
+		if _, err := client.CoreV1().Pods(testNamespace).Create(t.Context(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+	}
+
+	create("web-0")
+	if err := wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		return placed() == 1, nil
+	}); err != nil {
+		t.Fatalf("placement of web-0 not recorded: %v", err)
+	}
+
+	cancel()
+	select {
+	case <-c.stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("peer counter did not stop after its context was done")
+	}
+	create("web-1")
+	for name := range delivered {
+		if name == "web-1" {
+			break
+		}
+	}
+	if got := placed(); got != 1 {
+		t.Errorf("placements after stop = %d, want 1", got)
+	}
+}
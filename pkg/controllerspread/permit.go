@@ -0,0 +1,195 @@
+// pkg/controllerspread/permit.go
+//
+// Permit and PostBind extension points for ControllerSpreadFilter. Scaling a controller up by
+// many replicas binds all of its pods at once, which can overwhelm the kubelets and image
+// registries involved. With MaxConcurrentBinds set in the plugin args, at most that many pods of
+// a controller are in their binding cycle at a time; further pods wait in Permit until a bind
+// of the same controller completes or fails, or until BindWaitTimeout expires.
+//
+// A freed slot is granted to the longest waiting pod inside the throttle, even if the framework
+// has not registered that pod as waiting yet: the framework registers a pod only after Permit
+// returns Wait, and a bind completing in between must not leave the slot unused until the pod
+// times out. Such a pod is allowed as soon as it is registered.
+package controllerspread
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// defaultBindWaitTimeout is how long a pod waits in Permit by default.
+	defaultBindWaitTimeout = time.Minute
+
+	// allowPollInterval is how often a pod granted a slot before the framework registered it as
+	// waiting is looked up.
+	allowPollInterval = 10 * time.Millisecond
+)
+
+var _ framework.PermitPlugin = &ControllerSpreadFilter{}
+var _ framework.PostBindPlugin = &ControllerSpreadFilter{}
+
+// bindThrottle limits the number of pods of a controller that are binding at the same time.
+// Pods are keyed by controller UID.
+type bindThrottle struct {
+	// ctx is the context of the plugin; it ends the polls of allow when the scheduler stops.
+	ctx     context.Context
+	max     int
+	timeout time.Duration
+
+	mu sync.Mutex
+	// binding are the pods of each controller that passed Permit and have not finished binding.
+	binding map[string]map[types.UID]bool
+	// waiting are the pods of each controller waiting in Permit, in arrival order.
+	waiting map[string][]types.UID
+	// controllers maps the pods in binding or waiting to their controller UID.
+	controllers map[types.UID]string
+}
+
+// newBindThrottle returns a throttle for the args, or nil if MaxConcurrentBinds is not set.
+func newBindThrottle(ctx context.Context, args *ControllerSpreadArgs) *bindThrottle {
+	if args.MaxConcurrentBinds == 0 {
+		return nil
+	}
+	return &bindThrottle{
+		ctx:         ctx,
+		max:         int(args.MaxConcurrentBinds),
+		timeout:     args.BindWaitTimeout.Duration,
+		binding:     make(map[string]map[types.UID]bool),
+		waiting:     make(map[string][]types.UID),
+		controllers: make(map[types.UID]string),
+	}
+}
+
+// admit lets the pod bind if it already holds a slot or fewer than max pods of the controller are
+// binding, and otherwise queues it. It reports whether the pod may bind right away.
+func (t *bindThrottle) admit(controllerUID string, podUID types.UID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.binding[controllerUID][podUID] {
+		return true
+	}
+	t.controllers[podUID] = controllerUID
+	if len(t.binding[controllerUID]) < t.max {
+		if t.binding[controllerUID] == nil {
+			t.binding[controllerUID] = make(map[types.UID]bool)
+		}
+		t.binding[controllerUID][podUID] = true
+		return true
+	}
+	t.waiting[controllerUID] = append(t.waiting[controllerUID], podUID)
+	return false
+}
+
+// release forgets the pod, whether it finished binding, failed or gave up waiting, grants its slot
+// to the longest waiting pods of its controller and allows them to bind.
+func (t *bindThrottle) release(podUID types.UID, handle framework.Handle, pluginName string) {
+	for _, uid := range t.releaseSlot(podUID) {
+		t.allow(uid, handle, pluginName)
+	}
+}
+
+// releaseSlot forgets the pod and grants the freed slots to the longest waiting pods of its
+// controller, which it returns.
+func (t *bindThrottle) releaseSlot(podUID types.UID) []types.UID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	controllerUID, ok := t.controllers[podUID]
+	if !ok {
+		return nil
+	}
+	delete(t.controllers, podUID)
+	delete(t.binding[controllerUID], podUID)
+	queue := t.waiting[controllerUID]
+	for i, uid := range queue {
+		if uid == podUID {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	var granted []types.UID
+	for len(queue) > 0 && len(t.binding[controllerUID]) < t.max {
+		if t.binding[controllerUID] == nil {
+			t.binding[controllerUID] = make(map[types.UID]bool)
+		}
+		t.binding[controllerUID][queue[0]] = true
+		granted = append(granted, queue[0])
+		queue = queue[1:]
+	}
+	if len(queue) == 0 {
+		delete(t.waiting, controllerUID)
+	} else {
+		t.waiting[controllerUID] = queue
+	}
+	if len(t.binding[controllerUID]) == 0 {
+		delete(t.binding, controllerUID)
+	}
+	return granted
+}
+
+// granted reports whether the pod holds a bind slot.
+func (t *bindThrottle) granted(podUID types.UID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	controllerUID, ok := t.controllers[podUID]
+	return ok && t.binding[controllerUID][podUID]
+}
+
+// allow allows the pod granted a slot to bind. If the framework has not registered the pod as
+// waiting yet, it is allowed once registered, unless it gives up waiting and releases its slot
+// first, or the plugin's context is done. The poll is not bound to the context of the caller,
+// whose cycle ends before the pod is registered.
+func (t *bindThrottle) allow(podUID types.UID, handle framework.Handle, pluginName string) {
+	logger := klog.FromContext(t.ctx)
+	allowWaiting := func(context.Context) (bool, error) {
+		if !t.granted(podUID) {
+			return true, nil
+		}
+		waitingPod := handle.GetWaitingPod(podUID)
+		if waitingPod == nil {
+			return false, nil
+		}
+		logger.V(4).Info("Allowing pod waiting for a concurrent bind slot", "pod", klog.KObj(waitingPod.GetPod()))
+		waitingPod.Allow(pluginName)
+		return true, nil
+	}
+	if done, _ := allowWaiting(t.ctx); done {
+		return
+	}
+	go func() {
+		_ = wait.PollUntilContextTimeout(t.ctx, allowPollInterval, t.timeout, false, allowWaiting)
+	}()
+}
+
+// Permit lets the pod bind right away if fewer than MaxConcurrentBinds pods of its controller are
+// binding, and otherwise makes it wait for a slot for at most BindWaitTimeout.
+func (csf *ControllerSpreadFilter) Permit(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	if csf.binds == nil {
+		return nil, 0
+	}
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		// PreFilter skipped the pod, so it is not subject to the throttle.
+		return nil, 0
+	}
+	if csf.binds.admit(s.controller.UID, pod.UID) {
+		return nil, 0
+	}
+	klog.FromContext(ctx).V(4).Info("Waiting for a concurrent bind slot", "pod", klog.KObj(pod), "node", nodeName,
+		"controllerType", s.controller.Type, "controller", s.controller.Name, "maxConcurrentBinds", csf.binds.max)
+	return framework.NewStatus(framework.Wait), csf.binds.timeout
+}
+
+// PostBind frees the bind slot of the pod.
+func (csf *ControllerSpreadFilter) PostBind(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) {
+	if csf.binds != nil {
+		csf.binds.release(pod.UID, csf.handle, csf.Name())
+	}
+}
@@ -0,0 +1,189 @@
+package controllerspread
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fakeWaitingPod is a framework.WaitingPod that records whether it was allowed.
+type fakeWaitingPod struct {
+	pod *v1.Pod
+
+	mu      sync.Mutex
+	allowed bool
+}
+
+func (w *fakeWaitingPod) GetPod() *v1.Pod             { return w.pod }
+func (w *fakeWaitingPod) GetPendingPlugins() []string { return nil }
+func (w *fakeWaitingPod) Reject(string, string)       {}
+
+func (w *fakeWaitingPod) Allow(string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.allowed = true
+}
+
+func (w *fakeWaitingPod) isAllowed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.allowed
+}
+
+// waitingPodsHandle is a framework.Handle whose pods are registered as waiting by the test.
+type waitingPodsHandle struct {
+	framework.Handle
+
+	mu      sync.Mutex
+	waiting map[types.UID]*fakeWaitingPod
+}
+
+func (h *waitingPodsHandle) register(pod *fakeWaitingPod) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiting[pod.pod.UID] = pod
+}
+
+func (h *waitingPodsHandle) GetWaitingPod(uid types.UID) framework.WaitingPod {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pod, ok := h.waiting[uid]; ok {
+		return pod
+	}
+	return nil
+}
+
+func TestBindThrottleAdmit(t *testing.T) {
+	tests := []struct {
+		name string
+		// before are admitted before the release, and after are admitted after it.
+		before  []types.UID
+		release types.UID
+		after   []types.UID
+		want    []bool
+	}{
+		{
+			name:   "below the limit",
+			before: []types.UID{"a", "b"},
+			want:   []bool{true, true},
+		},
+		{
+			name:   "above the limit",
+			before: []types.UID{"a", "b", "c"},
+			want:   []bool{true, true, false},
+		},
+		{
+			name:    "slot freed by a completed bind",
+			before:  []types.UID{"a", "b"},
+			release: "a",
+			after:   []types.UID{"c"},
+			want:    []bool{true, true, true},
+		},
+		{
+			name:    "slot granted to the queued pod",
+			before:  []types.UID{"a", "b", "c"},
+			release: "a",
+			after:   []types.UID{"d"},
+			want:    []bool{true, true, false, false},
+		},
+		{
+			name:    "queued pod admitted again after its slot was granted",
+			before:  []types.UID{"a", "b", "c"},
+			release: "a",
+			after:   []types.UID{"c"},
+			want:    []bool{true, true, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := newBindThrottle(t.Context(), &ControllerSpreadArgs{MaxConcurrentBinds: 2, BindWaitTimeout: metav1.Duration{Duration: time.Second}})
+			handle := &waitingPodsHandle{waiting: make(map[types.UID]*fakeWaitingPod)}
+			var got []bool
+			for _, uid := range tt.before {
+				got = append(got, throttle.admit("web", uid))
+			}
+			if tt.release != "" {
+				throttle.release(tt.release, handle, Name)
+			}
+			for _, uid := range tt.after {
+				got = append(got, throttle.admit("web", uid))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("admit() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBindThrottleRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		// registered registers the queued pod as waiting before the first pod is released.
+		registered bool
+		// gaveUp releases the queued pod, as Unreserve does after a timeout, before it is
+		// registered as waiting.
+		gaveUp bool
+		// stopped cancels the context of the plugin before the first pod is released.
+		stopped     bool
+		wantAllowed bool
+	}{
+		{
+			name:        "pod registered as waiting",
+			registered:  true,
+			wantAllowed: true,
+		},
+		{
+			name:        "pod registered after the slot was freed",
+			wantAllowed: true,
+		},
+		{
+			name:   "pod gave up before it was registered",
+			gaveUp: true,
+		},
+		{
+			name:    "plugin stopped before the pod was registered",
+			stopped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			throttle := newBindThrottle(ctx, &ControllerSpreadArgs{MaxConcurrentBinds: 1, BindWaitTimeout: metav1.Duration{Duration: time.Second}})
+			handle := &waitingPodsHandle{waiting: make(map[types.UID]*fakeWaitingPod)}
+			queued := &fakeWaitingPod{pod: makePod("web-1", "", ownerRef(ReplicaSetType, "web-hash"))}
+			if !throttle.admit("web", "first") {
+				t.Fatalf("admit() of the first pod = false")
+			}
+			if throttle.admit("web", queued.pod.UID) {
+				t.Fatalf("admit() of the queued pod = true")
+			}
+			if tt.registered {
+				handle.register(queued)
+			}
+			if tt.stopped {
+				cancel()
+			}
+			throttle.release("first", handle, Name)
+			if tt.gaveUp {
+				throttle.release(queued.pod.UID, handle, Name)
+			}
+			handle.register(queued)
+
+			time.Sleep(10 * allowPollInterval)
+			if got := queued.isAllowed(); got != tt.wantAllowed {
+				t.Errorf("queued pod allowed = %v, want %v", got, tt.wantAllowed)
+			}
+			if got := throttle.admit("web", "next"); got != tt.gaveUp {
+				t.Errorf("admit() of the next pod = %v, want %v", got, tt.gaveUp)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// pkg/controllerspread/pod_index.go
+//
+// Pod and ReplicaSet informer index keyed on namespace and owner UID. It lets the plugin fetch
+// exactly the pods of a controller, and the ReplicaSets of a Deployment, instead of listing and
+// filtering every object in the namespace.
+package controllerspread
+
+import (
+	"fmt"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// ownerUIDIndex is the name of the pod and ReplicaSet index keyed on namespace and owner UID.
+	ownerUIDIndex = "controllerspread.ownerUID"
+)
+
+// ownerUIDIndexKey returns the index key of an owner UID within a namespace.
+func ownerUIDIndexKey(namespace, uid string) string {
+	return namespace + "/" + uid
+}
+
+// ownerUIDIndexFunc indexes an object under each of its owner references.
+func ownerUIDIndexFunc(obj interface{}) ([]string, error) {
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("expected an object with metadata, got %T: %w", obj, err)
+	}
+	ownerRefs := object.GetOwnerReferences()
+	keys := make([]string, 0, len(ownerRefs))
+	for _, ownerRef := range ownerRefs {
+		keys = append(keys, ownerUIDIndexKey(object.GetNamespace(), string(ownerRef.UID)))
+	}
+	return keys, nil
+}
+
+// addOwnerUIDIndex registers the owner UID index on the shared pod informer and returns the
+// informer. It returns nil if the index cannot be added, e.g. because the informer already started.
+func addOwnerUIDIndex(handle framework.Handle) cache.SharedIndexInformer {
+	return addOwnerUIDIndexTo(handle.SharedInformerFactory().Core().V1().Pods().Informer(), "pod")
+}
+
+// addReplicaSetOwnerUIDIndex registers the owner UID index on the shared ReplicaSet informer and
+// returns the informer, or nil if the index cannot be added.
+func addReplicaSetOwnerUIDIndex(handle framework.Handle) cache.SharedIndexInformer {
+	return addOwnerUIDIndexTo(handle.SharedInformerFactory().Apps().V1().ReplicaSets().Informer(), "ReplicaSet")
+}
+
+// addOwnerUIDIndexTo registers the owner UID index on the informer of the resource.
+func addOwnerUIDIndexTo(informer cache.SharedIndexInformer, resource string) cache.SharedIndexInformer {
+	if _, exists := informer.GetIndexer().GetIndexers()[ownerUIDIndex]; exists {
+		return informer
+	}
+	if err := informer.AddIndexers(cache.Indexers{ownerUIDIndex: ownerUIDIndexFunc}); err != nil {
+		klog.ErrorS(err, "Could not add owner index, falling back to listing", "resource", resource)
+		return nil
+	}
+	return informer
+}
+
+// candidatePods returns the pods that may belong to the controller: the pods owned directly by
+// it and the pods owned by its ReplicaSets, or by its Jobs for a CronJob. Without a synced index
+// all pods in the namespace are returned, and so they are when the index is stale, see
+// staleIndexFallback.
+func (csf *ControllerSpreadFilter) candidatePods(logger klog.Logger, namespace string, controller ControllerInfo) ([]*v1.Pod, error) {
+	if csf.podInformer == nil || !csf.podInformer.HasSynced() {
+		return csf.podLister.Pods(namespace).List(labels.Everything())
+	}
+
+	ownerUIDs, err := csf.ownerUIDsOf(namespace, controller)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var pods []*v1.Pod
+	for _, uid := range ownerUIDs {
+		objs, err := csf.podInformer.GetIndexer().ByIndex(ownerUIDIndex, ownerUIDIndexKey(namespace, uid))
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			pod, ok := obj.(*v1.Pod)
+			if !ok || seen[string(pod.UID)] {
+				continue
+			}
+			seen[string(pod.UID)] = true
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return csf.staleIndexFallback(logger, namespace, controller, ownerUIDs)
+	}
+	return pods, nil
+}
+
+// ownerUIDsOf returns the UIDs of the objects that may directly own pods of the controller: the
+// controller itself, and its ReplicaSets for a Deployment or its Jobs for a CronJob.
+func (csf *ControllerSpreadFilter) ownerUIDsOf(namespace string, controller ControllerInfo) ([]string, error) {
+	ownerUIDs := []string{controller.UID}
+	switch controller.Type {
+	case DeploymentType:
+		childUIDs, err := csf.replicaSetUIDsOf(namespace, controller)
+		if err != nil {
+			return nil, err
+		}
+		ownerUIDs = append(ownerUIDs, childUIDs...)
+	case CronJobType:
+		childJobs, err := csf.jobLister.Jobs(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range childJobs {
+			if isOwnedBy(job.OwnerReferences, controller) {
+				ownerUIDs = append(ownerUIDs, string(job.UID))
+			}
+		}
+	}
+	return ownerUIDs, nil
+}
+
+// replicaSetUIDsOf returns the UIDs of the ReplicaSets of the Deployment. Without a synced index
+// all ReplicaSets in the namespace are listed and filtered.
+func (csf *ControllerSpreadFilter) replicaSetUIDsOf(namespace string, deployment ControllerInfo) ([]string, error) {
+	var uids []string
+	if csf.rsInformer == nil || !csf.rsInformer.HasSynced() {
+		replicaSets, err := csf.rsLister.ReplicaSets(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, rs := range replicaSets {
+			if isOwnedBy(rs.OwnerReferences, deployment) {
+				uids = append(uids, string(rs.UID))
+			}
+		}
+		return uids, nil
+	}
+	objs, err := csf.rsInformer.GetIndexer().ByIndex(ownerUIDIndex, ownerUIDIndexKey(namespace, deployment.UID))
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if rs, ok := obj.(*appsv1.ReplicaSet); ok && isOwnedBy(rs.OwnerReferences, deployment) {
+			uids = append(uids, string(rs.UID))
+		}
+	}
+	return uids, nil
+}
+
+// staleIndexFallback handles an empty index result. The pod being scheduled is itself owned by
+// the controller, so an empty result usually means the index has not caught up with the store,
+// e.g. right after the scheduler started. The namespace is listed once: if the store holds pods
+// of the owners, the index is stale and all pods in the namespace are returned.
+func (csf *ControllerSpreadFilter) staleIndexFallback(logger klog.Logger, namespace string, controller ControllerInfo, ownerUIDs []string) ([]*v1.Pod, error) {
+	allPods, err := csf.podLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range allPods {
+		for _, ownerRef := range p.OwnerReferences {
+			if slices.Contains(ownerUIDs, string(ownerRef.UID)) {
+				logger.V(2).Info("Pod owner index is stale, falling back to listing the namespace", "namespace", namespace,
+					"controllerType", controller.Type, "controller", controller.Name)
+				podIndexFallbacks.WithLabelValues(csf.Name()).Inc()
+				return allPods, nil
+			}
+		}
+	}
+	return nil, nil
+}
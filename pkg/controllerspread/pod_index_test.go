@@ -0,0 +1,170 @@
+package controllerspread
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
+)
+
+// countingReplicaSetLister is a ReplicaSetLister that counts the namespace List calls.
+type countingReplicaSetLister struct {
+	appslisters.ReplicaSetLister
+	lists *int
+}
+
+func (l countingReplicaSetLister) ReplicaSets(namespace string) appslisters.ReplicaSetNamespaceLister {
+	return countingReplicaSetNamespaceLister{ReplicaSetNamespaceLister: l.ReplicaSetLister.ReplicaSets(namespace), lists: l.lists}
+}
+
+type countingReplicaSetNamespaceLister struct {
+	appslisters.ReplicaSetNamespaceLister
+	lists *int
+}
+
+func (l countingReplicaSetNamespaceLister) List(selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+	*l.lists++
+	return l.ReplicaSetNamespaceLister.List(selector)
+}
+
+func TestOwnerUIDsOf(t *testing.T) {
+	nodes := makeNodes("node-a")
+	web := makeDeployment("web", 2, nil)
+	api := makeDeployment("api", 2, nil)
+	nightlyRun := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "nightly-1", Namespace: testNamespace, UID: testUID("nightly-1"),
+		OwnerReferences: []metav1.OwnerReference{ownerRef(CronJobType, "nightly")}}}
+	objs := []runtime.Object{web, makeReplicaSet(web), api, makeReplicaSet(api), nightlyRun}
+	tests := []struct {
+		name       string
+		controller ControllerInfo
+		want       []string
+		// wantIndexedLists and wantListedLists are the ReplicaSet List calls with and without the
+		// ReplicaSet owner index.
+		wantIndexedLists int
+		wantListedLists  int
+	}{
+		{
+			name:            "Deployment",
+			controller:      ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))},
+			want:            []string{string(testUID("web")), string(testUID("web-hash"))},
+			wantListedLists: 1,
+		},
+		{
+			name:       "StatefulSet",
+			controller: ControllerInfo{Type: StatefulSetType, Name: "db", UID: string(testUID("db"))},
+			want:       []string{string(testUID("db"))},
+		},
+		{
+			name:       "CronJob",
+			controller: ControllerInfo{Type: CronJobType, Name: "nightly", UID: string(testUID("nightly"))},
+			want:       []string{string(testUID("nightly")), string(testUID("nightly-1"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexed := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, objs...)
+			fh := newTestFramework(t, nodes, nil)
+			listers := newTestListers(nodes, objs...)
+			p, err := NewWithListersContext(t.Context(), &ControllerSpreadArgs{}, fh, listers)
+			if err != nil {
+				t.Fatalf("NewWithListersContext: %v", err)
+			}
+			listed := p.(*ControllerSpreadFilter)
+
+			for _, c := range []struct {
+				name      string
+				csf       *ControllerSpreadFilter
+				wantLists int
+			}{
+				{name: "indexed", csf: indexed, wantLists: tt.wantIndexedLists},
+				{name: "listed", csf: listed, wantLists: tt.wantListedLists},
+			} {
+				var lists int
+				c.csf.rsLister = countingReplicaSetLister{ReplicaSetLister: c.csf.rsLister, lists: &lists}
+				got, err := c.csf.ownerUIDsOf(testNamespace, tt.controller)
+				if err != nil {
+					t.Fatalf("%s ownerUIDsOf: %v", c.name, err)
+				}
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("%s ownerUIDsOf() (-want,+got):\n%s", c.name, diff)
+				}
+				if lists != c.wantLists {
+					t.Errorf("%s ReplicaSet List calls = %d, want %d", c.name, lists, c.wantLists)
+				}
+			}
+		})
+	}
+}
+
+func TestCandidatePodsStaleIndex(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	deploy := makeDeployment("web", 2, nil)
+	web := makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash"))
+	other := makePod("api-0", "node-b", ownerRef(ReplicaSetType, "api-hash"))
+	tests := []struct {
+		name string
+		// indexed are the pods in the owner index and stored the pods the lister returns, which
+		// the index may not have caught up with.
+		indexed []runtime.Object
+		stored  []runtime.Object
+		want    []string
+		// wantFallbacks is the increase of pod_index_fallbacks_total.
+		wantFallbacks float64
+	}{
+		{
+			name:    "index up to date",
+			indexed: []runtime.Object{web, other},
+			stored:  []runtime.Object{web, other},
+			want:    []string{"web-0"},
+		},
+		{
+			name:          "index stale",
+			stored:        []runtime.Object{web, other},
+			want:          []string{"api-0", "web-0"},
+			wantFallbacks: 1,
+		},
+		{
+			name:   "no pods of the controller",
+			stored: []runtime.Object{other},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append([]runtime.Object{deploy, makeReplicaSet(deploy)}, tt.indexed...)...)
+			p.podLister = newTestListers(nil, tt.stored...).Pods
+			counter := podIndexFallbacks.WithLabelValues(p.Name())
+			before, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading pod_index_fallbacks_total: %v", err)
+			}
+
+			pods, err := p.candidatePods(klog.Background(), testNamespace, ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))})
+			if err != nil {
+				t.Fatalf("candidatePods: %v", err)
+			}
+			var got []string
+			for _, pod := range pods {
+				got = append(got, pod.Name)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("candidatePods() (-want,+got):\n%s", diff)
+			}
+			after, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatalf("reading pod_index_fallbacks_total: %v", err)
+			}
+			if got := after - before; got != tt.wantFallbacks {
+				t.Errorf("pod_index_fallbacks_total increased by %v, want %v", got, tt.wantFallbacks)
+			}
+		})
+	}
+}
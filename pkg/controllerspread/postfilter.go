@@ -0,0 +1,168 @@
+// pkg/controllerspread/postfilter.go
+//
+// PostFilter extension point for ControllerSpreadFilter. When a pod could not be scheduled
+// because of the spread constraint, PostFilter records a FailedSpread event, see events.go, and
+// looks for a node that would satisfy the spread but was rejected by other plugins (e.g. for lack
+// of resources), and preempts a lower-priority pod of another controller on it, respecting
+// PodDisruptionBudgets.
+package controllerspread
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+var _ framework.PostFilterPlugin = &ControllerSpreadFilter{}
+
+// PostFilter records a FailedSpread event on the pod and preempts a single victim to make room
+// for the pod on a node that satisfies its spread.
+func (csf *ControllerSpreadFilter) PostFilter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	logger := klog.FromContext(ctx)
+	if !rejectedBySpread(filteredNodeStatusMap, csf.Name()) {
+		return nil, framework.NewStatus(framework.Unschedulable, "pod was not rejected by the spread constraint")
+	}
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	csf.events.recordFailedSpread(pod, failedSpreadMessage(filteredNodeStatusMap, csf.Name(), s.levels), time.Now())
+	if pod.Spec.PreemptionPolicy != nil && *pod.Spec.PreemptionPolicy == v1.PreemptNever {
+		return nil, framework.NewStatus(framework.Unschedulable, "pod preemption policy is Never")
+	}
+
+	nodeInfos, err := csf.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	pdbs, err := csf.pdbLister.List(labels.Everything())
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		status, ok := filteredNodeStatusMap[node.Name]
+		if !ok || status.Code() == framework.UnschedulableAndUnresolvable {
+			continue
+		}
+		if !csf.filterNode(logger, s, nodeInfo).IsSuccess() {
+			continue
+		}
+		for _, victim := range csf.preemptionCandidates(logger, pod, s.controller, nodeInfo, pdbs) {
+			if !csf.fitsWithoutVictim(ctx, cycleState, pod, nodeInfo, victim) {
+				continue
+			}
+			if err := csf.preempt(ctx, pod, victim, node.Name); err != nil {
+				return nil, framework.AsStatus(err)
+			}
+			return framework.NewPostFilterResultWithNominatedNode(node.Name), framework.NewStatus(framework.Success)
+		}
+	}
+	return nil, framework.NewStatus(framework.Unschedulable, "no preemption victim found to satisfy the spread constraint")
+}
+
+// rejectedBySpread reports whether at least one node was rejected by the named plugin.
+func rejectedBySpread(filteredNodeStatusMap framework.NodeToStatusMap, plugin string) bool {
+	for _, status := range filteredNodeStatusMap {
+		if status.Plugin() == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// preemptionCandidates returns the pods on the node that may be preempted for the pod, lowest
+// priority first: pods of lower priority that belong to another controller and whose eviction
+// does not violate a PodDisruptionBudget.
+func (csf *ControllerSpreadFilter) preemptionCandidates(logger klog.Logger, pod *v1.Pod, controller ControllerInfo, nodeInfo *framework.NodeInfo, pdbs []*policy.PodDisruptionBudget) []*v1.Pod {
+	var candidates []*v1.Pod
+	for _, podInfo := range nodeInfo.Pods {
+		p := podInfo.Pod
+		if podPriority(p) >= podPriority(pod) || p.DeletionTimestamp != nil {
+			continue
+		}
+		if csf.isOwnedByTopController(logger, p, controller) {
+			continue
+		}
+		if violatesPDB(p, pdbs) {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return podPriority(candidates[i]) < podPriority(candidates[j])
+	})
+	return candidates
+}
+
+// fitsWithoutVictim runs the filter plugins against a copy of the node with the victim removed.
+func (csf *ControllerSpreadFilter) fitsWithoutVictim(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo, victim *v1.Pod) bool {
+	logger := klog.FromContext(ctx)
+	nodeInfoCopy := nodeInfo.Snapshot()
+	if err := nodeInfoCopy.RemovePod(logger, victim); err != nil {
+		return false
+	}
+	victimInfo, err := framework.NewPodInfo(victim)
+	if err != nil {
+		return false
+	}
+	stateCopy := cycleState.Clone()
+	if status := csf.handle.RunPreFilterExtensionRemovePod(ctx, stateCopy, pod, victimInfo, nodeInfoCopy); !status.IsSuccess() {
+		return false
+	}
+	return csf.handle.RunFilterPluginsWithNominatedPods(ctx, stateCopy, pod, nodeInfoCopy).IsSuccess()
+}
+
+// preempt deletes the victim and records a Preempted event, as the default preemptor does.
+func (csf *ControllerSpreadFilter) preempt(ctx context.Context, pod, victim *v1.Pod, nodeName string) error {
+	logger := klog.FromContext(ctx)
+	if waitingPod := csf.handle.GetWaitingPod(victim.UID); waitingPod != nil {
+		waitingPod.Reject(csf.Name(), "preempted")
+	} else if err := schedutil.DeletePod(ctx, csf.handle.ClientSet(), victim); err != nil {
+		logger.Error(err, "Could not preempt pod", "pod", klog.KObj(victim), "preemptor", klog.KObj(pod))
+		return err
+	}
+	logger.V(2).Info("Preempted pod to satisfy spread constraint", "preemptor", klog.KObj(pod), "victim", klog.KObj(victim), "node", nodeName)
+	csf.handle.EventRecorder().Eventf(victim, pod, v1.EventTypeNormal, "Preempted", "Preempting", "Preempted by pod %v on node %v", pod.UID, nodeName)
+	return nil
+}
+
+// violatesPDB reports whether evicting the pod would violate a PodDisruptionBudget that selects it.
+func violatesPDB(pod *v1.Pod, pdbs []*policy.PodDisruptionBudget) bool {
+	for _, pdb := range pdbs {
+		if pdb.Namespace != pod.Namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if _, disrupted := pdb.Status.DisruptedPods[pod.Name]; disrupted {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// podPriority returns the priority of the pod, or 0 if it has none.
+func podPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+// pkg/controllerspread/prebind.go
+//
+// PreBind extension point for ControllerSpreadFilter. Binding runs asynchronously after the
+// scheduling cycle, so peers may have been bound or assumed since Filter accepted the node
+// (e.g. by another scheduler). PreBind re-checks the spread of the chosen node against the
+// latest informer cache and assumed placements, and fails the binding if it is now violated,
+// which sends the pod back to the scheduling queue.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.PreBindPlugin = &ControllerSpreadFilter{}
+
+// PreBind re-runs the spread check for the node chosen for the pod.
+func (csf *ControllerSpreadFilter) PreBind(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	logger := klog.FromContext(ctx)
+	if isSpreadDisabled(logger, pod) {
+		return nil
+	}
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		// PreFilter skipped the pod, so it is not subject to the spread constraint.
+		return nil
+	}
+	nodeInfo, err := csf.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("getting node %q from snapshot: %w", nodeName, err))
+	}
+
+	latest, err := csf.refreshState(ctx, pod, s)
+	if err != nil {
+		logger.Error(err, "Error listing pods", "namespace", pod.Namespace)
+		return framework.AsStatus(fmt.Errorf("error listing pods: %w", err))
+	}
+	status := csf.filterNode(logger, latest, nodeInfo)
+	if status.IsSuccess() || s.preferred {
+		csf.annotateAchievedSpread(ctx, logger, pod, latest, nodeInfo.Node())
+		return nil
+	}
+
+	logger.V(2).Info("Spread constraint violated since the node was selected", "pod", klog.KObj(pod), "node", nodeName,
+		"controllerType", s.controller.Type, "controller", s.controller.Name, "reason", status.Message())
+	if s.mode == ObserveMode {
+		observedRejections.WithLabelValues(csf.Name(), string(s.controller.Type)).Inc()
+		csf.annotateAchievedSpread(ctx, logger, pod, latest, nodeInfo.Node())
+		return nil
+	}
+	return framework.AsStatus(fmt.Errorf("spread constraint violated since node %s was selected: %s", nodeName, status.Message()))
+}
+
+// refreshState recomputes the per-node distribution of the state from the informer cache and the
+// assumed placements. The state is returned unchanged if the distribution did not change.
+func (csf *ControllerSpreadFilter) refreshState(ctx context.Context, pod *v1.Pod, s *controllerSpreadState) (*controllerSpreadState, error) {
+	logger := klog.FromContext(ctx)
+	var controllerPods []*v1.Pod
+	var err error
+	if s.groupID != "" {
+		controllerPods, _, err = csf.listControllerGroupPods(ctx, pod.Namespace, s.groupID)
+	} else {
+		controllerPods, err = csf.listControllerPods(ctx, pod.Namespace, s.controller)
+	}
+	if err != nil {
+		return nil, err
+	}
+	controllerPods = csf.withRequiredResource(withoutPod(controllerPods, pod))
+	if s.groupID == "" {
+		scope := peerScope{revision: s.revision, images: s.imageGroup}
+		scope.index, scope.indexed = csf.completionIndexOf(pod, s.controller)
+		scope.partition, scope.rolling = csf.rollingPartitionOf(pod, s.controller)
+		controllerPods = narrowPeers(controllerPods, s.controller, scope)
+	}
+
+	nodeCounts := csf.peerNodeCounts(s.groupKey, pod, controllerPods)
+	nodeCounts = csf.withinSpreadNodes(pod, nodeCounts, s.nodePool)
+	spreadCounts := nodeCounts
+	if s.readyOnly {
+		// Readiness may change without changing the counts.
+		spreadCounts = csf.withinSpreadNodes(pod, csf.readyNodeCounts(s.groupKey, controllerPods, pod), s.nodePool)
+	} else if equalCounts(nodeCounts, s.nodeCounts) {
+		return s, nil
+	}
+
+	latest := s.Clone().(*controllerSpreadState)
+	latest.controllerPods = controllerPods
+	latest.scheduledPeers = sumCounts(spreadCounts)
+	latest.nodeCounts = nodeCounts
+	for i := range latest.levels {
+		latest.levels[i].domainCounts = csf.countPodsPerDomain(logger, spreadCounts, latest.levels[i].key)
+	}
+	return latest, nil
+}
+
+// equalCounts reports whether the two count maps hold the same counts.
+func equalCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, count := range a {
+		if b[key] != count {
+			return false
+		}
+	}
+	return true
+}
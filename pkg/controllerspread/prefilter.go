@@ -0,0 +1,542 @@
+// pkg/controllerspread/prefilter.go
+//
+// PreFilter extension point for ControllerSpreadFilter. The controller's pods and their
+// per-node distribution are computed once per scheduling cycle and stored in the CycleState,
+// so that Filter does not re-list the namespace for every candidate node.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// preFilterStateKey is the CycleState key under which PreFilter stores its result.
+	preFilterStateKey = "PreFilter" + Name
+)
+
+var _ framework.PreFilterPlugin = &ControllerSpreadFilter{}
+
+// controllerSpreadState is computed in PreFilter and consumed by Filter and Score.
+type controllerSpreadState struct {
+	// controller is the top-level controller of the pod being scheduled.
+	controller ControllerInfo
+	// groupKey identifies the peers of the pod for assumed placements: the controller UID,
+	// qualified by the completion index for Indexed Jobs, or the controller group.
+	groupKey string
+	// groupID is the group-id of the controller if it is part of a controller group, and empty
+	// otherwise.
+	groupID string
+	// revision is the pod-template-hash of the pod's Deployment revision if the Deployment
+	// spreads per revision, and empty otherwise.
+	revision string
+	// imageGroup is the primary container image the peers share if the controller groups its
+	// pods by image, and the zero value otherwise.
+	imageGroup imageGroup
+	// nodePool is the node pool the spread is scoped to, or the zero value for a cluster-wide
+	// spread.
+	nodePool nodePool
+	// controllerPods are the running or pending pods of the controller, including pending
+	// peers that are not yet bound to a node.
+	controllerPods []*v1.Pod
+	// scheduledPeers is the number of peers bound, nominated or assumed onto a node, i.e. the sum of
+	// nodeCounts, or of the Ready peers with readyOnly. Filter's short-circuit for the first
+	// placement uses this count.
+	scheduledPeers int
+	// nodeCounts is the number of controller pods per node name.
+	nodeCounts map[string]int
+	// readyOnly reports that only Ready peers count toward the levels, while nodeCounts holds all
+	// peers for the per-node limits.
+	readyOnly bool
+	// levels are the topology levels of the spread constraint, ordered from the coarsest to
+	// the finest. The last level requires the min-hosts number of domains.
+	levels []topologyLevel
+	// anyLevel reports that a placement meeting the minimum of any one level is accepted, rather
+	// than of every level.
+	anyLevel bool
+	// maxPodsPerNode caps the number of controller pods on a single node; 0 means unlimited.
+	maxPodsPerNode int32
+	// spreadAfter is the number of scheduled peers below which the pod is placed freely.
+	spreadAfter int32
+	// maxSkew caps the skew between the domains of each level; 0 means unlimited.
+	maxSkew int32
+	// reserveDomains is the number of free domains of the finest level to keep for the unplaced
+	// pods; 0 means the levels' minimums apply instead.
+	reserveDomains int32
+	// desired is the number of pods the peers are spread for, e.g. the desired replica count.
+	desired int32
+	// spreadWeight scales the Score of the controller's pods.
+	spreadWeight int64
+	// mode is the enforcement mode of the controller: the plugin-wide Mode, or Enforce for a
+	// controller whose mode annotation is "required".
+	mode Mode
+	// preferred reports that the controller's spread is best-effort: Filter never rejects a
+	// node and spreading is left to Score.
+	preferred bool
+	// reschedule reports that the pod replaces a peer lost to a node failure and its spread is
+	// preferred for that reason, see lostPeers.
+	reschedule bool
+	// vpaReplacement reports that the pod replaces a peer evicted by a VerticalPodAutoscaler and
+	// its spread is relaxed for that reason, see replacesVPAEviction.
+	vpaReplacement bool
+	// onePerNode forbids placing the pod on any node that already runs a controller pod,
+	// regardless of the levels. It is set for DaemonSets and Indexed Jobs.
+	onePerNode bool
+}
+
+// Clone implements framework.StateData.
+func (s *controllerSpreadState) Clone() framework.StateData {
+	if s == nil {
+		return nil
+	}
+	c := &controllerSpreadState{
+		controller:     s.controller,
+		groupKey:       s.groupKey,
+		groupID:        s.groupID,
+		revision:       s.revision,
+		imageGroup:     s.imageGroup,
+		nodePool:       s.nodePool,
+		controllerPods: append([]*v1.Pod(nil), s.controllerPods...),
+		scheduledPeers: s.scheduledPeers,
+		nodeCounts:     make(map[string]int, len(s.nodeCounts)),
+		levels:         make([]topologyLevel, len(s.levels)),
+		anyLevel:       s.anyLevel,
+		readyOnly:      s.readyOnly,
+		maxPodsPerNode: s.maxPodsPerNode,
+		spreadAfter:    s.spreadAfter,
+		maxSkew:        s.maxSkew,
+		reserveDomains: s.reserveDomains,
+		desired:        s.desired,
+		spreadWeight:   s.spreadWeight,
+		mode:           s.mode,
+		preferred:      s.preferred,
+		reschedule:     s.reschedule,
+		vpaReplacement: s.vpaReplacement,
+		onePerNode:     s.onePerNode,
+	}
+	for node, count := range s.nodeCounts {
+		c.nodeCounts[node] = count
+	}
+	for i, level := range s.levels {
+		c.levels[i] = topologyLevel{key: level.key, required: level.required, domainCounts: make(map[string]int, len(level.domainCounts)),
+			eligibleDomains: level.eligibleDomains}
+		for domain, count := range level.domainCounts {
+			c.levels[i].domainCounts[domain] = count
+		}
+	}
+	return c
+}
+
+// PreFilter resolves the pod's controller, its spread requirement and its current pods.
+// It returns Skip when the pod has no controller or the controller wants at most one replica.
+// It logs through the contextual logger of ctx, as do the helpers it passes the logger to.
+func (csf *ControllerSpreadFilter) PreFilter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	logger := klog.FromContext(ctx)
+	if !csf.caches.ready() {
+		// Retried with backoff rather than placing the pod against a partial view.
+		return nil, framework.NewStatus(framework.Error, "waiting for informer caches to sync")
+	}
+	inScope, err := csf.namespaces.inScope(logger, pod.Namespace)
+	if err != nil {
+		return nil, csf.errorStatus(fmt.Errorf("looking up namespace %s: %w", pod.Namespace, err), framework.Skip)
+	}
+	if !inScope {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	controller, ok := csf.resolveGroup(logger, pod)
+	if !ok || !csf.isControllerTypeEnabled(controller.Type) {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	if csf.outOfResourceScope(pod) {
+		// Only pods requesting the RequireResource are spread.
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	if csf.ownedBySuspendedJob(pod) {
+		// The Job controller deletes the pods of a suspended Job; there is nothing to spread.
+		return nil, framework.NewStatus(framework.Skip)
+	}
+
+	minHostsVal := csf.defaultMinHostsFor(pod.Namespace, controller.Type)
+	desired, annotations, err := csf.getGroupSpec(ctx, pod, controller)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, framework.AsStatus(ctxErr)
+	}
+	if err != nil {
+		if isRetriableSpecError(err) {
+			// Scheduling without the spec could place the pod unsafely; retry the pod instead.
+			logger.Error(err, "Could not retrieve controller", "controllerType", controller.Type, "controller", controller.Name, "namespace", pod.Namespace)
+			return nil, framework.NewStatus(framework.Error, fmt.Sprintf("retrieving %s %s/%s: %v", controller.Type, pod.Namespace, controller.Name, err))
+		}
+		if apierrors.IsNotFound(err) {
+			if ownerNamespace, ok := csf.foreignControllerNamespace(pod.Namespace, controller); ok {
+				logger.V(2).Info("Skipping spread for pod owned by a controller in another namespace", "pod", klog.KObj(pod),
+					"controllerType", controller.Type, "controller", controller.Name, "controllerNamespace", ownerNamespace)
+				return nil, framework.NewStatus(framework.Skip)
+			}
+			logger.V(4).Info("Skipping spread for controller that no longer exists", "controllerType", controller.Type, "controller", controller.Name, "namespace", pod.Namespace)
+			return nil, framework.NewStatus(framework.Skip)
+		}
+		logger.Error(err, "Invalid controller spec", "controllerType", controller.Type, "controller", controller.Name, "namespace", pod.Namespace)
+		return nil, csf.errorStatus(err, framework.Skip)
+	}
+
+	var maxPodsPerNode int32
+	if val, exists := annotations[maxPodsPerNodeAnnotationKey]; exists {
+		parsed, ok := parseMaxPodsPerNodeAnnotation(val)
+		if ok {
+			maxPodsPerNode = parsed
+		} else {
+			logger.V(2).Info("Ignoring invalid annotation", "annotation", maxPodsPerNodeAnnotationKey, "value", val,
+				"controller", controller.Name, "namespace", pod.Namespace)
+		}
+	}
+
+	partition, rolling := csf.rollingPartitionOf(pod, controller)
+	revision, revisionDesired, perRevision := csf.revisionOf(logger, pod, controller, annotations)
+	index, indexed := csf.completionIndexOf(pod, controller)
+	images, byImage := imageGroupOf(logger, pod, annotations, controller)
+	var groupPods []*v1.Pod
+	groupID := controllerGroupID(annotations, controller)
+	if groupID != "" {
+		// The pods of every controller of the group are peers; the narrower scopes of a single
+		// controller do not apply.
+		partition, rolling = 0, false
+		revision, perRevision = "", false
+		index, indexed = "", false
+		images, byImage = imageGroup{}, false
+		var groupDesiredCounts map[string]int32
+		groupPods, groupDesiredCounts, err = csf.listControllerGroupPods(ctx, pod.Namespace, groupID)
+		if err != nil {
+			logger.Error(err, "Error listing pods", "namespace", pod.Namespace)
+			return nil, framework.NewStatus(framework.Error, fmt.Sprintf("error listing pods: %v", err))
+		}
+		desired = groupDesired(groupDesiredCounts, controller, desired)
+	}
+	if rolling {
+		// Only the ordinals being rolled are peers.
+		desired -= partition
+	}
+	if perRevision {
+		// Only the pods of the same Deployment revision are peers.
+		desired = revisionDesired
+	}
+
+	if val, exists := minHostsAnnotation(pod, annotations); exists {
+		minHostsVal, err = parseMinHostsAnnotation(val, desired, minHostsVal)
+		if err != nil {
+			csf.reportInvalidAnnotation(logger, minHostsAnnotationKey, controller, err)
+		} else if minHostsVal > desired {
+			csf.reportMinHostsClamped(logger, pod, controller, minHostsVal, desired)
+		}
+	}
+
+	requiredHosts := min(desired, minHostsVal)
+	if pdbHosts := csf.pdbRequiredHosts(logger, pod, desired); pdbHosts > requiredHosts {
+		// Losing any one domain must not take the pods below the minAvailable of their PDB.
+		requiredHosts = pdbHosts
+	}
+	strict := isStrictSpread(logger, annotations, controller)
+	if strict {
+		// Every pod up to the desired count needs its own node; min-hosts does not relax it.
+		requiredHosts = desired
+	}
+	if desired <= 1 && maxPodsPerNode == 0 && controller.Type != LabelGroupType {
+		// A single pod is always spread. With a per-node cap, or in a label group whose members
+		// may outnumber its size, surplus pods such as rolling update surges are still checked.
+		return nil, framework.NewStatus(framework.Skip)
+	}
+
+	groupKey := controller.UID
+	onePerNode := controller.Type == DaemonSetType
+	if indexed {
+		// Only pods with the same completion index are peers, at most one per node.
+		groupKey = controller.UID + "/" + index
+		onePerNode = true
+	}
+	if rolling {
+		groupKey = controller.UID + "/rolling"
+	}
+	if perRevision {
+		groupKey = controller.UID + "/" + revision
+	}
+	if byImage {
+		groupKey += "/" + images.image
+	}
+	if groupID != "" {
+		groupKey = controllerGroupKey(pod.Namespace, groupID)
+	}
+
+	var controllerPods []*v1.Pod
+	var nodeCounts map[string]int
+	counted := false
+	consistentRead := csf.wantsConsistentRead(logger, annotations, controller)
+	readyOnly := isCountReadyOnly(logger, annotations, controller)
+	if !consistentRead && !readyOnly {
+		nodeCounts, counted = csf.eventDrivenCounts(pod, controller, groupKey)
+	}
+	if !counted {
+		if groupID != "" {
+			controllerPods = csf.withRequiredResource(withoutPod(groupPods, pod))
+		} else {
+			controllerPods, err = csf.listPeers(ctx, pod, controller, consistentRead)
+		}
+		if err != nil {
+			logger.Error(err, "Error listing pods", "namespace", pod.Namespace)
+			return nil, framework.NewStatus(framework.Error, fmt.Sprintf("error listing pods: %v", err))
+		}
+		controllerPodsScanned.WithLabelValues(csf.Name()).Set(float64(len(controllerPods)))
+		if gated, ok := csf.awaitingSpreadGate(controllerPods); ok {
+			logger.V(4).Info("Controller not ready to spread, a pod still carries the spread gate", "pod", klog.KObj(pod),
+				"controller", controller.Name, "gatedPod", klog.KObj(gated), "gate", csf.args.SpreadGate)
+			return nil, framework.NewStatus(framework.Skip)
+		}
+		scope := peerScope{index: index, indexed: indexed, partition: partition, rolling: rolling, revision: revision, images: images}
+		controllerPods = narrowPeers(controllerPods, controller, scope)
+		nodeCounts = csf.peerNodeCounts(groupKey, pod, controllerPods)
+		csf.addSnapshotPeers(logger, pod, controllerPods, nodeCounts)
+	}
+	pool, _ := nodePoolOf(logger, pod, annotations, controller)
+	nodeCounts = csf.withinSpreadNodes(pod, nodeCounts, pool)
+	spreadCounts := nodeCounts
+	if readyOnly {
+		spreadCounts = csf.withinSpreadNodes(pod, csf.readyNodeCounts(groupKey, controllerPods, pod), pool)
+	}
+
+	levels := csf.topologyLevels(logger, controller, annotations, spreadCounts, desired, minHostsVal, requiredHosts)
+	if err := csf.addEligibleDomains(logger, pod, levels); err != nil {
+		return nil, framework.AsStatus(fmt.Errorf("listing nodes: %w", err))
+	}
+	if strict {
+		// A strict controller keeps its pods on distinct nodes even if some then stay pending.
+		clampToFeasibleDomains(logger, pod, levels[:len(levels)-1])
+	} else {
+		clampToFeasibleDomains(logger, pod, levels)
+	}
+	maxSkew := parseMaxSkewAnnotation(logger, annotations, controller)
+	if grace := parseScaleUpGraceAnnotation(logger, annotations, controller); grace > 0 && csf.scaleUps.scaledUpWithin(controller.UID, grace, time.Now()) {
+		// Right after a scale-up only min-hosts is enforced, so a burst of new pods is not held
+		// back by the per-node cap and the skew limit.
+		logger.V(4).Info("Relaxing spread during scale-up grace period", "pod", klog.KObj(pod), "controller", controller.Name, "grace", grace)
+		maxPodsPerNode = 0
+		maxSkew = 0
+	}
+	vpaReplacement := csf.replacesVPAEviction(pod, controller, time.Now())
+	if vpaReplacement {
+		// The VPA recreates pods one at a time; the replacement may meet transiently co-located
+		// peers, so it is relaxed like during a scale-up grace period.
+		logger.V(4).Info("Relaxing spread for pod replacing a peer evicted by a VerticalPodAutoscaler", "pod", klog.KObj(pod), "controller", controller.Name)
+		maxPodsPerNode = 0
+		maxSkew = 0
+	}
+
+	mode, preferred := parseSpreadModeAnnotation(logger, annotations, csf.args.Mode)
+	reschedule := !preferred && !enforcesOnReschedule(logger, annotations, controller) && csf.lostPeers.pending(controller.UID, time.Now())
+	if reschedule {
+		// The pod replaces a peer lost to a node failure; it may re-pack rather than stay pending.
+		logger.V(2).Info("Relaxing spread for pod replacing a peer lost to a node failure", "pod", klog.KObj(pod),
+			"controllerType", controller.Type, "controller", controller.Name)
+		preferred = true
+	}
+	s := &controllerSpreadState{
+		controller:     controller,
+		groupKey:       groupKey,
+		groupID:        groupID,
+		revision:       revision,
+		imageGroup:     images,
+		nodePool:       pool,
+		controllerPods: controllerPods,
+		scheduledPeers: sumCounts(spreadCounts),
+		nodeCounts:     nodeCounts,
+		levels:         levels,
+		anyLevel:       parseLevelMatchAnnotation(logger, annotations, controller),
+		readyOnly:      readyOnly,
+		maxPodsPerNode: maxPodsPerNode,
+		spreadAfter:    parseSpreadAfterAnnotation(logger, annotations, controller),
+		maxSkew:        maxSkew,
+		reserveDomains: parseReserveDomainsAnnotation(logger, annotations, controller),
+		desired:        desired,
+		spreadWeight:   parseSpreadWeightAnnotation(annotations),
+		mode:           mode,
+		preferred:      preferred,
+		reschedule:     reschedule,
+		vpaReplacement: vpaReplacement,
+		onePerNode:     onePerNode,
+	}
+	csf.tracker.record(pod.Namespace, s, time.Now())
+	cycleState.Write(preFilterStateKey, s)
+	return nil, nil
+}
+
+// computePreFilterState runs PreFilter for a pod whose cycle state holds no PreFilter state, which
+// happens when the plugin is not enabled at the PreFilter extension point. PreFilter writes the
+// state to the cycle state, so that Filter computes it once per cycle rather than once per node.
+// It returns nil and the status of Filter if PreFilter skipped the pod or failed.
+func (csf *ControllerSpreadFilter) computePreFilterState(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod) (*controllerSpreadState, *framework.Status) {
+	_, status := csf.PreFilter(ctx, cycleState, pod)
+	if status.Code() == framework.Skip {
+		return nil, nil
+	}
+	if !status.IsSuccess() {
+		return nil, status
+	}
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	return s, nil
+}
+
+// PreFilterExtensions returns nil as the plugin does not track added or removed pods.
+func (csf *ControllerSpreadFilter) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+// getPreFilterState reads the PreFilter result from the cycle state.
+func getPreFilterState(cycleState *framework.CycleState) (*controllerSpreadState, error) {
+	c, err := cycleState.Read(preFilterStateKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q from cycleState: %w", preFilterStateKey, err)
+	}
+	s, ok := c.(*controllerSpreadState)
+	if !ok {
+		return nil, fmt.Errorf("%+v convert to controllerspread.controllerSpreadState error", c)
+	}
+	return s, nil
+}
+
+// peerScope narrows the pods of a controller to the peers of the pod being scheduled. The zero
+// value keeps all pods.
+type peerScope struct {
+	// index is the completion index of an Indexed Job pod, if indexed.
+	index   string
+	indexed bool
+	// partition is the StatefulSet partition of a rolling update, if rolling.
+	partition int32
+	rolling   bool
+	// revision is the pod-template-hash of a Deployment spread per revision, or empty.
+	revision string
+	// images is the image group of a controller grouping its pods by image, or the zero value.
+	images imageGroup
+}
+
+// listPeers returns the active pods of the pod's controller other than the pod itself that
+// count toward its spread, read from the API server for consistent reads when permitted and
+// from the informer cache otherwise.
+func (csf *ControllerSpreadFilter) listPeers(ctx context.Context, pod *v1.Pod, controller ControllerInfo, consistentRead bool) ([]*v1.Pod, error) {
+	var controllerPods []*v1.Pod
+	listed := false
+	var err error
+	if consistentRead {
+		controllerPods, listed, err = csf.listControllerPodsConsistently(ctx, pod.Namespace, controller)
+	}
+	if err == nil && !listed {
+		controllerPods, err = csf.listControllerPods(ctx, pod.Namespace, controller)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return csf.withRequiredResource(withoutPod(controllerPods, pod)), nil
+}
+
+// narrowPeers returns the pods of the controller within the scope.
+func narrowPeers(pods []*v1.Pod, controller ControllerInfo, scope peerScope) []*v1.Pod {
+	if scope.indexed {
+		pods = withCompletionIndex(pods, scope.index)
+	}
+	if scope.rolling {
+		pods = withOrdinalAtLeast(pods, controller.Name, scope.partition)
+	}
+	if scope.revision != "" {
+		pods = withPodTemplateHash(pods, scope.revision)
+	}
+	if scope.images.image != "" {
+		// Only the pods running the same image are peers.
+		pods = withImage(pods, scope.images)
+	}
+	return pods
+}
+
+// peerNodeCounts returns the number of peers bound, nominated or assumed onto each node.
+func (csf *ControllerSpreadFilter) peerNodeCounts(groupKey string, pod *v1.Pod, peers []*v1.Pod) map[string]int {
+	nodeCounts := countPodsPerNode(peers)
+	csf.assumed.addToNodeCounts(groupKey, placementsOf(peers), pod.UID, nodeCounts, time.Now())
+	return nodeCounts
+}
+
+// withoutPod returns the pods other than the given one. The pod being scheduled may already be
+// in the informer cache with a stale NodeName (e.g. after a failed bind) and must not count
+// toward its own spread.
+func withoutPod(pods []*v1.Pod, pod *v1.Pod) []*v1.Pod {
+	result := make([]*v1.Pod, 0, len(pods))
+	for _, p := range pods {
+		if p.UID != pod.UID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// sumCounts returns the total of the counts.
+func sumCounts(counts map[string]int) int {
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// countPodsPerNode returns the number of pods bound or nominated to each node. Pods that are
+// neither bound nor nominated are ignored.
+func countPodsPerNode(pods []*v1.Pod) map[string]int {
+	nodeCounts := make(map[string]int)
+	for _, p := range pods {
+		if nodeName := placedNodeName(p); nodeName != "" {
+			nodeCounts[nodeName]++
+		}
+	}
+	return nodeCounts
+}
+
+// peerPlacement is the node a peer is bound or nominated to.
+type peerPlacement struct {
+	nodeName string
+	// bound is false for a pod nominated to the node while waiting on preemption.
+	bound bool
+}
+
+// placementsOf returns the placements of the bound and nominated pods, keyed by pod UID.
+func placementsOf(pods []*v1.Pod) map[types.UID]peerPlacement {
+	placements := make(map[types.UID]peerPlacement, len(pods))
+	for _, p := range pods {
+		if nodeName := placedNodeName(p); nodeName != "" {
+			placements[p.UID] = peerPlacement{nodeName: nodeName, bound: p.Spec.NodeName != ""}
+		}
+	}
+	return placements
+}
+
+// countPlacements returns the number of placements on each node.
+func countPlacements(placements map[types.UID]peerPlacement) map[string]int {
+	nodeCounts := make(map[string]int)
+	for _, placement := range placements {
+		nodeCounts[placement.nodeName]++
+	}
+	return nodeCounts
+}
+
+// placedNodeName returns the node the pod is bound to or, for a pod waiting on preemption, the
+// node it is nominated to, so that two peers are not nominated to the same node. It returns ""
+// for a pod that is neither bound nor nominated.
+func placedNodeName(p *v1.Pod) string {
+	if p.Spec.NodeName != "" {
+		return p.Spec.NodeName
+	}
+	return p.Status.NominatedNodeName
+}
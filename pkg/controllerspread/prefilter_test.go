@@ -0,0 +1,280 @@
+package controllerspread
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestPreFilterExcludesPodBeingScheduled(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		// cachedNode is the stale node of the pod being scheduled in the informer cache, e.g.
+		// after a failed bind, or empty if the pod is not in the cache.
+		cachedNode string
+		want       []string
+	}{
+		{
+			name: "pod not in the cache",
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:       "pod cached with a stale node",
+			cachedNode: "node-b",
+			want:       []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			if tt.cachedNode != "" {
+				cached := makePod("web-new", tt.cachedNode, ownerRef(ReplicaSetType, "web-hash"))
+				objs = append(objs, cached)
+			}
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, objs...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterNominatedPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		// nominatedNode is the node the pending peer is nominated to, or empty if it is not
+		// nominated.
+		nominatedNode string
+		want          []string
+	}{
+		{
+			name: "pending peer",
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:          "nominated peer",
+			nominatedNode: "node-b",
+			want:          []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a")
+			peer := makePod("web-1", "", ownerRef(ReplicaSetType, "web-hash"))
+			peer.Status.NominatedNodeName = tt.nominatedNode
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, peer, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPreFilterSingleReplica(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantSkip    bool
+		want        []string
+	}{
+		{
+			name:     "no per-node cap",
+			wantSkip: true,
+			want:     []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:        "per-node cap",
+			annotations: map[string]string{maxPodsPerNodeAnnotationKey: "1"},
+			want:        []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// web-0 is still running while the surge pod of a rolling update is scheduled.
+			objs := makeDeploymentPods(makeDeployment("web", 1, tt.annotations), "node-a")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if _, status := preFilter(t, p, pod); (status.Code() == framework.Skip) != tt.wantSkip {
+				t.Errorf("PreFilter() = %v, want Skip %v", status, tt.wantSkip)
+			}
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCountPodsPerNode(t *testing.T) {
+	owner := ownerRef(ReplicaSetType, "web-hash")
+	nominated := makePod("web-1", "", owner)
+	nominated.Status.NominatedNodeName = "node-b"
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want map[string]int
+	}{
+		{
+			name: "bound pods",
+			pods: []*v1.Pod{makePod("web-0", "node-a", owner), makePod("web-1", "node-a", owner)},
+			want: map[string]int{"node-a": 2},
+		},
+		{
+			name: "pending pod",
+			pods: []*v1.Pod{makePod("web-0", "node-a", owner), makePod("web-1", "", owner)},
+			want: map[string]int{"node-a": 1},
+		},
+		{
+			name: "nominated pod",
+			pods: []*v1.Pod{makePod("web-0", "node-a", owner), nominated},
+			want: map[string]int{"node-a": 1, "node-b": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, countPodsPerNode(tt.pods)); diff != "" {
+				t.Errorf("countPodsPerNode() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// erroringDeploymentLister is a DeploymentLister whose Get fails with err.
+type erroringDeploymentLister struct {
+	appslisters.DeploymentLister
+	err error
+}
+
+func (l erroringDeploymentLister) Deployments(namespace string) appslisters.DeploymentNamespaceLister {
+	return erroringDeploymentNamespaceLister{DeploymentNamespaceLister: l.DeploymentLister.Deployments(namespace), err: l.err}
+}
+
+type erroringDeploymentNamespaceLister struct {
+	appslisters.DeploymentNamespaceLister
+	err error
+}
+
+func (l erroringDeploymentNamespaceLister) Get(string) (*appsv1.Deployment, error) {
+	return nil, l.err
+}
+
+func TestPreFilterControllerLookupErrors(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	deploy := makeDeployment("web", 3, nil)
+	tests := []struct {
+		name string
+		objs []runtime.Object
+		// lookupErr fails the Deployment lookup if set.
+		lookupErr error
+		want      framework.Code
+	}{
+		{
+			name: "controller found",
+			objs: makeDeploymentPods(deploy, "node-a"),
+			want: framework.Success,
+		},
+		{
+			name: "controller not found",
+			objs: []runtime.Object{makeReplicaSet(deploy), makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash"))},
+			want: framework.Skip,
+		},
+		{
+			name:      "transient lookup error",
+			objs:      makeDeploymentPods(deploy, "node-a"),
+			lookupErr: apierrors.NewServiceUnavailable("etcd is unavailable"),
+			want:      framework.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			listers := newTestListers(nodes, append(tt.objs, pod)...)
+			if tt.lookupErr != nil {
+				listers.Deployments = erroringDeploymentLister{DeploymentLister: listers.Deployments, err: tt.lookupErr}
+			}
+			p, err := NewWithListersContext(t.Context(), &ControllerSpreadArgs{}, newTestFramework(t, nodes, nil), listers)
+			if err != nil {
+				t.Fatalf("NewWithListersContext: %v", err)
+			}
+
+			if _, status := preFilter(t, p.(*ControllerSpreadFilter), pod); status.Code() != tt.want {
+				t.Errorf("PreFilter() = %v, want %v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetriableSpecError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "not found",
+			err:  apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web"),
+		},
+		{
+			name: "invalid spec",
+			err:  fmt.Errorf("replicas path: %w", errInvalidSpec),
+		},
+		{
+			name: "API server error",
+			err:  apierrors.NewServiceUnavailable("etcd is unavailable"),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetriableSpecError(tt.err); got != tt.want {
+				t.Errorf("isRetriableSpecError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutPod(t *testing.T) {
+	owner := ownerRef(ReplicaSetType, "web-hash")
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want []string
+	}{
+		{
+			name: "pod listed",
+			pods: []*v1.Pod{makePod("web-0", "node-a", owner), makePod("web-new", "node-b", owner)},
+			want: []string{"web-0"},
+		},
+		{
+			name: "pod not listed",
+			pods: []*v1.Pod{makePod("web-0", "node-a", owner), makePod("web-1", "node-b", owner)},
+			want: []string{"web-0", "web-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range withoutPod(tt.pods, makePod("web-new", "", owner)) {
+				got = append(got, p.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withoutPod() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
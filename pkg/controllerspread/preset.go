@@ -0,0 +1,30 @@
+// pkg/controllerspread/preset.go
+//
+// Presets expand into a combination of plugin args for common spreading policies. The HA preset
+// requires controllers to span availability zones and then balances their pods across the nodes
+// of those zones: the zone level is enforced by Filter, and Score, which prefers nodes running
+// fewer of the controller's pods, spreads them across nodes on a best-effort basis. Presets only
+// fill args that are not set, so explicit args always take precedence.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// Preset names a combination of plugin args.
+type Preset string
+
+const (
+	// HAPreset requires spreading across zones and prefers spreading across nodes.
+	HAPreset Preset = "HA"
+)
+
+// applyPreset fills the unset args expanded from the preset of the args.
+func applyPreset(args *ControllerSpreadArgs) {
+	switch args.Preset {
+	case HAPreset:
+		if len(args.TopologyKeys) == 0 {
+			args.TopologyKeys = []string{v1.LabelTopologyZone}
+		}
+	}
+}
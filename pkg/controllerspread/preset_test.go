@@ -0,0 +1,73 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestApplyPreset(t *testing.T) {
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "no preset",
+		},
+		{
+			name: "HA preset",
+			args: ControllerSpreadArgs{Preset: HAPreset},
+			want: []string{v1.LabelTopologyZone},
+		},
+		{
+			name: "HA preset with explicit topology keys",
+			args: ControllerSpreadArgs{Preset: HAPreset, TopologyKeys: []string{"topology.example.com/rack"}},
+			want: []string{"topology.example.com/rack"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyPreset(&tt.args)
+			if diff := cmp.Diff(tt.want, tt.args.TopologyKeys); diff != "" {
+				t.Errorf("topology keys (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterHAPreset(t *testing.T) {
+	nodes := []*v1.Node{
+		makeNode("node-a1", map[string]string{v1.LabelTopologyZone: "zone-a"}),
+		makeNode("node-a2", map[string]string{v1.LabelTopologyZone: "zone-a"}),
+		makeNode("node-b1", map[string]string{v1.LabelTopologyZone: "zone-b"}),
+	}
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		want []string
+	}{
+		{
+			name: "no preset",
+			want: []string{"node-a2", "node-b1"},
+		},
+		{
+			name: "HA preset",
+			args: ControllerSpreadArgs{Preset: HAPreset},
+			want: []string{"node-b1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDefaults_ControllerSpreadArgs(&tt.args)
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "2"}), "node-a1")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// pkg/controllerspread/queueing_hints.go
+//
+// EnqueueExtensions for ControllerSpreadFilter. Pods rejected because of the spread constraint
+// are moved back to the active queue when a peer is placed, moves or goes away, or when nodes
+// change their topology, instead of waiting for the periodic backoff. With the
+// SchedulerQueueingHints feature enabled, the hints skip events that cannot unblock the pod, and
+// peer events queue the controller's rejected pods in batches, see requeueBatcher.
+package controllerspread
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+var _ framework.EnqueueExtensions = &ControllerSpreadFilter{}
+
+// EventsToRegister returns the cluster events that may make a pod rejected by the plugin
+// schedulable.
+func (csf *ControllerSpreadFilter) EventsToRegister() []framework.ClusterEventWithHint {
+	return []framework.ClusterEventWithHint{
+		// A peer that is placed, moves, terminates or leaves the group changes the spread.
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.All}, QueueingHintFn: csf.isSchedulableAfterPodChange},
+		// New nodes add domains, deleted nodes may lower the feasible spread, and label or taint
+		// changes move nodes between domains. UpdateNodeTaint also catches nodes that become
+		// ready after their Add event was filtered out.
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add | framework.Delete | framework.UpdateNodeLabel | framework.UpdateNodeTaint}, QueueingHintFn: csf.isSchedulableAfterNodeChange},
+	}
+}
+
+// isSchedulableAfterPodChange queues the pod only if the event changes where a peer of the pod
+// occupies a node, or whether a placed peer is Ready.
+func (csf *ControllerSpreadFilter) isSchedulableAfterPodChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldPod, newPod, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	controller, ok := csf.resolveGroup(logger, pod)
+	if !ok {
+		return framework.Queue, nil
+	}
+	oldNode, newNode := csf.peerNode(logger, pod, controller, oldPod), csf.peerNode(logger, pod, controller, newPod)
+	// Readiness changes matter to controllers that count Ready peers only.
+	if oldNode == newNode && (newNode == "" || podutil.IsPodReady(oldPod) == podutil.IsPodReady(newPod)) {
+		logger.V(5).Info("Pod event does not change the placement of a peer", "pod", klog.KObj(pod), "changedPod", klog.KObj(newPod))
+		return framework.QueueSkip, nil
+	}
+	if csf.requeues != nil && !csf.requeues.admit(controller.UID, oldPod, newPod, time.Now()) {
+		logger.V(5).Info("Peer placement changed, but the requeue batch of the controller is full", "pod", klog.KObj(pod), "changedPod", klog.KObj(newPod))
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("Peer placement changed, the pod may be schedulable", "pod", klog.KObj(pod), "changedPod", klog.KObj(newPod))
+	return framework.Queue, nil
+}
+
+// peerNode returns the node occupied by p as a peer of the pod, or "" if p is not an active peer
+// bound or nominated to a node.
+func (csf *ControllerSpreadFilter) peerNode(logger klog.Logger, pod *v1.Pod, controller ControllerInfo, p *v1.Pod) string {
+	if p == nil || p.UID == pod.UID || p.Namespace != pod.Namespace || placedNodeName(p) == "" {
+		return ""
+	}
+	if !csf.isActivePod(p) {
+		return ""
+	}
+	if !csf.isOwnedByTopController(logger, p, controller) && !csf.inControllerGroupOf(logger, pod.Namespace, controller, p) {
+		return ""
+	}
+	return placedNodeName(p)
+}
+
+// isSchedulableAfterNodeChange queues the pod when a node is added or deleted, when the
+// labels or taints that define topology domains change, or when a node is cordoned or
+// uncordoned, which changes the counts with IgnoreCordonedNodes.
+func (csf *ControllerSpreadFilter) isSchedulableAfterNodeChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldNode, newNode, err := schedutil.As[*v1.Node](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if oldNode == nil || newNode == nil {
+		return framework.Queue, nil
+	}
+	if maps.Equal(oldNode.Labels, newNode.Labels) && oldNode.Spec.Unschedulable == newNode.Spec.Unschedulable && slices.EqualFunc(oldNode.Spec.Taints, newNode.Spec.Taints, func(a, b v1.Taint) bool {
+		return a.Key == b.Key && a.Value == b.Value
+	}) {
+		logger.V(5).Info("Node update does not change topology domains", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return framework.QueueSkip, nil
+	}
+	return framework.Queue, nil
+}
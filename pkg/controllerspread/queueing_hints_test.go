@@ -0,0 +1,165 @@
+package controllerspread
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestIsSchedulableAfterPodChange(t *testing.T) {
+	owner := ownerRef(ReplicaSetType, "web-hash")
+	ready := func(p *v1.Pod) *v1.Pod {
+		p.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+		return p
+	}
+	tests := []struct {
+		name    string
+		oldObj  interface{}
+		newObj  interface{}
+		want    framework.QueueingHint
+		wantErr bool
+	}{
+		{
+			name:   "peer bound",
+			oldObj: makePod("web-1", "", owner),
+			newObj: makePod("web-1", "node-a", owner),
+			want:   framework.Queue,
+		},
+		{
+			name:   "peer added on a node",
+			newObj: makePod("web-1", "node-a", owner),
+			want:   framework.Queue,
+		},
+		{
+			name:   "peer deleted",
+			oldObj: makePod("web-1", "node-a", owner),
+			want:   framework.Queue,
+		},
+		{
+			name:   "peer became Ready",
+			oldObj: makePod("web-1", "node-a", owner),
+			newObj: ready(makePod("web-1", "node-a", owner)),
+			want:   framework.Queue,
+		},
+		{
+			name:   "peer updated on the same node",
+			oldObj: makePod("web-1", "node-a", owner),
+			newObj: makePod("web-1", "node-a", owner),
+			want:   framework.QueueSkip,
+		},
+		{
+			name:   "pending peer updated",
+			oldObj: makePod("web-1", "", owner),
+			newObj: makePod("web-1", "", owner),
+			want:   framework.QueueSkip,
+		},
+		{
+			name:   "pod of another controller bound",
+			oldObj: makePod("api-1", "", ownerRef(ReplicaSetType, "api-hash")),
+			newObj: makePod("api-1", "node-a", ownerRef(ReplicaSetType, "api-hash")),
+			want:   framework.QueueSkip,
+		},
+		{
+			name:   "the pod itself bound",
+			oldObj: makePod("web-new", "", owner),
+			newObj: makePod("web-new", "node-a", owner),
+			want:   framework.QueueSkip,
+		},
+		{
+			name:    "unexpected object",
+			newObj:  makeNode("node-a", nil),
+			want:    framework.Queue,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := makeNodes("node-a", "node-b")
+			objs := makeDeploymentPods(makeDeployment("web", 3, nil), "node-a")
+			objs = append(objs, makeDeploymentPods(makeDeployment("api", 3, nil))...)
+			pod := makePod("web-new", "", owner)
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			got, err := p.isSchedulableAfterPodChange(klog.Background(), pod, tt.oldObj, tt.newObj)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("isSchedulableAfterPodChange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("isSchedulableAfterPodChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSchedulableAfterNodeChange(t *testing.T) {
+	zoneA := map[string]string{v1.LabelTopologyZone: "zone-a"}
+	zoneB := map[string]string{v1.LabelTopologyZone: "zone-b"}
+	tainted := func(n *v1.Node) *v1.Node {
+		n.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+		return n
+	}
+	cordoned := func(n *v1.Node) *v1.Node {
+		n.Spec.Unschedulable = true
+		return n
+	}
+	heartbeat := func(n *v1.Node) *v1.Node {
+		n.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+		return n
+	}
+	tests := []struct {
+		name   string
+		oldObj interface{}
+		newObj interface{}
+		want   framework.QueueingHint
+	}{
+		{
+			name:   "node added",
+			newObj: makeNode("node-c", zoneA),
+			want:   framework.Queue,
+		},
+		{
+			name:   "node deleted",
+			oldObj: makeNode("node-c", zoneA),
+			want:   framework.Queue,
+		},
+		{
+			name:   "topology label changed",
+			oldObj: makeNode("node-c", zoneA),
+			newObj: makeNode("node-c", zoneB),
+			want:   framework.Queue,
+		},
+		{
+			name:   "taint added",
+			oldObj: makeNode("node-c", zoneA),
+			newObj: tainted(makeNode("node-c", zoneA)),
+			want:   framework.Queue,
+		},
+		{
+			name:   "node cordoned",
+			oldObj: makeNode("node-c", zoneA),
+			newObj: cordoned(makeNode("node-c", zoneA)),
+			want:   framework.Queue,
+		},
+		{
+			name:   "status update",
+			oldObj: makeNode("node-c", zoneA),
+			newObj: heartbeat(makeNode("node-c", zoneA)),
+			want:   framework.QueueSkip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := &ControllerSpreadFilter{}
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			got, err := csf.isSchedulableAfterNodeChange(klog.Background(), pod, tt.oldObj, tt.newObj)
+			if err != nil {
+				t.Fatalf("isSchedulableAfterNodeChange: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isSchedulableAfterNodeChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// pkg/controllerspread/ready_only.go
+//
+// Spreading among Ready peers only. Peers that are Pending, or running but not Ready, may never
+// become available, e.g. when they wait for resources no node has, and counting them makes the
+// spread look better than it is. With the "controller-spread-scheduler/count-ready-only"
+// annotation set to "true" on the controller, only peers whose Ready condition is true, and
+// placements of this scheduler that are still in flight, count toward the spread levels and the
+// skew. All active peers still count for the per-node limits, max-pods-per-node and one pod per
+// node for DaemonSets and Indexed Jobs.
+package controllerspread
+
+import (
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+const (
+	// Annotation key to count only Ready peers toward the spread.
+	countReadyOnlyAnnotationKey = "controller-spread-scheduler/count-ready-only"
+)
+
+// isCountReadyOnly reports whether only the Ready peers of the controller count toward its spread.
+func isCountReadyOnly(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	val, exists := annotations[countReadyOnlyAnnotationKey]
+	if !exists {
+		return false
+	}
+	readyOnly, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", countReadyOnlyAnnotationKey, "value", val, "controller", controller.Name)
+		return false
+	}
+	return readyOnly
+}
+
+// readyNodeCounts returns the number of Ready peers per node, plus the placements of the group
+// still in flight, which are not Ready yet but must be spread like Ready peers.
+func (csf *ControllerSpreadFilter) readyNodeCounts(groupKey string, peers []*v1.Pod, pod *v1.Pod) map[string]int {
+	var ready []*v1.Pod
+	for _, p := range peers {
+		if podutil.IsPodReady(p) {
+			ready = append(ready, p)
+		}
+	}
+	nodeCounts := countPodsPerNode(ready)
+	csf.assumed.addToNodeCounts(groupKey, placementsOf(ready), pod.UID, nodeCounts, time.Now())
+	return nodeCounts
+}
+
+// withinSpreadNodes removes the nodes that are not spread domains for the pod from the per-node
+// pod counts: excluded nodes, cordoned nodes with IgnoreCordonedNodes, nodes with taints the pod
+// does not tolerate, nodes reserved for another tenant and nodes outside the pool.
+func (csf *ControllerSpreadFilter) withinSpreadNodes(pod *v1.Pod, nodeCounts map[string]int, pool nodePool) map[string]int {
+	nodeCounts = csf.withoutExcludedNodes(nodeCounts)
+	nodeCounts = csf.withoutCordonedNodes(nodeCounts)
+	nodeCounts = csf.withoutUntoleratedNodes(pod, nodeCounts)
+	nodeCounts = csf.withoutReservedNodes(nodeCounts, csf.tenantOf(pod))
+	return csf.withinNodePool(nodeCounts, pool)
+}
@@ -0,0 +1,91 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// withReady sets the Ready condition of the pod and returns the pod.
+func withReady(pod *v1.Pod, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: status}}
+	return pod
+}
+
+func TestIsCountReadyOnly(t *testing.T) {
+	controller := ControllerInfo{Type: DeploymentType, Name: "web", UID: string(testUID("web"))}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:        "enabled",
+			annotations: map[string]string{countReadyOnlyAnnotationKey: "true"},
+			want:        true,
+		},
+		{
+			name:        "disabled",
+			annotations: map[string]string{countReadyOnlyAnnotationKey: "false"},
+		},
+		{
+			name:        "invalid value",
+			annotations: map[string]string{countReadyOnlyAnnotationKey: "ready"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCountReadyOnly(klog.Background(), tt.annotations, controller); got != tt.want {
+				t.Errorf("isCountReadyOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCountReadyOnly(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "all active peers counted",
+			annotations: map[string]string{minHostsAnnotationKey: "3"},
+			want:        []string{"node-c"},
+		},
+		{
+			name:        "Ready peers counted",
+			annotations: map[string]string{minHostsAnnotationKey: "3", countReadyOnlyAnnotationKey: "true"},
+			want:        []string{"node-b", "node-c"},
+		},
+		{
+			name: "unready peer counted for max-pods-per-node",
+			annotations: map[string]string{minHostsAnnotationKey: "3", countReadyOnlyAnnotationKey: "true",
+				maxPodsPerNodeAnnotationKey: "1"},
+			want: []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := ownerRef(ReplicaSetType, "web-hash")
+			objs := makeDeploymentPods(makeDeployment("web", 3, tt.annotations))
+			objs = append(objs, withReady(makePod("web-0", "node-a", owner), true), withReady(makePod("web-1", "node-b", owner), false))
+			pod := makePod("web-new", "", owner)
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// pkg/controllerspread/rejection_log.go
+//
+// Rate-limited rejection logging for ControllerSpreadFilter. Every node Filter rejects is logged
+// at verbosity 5 with its details, which floods the logs when many pods churn. In addition, the
+// rejections of each controller are counted and summarized at verbosity 2 at most once per
+// RejectionLogInterval: the first rejection is logged right away, and the rejections in between
+// are reported with the next summary.
+package controllerspread
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultRejectionLogInterval is the default minimum interval between the rejection
+	// summaries of a controller.
+	defaultRejectionLogInterval = time.Minute
+)
+
+// rejectionSummary counts the rejections of a controller since its last summary.
+type rejectionSummary struct {
+	controller ControllerInfo
+	namespace  string
+	logged     time.Time
+	rejections int
+	pods       map[string]bool
+	reason     string
+}
+
+// rejectionLogger summarizes the rejections of each controller at most once per interval.
+type rejectionLogger struct {
+	interval time.Duration
+
+	mu           sync.Mutex
+	byController map[string]*rejectionSummary
+	pruned       time.Time
+}
+
+// newRejectionLogger returns a rejection logger for the args.
+func newRejectionLogger(args *ControllerSpreadArgs) *rejectionLogger {
+	return &rejectionLogger{interval: args.RejectionLogInterval.Duration, byController: make(map[string]*rejectionSummary)}
+}
+
+// record counts a node rejected for the pod, with the rejection message, and logs the summary of
+// the controller if its last summary is at least an interval old. Summaries are logged through the
+// logger of the scheduling cycle once the lock is released.
+func (l *rejectionLogger) record(logger klog.Logger, pod *v1.Pod, controller ControllerInfo, reason string, now time.Time) {
+	var reports []rejectionReport
+	l.mu.Lock()
+	summary, ok := l.byController[controller.UID]
+	if !ok {
+		summary = &rejectionSummary{controller: controller, namespace: pod.Namespace, pods: make(map[string]bool)}
+		l.byController[controller.UID] = summary
+	}
+	summary.rejections++
+	summary.pods[pod.Name] = true
+	summary.reason = reason
+	if now.Sub(summary.logged) >= l.interval {
+		reports = append(reports, summary.take(now))
+	}
+	if now.Sub(l.pruned) >= l.interval {
+		reports = l.pruneLocked(now, reports)
+	}
+	l.mu.Unlock()
+
+	for _, report := range reports {
+		report.log(logger)
+	}
+}
+
+// pruneLocked forgets the controllers without a summary for an interval, appending the reports of
+// the rejections counted since their last summary.
+func (l *rejectionLogger) pruneLocked(now time.Time, reports []rejectionReport) []rejectionReport {
+	for uid, summary := range l.byController {
+		if now.Sub(summary.logged) < l.interval {
+			continue
+		}
+		if summary.rejections > 0 {
+			reports = append(reports, summary.take(now))
+		}
+		delete(l.byController, uid)
+	}
+	l.pruned = now
+	return reports
+}
+
+// rejectionReport is a summary of the rejections of a controller, ready to be logged.
+type rejectionReport struct {
+	controller ControllerInfo
+	namespace  string
+	rejections int
+	pods       int
+	reason     string
+}
+
+// take returns the report of the rejections counted since the last summary and resets the counts.
+func (s *rejectionSummary) take(now time.Time) rejectionReport {
+	report := rejectionReport{controller: s.controller, namespace: s.namespace, rejections: s.rejections, pods: len(s.pods), reason: s.reason}
+	s.logged = now
+	s.rejections = 0
+	s.pods = make(map[string]bool)
+	return report
+}
+
+// log logs the report at verbosity 2.
+func (r rejectionReport) log(logger klog.Logger) {
+	logger.V(2).Info("Spread constraint rejected nodes", "controllerType", r.controller.Type, "controller", r.controller.Name,
+		"namespace", r.namespace, "rejections", r.rejections, "pods", r.pods, "lastReason", r.reason)
+}
@@ -0,0 +1,144 @@
+package controllerspread
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// summaryCounts is the part of a rejectionSummary the tests compare.
+type summaryCounts struct {
+	Rejections int
+	Pods       int
+	Logged     time.Duration
+}
+
+func countsOf(s *rejectionSummary, start time.Time) summaryCounts {
+	return summaryCounts{Rejections: s.rejections, Pods: len(s.pods), Logged: s.logged.Sub(start)}
+}
+
+// summaryLogger returns a logger counting the summaries logged through it.
+func summaryLogger(logged *int) klog.Logger {
+	return funcr.New(func(prefix, args string) { *logged++ }, funcr.Options{Verbosity: 2})
+}
+
+func TestRejectionLoggerRecord(t *testing.T) {
+	type rejection struct {
+		pod string
+		at  time.Duration
+	}
+	tests := []struct {
+		name       string
+		rejections []rejection
+		want       summaryCounts
+		wantLogged int
+	}{
+		{
+			name:       "first rejection logged right away",
+			rejections: []rejection{{pod: "web-new", at: 0}},
+			want:       summaryCounts{},
+			wantLogged: 1,
+		},
+		{
+			name: "rejections within the interval counted",
+			rejections: []rejection{
+				{pod: "web-new", at: 0},
+				{pod: "web-new", at: 10 * time.Second},
+				{pod: "web-other", at: 20 * time.Second},
+			},
+			want:       summaryCounts{Rejections: 2, Pods: 2},
+			wantLogged: 1,
+		},
+		{
+			name: "summary once the interval passed",
+			rejections: []rejection{
+				{pod: "web-new", at: 0},
+				{pod: "web-new", at: 10 * time.Second},
+				{pod: "web-new", at: time.Minute},
+			},
+			want:       summaryCounts{Logged: time.Minute},
+			wantLogged: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRejectionLogger(&ControllerSpreadArgs{RejectionLogInterval: metav1.Duration{Duration: time.Minute}})
+			controller := ControllerInfo{Type: DeploymentType, Name: "web", UID: "web"}
+			start := time.Now()
+			var logged int
+			for _, r := range tt.rejections {
+				l.record(summaryLogger(&logged), makePod(r.pod, "", ownerRef(ReplicaSetType, "web-hash")), controller, "rejected", start.Add(r.at))
+			}
+			if diff := cmp.Diff(tt.want, countsOf(l.byController["web"], start)); diff != "" {
+				t.Errorf("summary (-want,+got):\n%s", diff)
+			}
+			if logged != tt.wantLogged {
+				t.Errorf("logged %d summaries, want %d", logged, tt.wantLogged)
+			}
+		})
+	}
+}
+
+func TestRejectionLoggerPrune(t *testing.T) {
+	l := newRejectionLogger(&ControllerSpreadArgs{RejectionLogInterval: metav1.Duration{Duration: time.Minute}})
+	web := ControllerInfo{Type: DeploymentType, Name: "web", UID: "web"}
+	api := ControllerInfo{Type: DeploymentType, Name: "api", UID: "api"}
+	start := time.Now()
+	var logged int
+	logger := summaryLogger(&logged)
+
+	l.record(logger, makePod("api-new", "", ownerRef(ReplicaSetType, "api-hash")), api, "rejected", start)
+	l.record(logger, makePod("api-new", "", ownerRef(ReplicaSetType, "api-hash")), api, "rejected", start.Add(10*time.Second))
+	l.record(logger, makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash")), web, "rejected", start.Add(70*time.Second))
+
+	// api has no summary since start; its pending rejection is logged and it is forgotten.
+	if diff := cmp.Diff([]string{"web"}, slices.Sorted(maps.Keys(l.byController))); diff != "" {
+		t.Errorf("tracked controllers (-want,+got):\n%s", diff)
+	}
+	// The first api rejection, the first web rejection and the pruned api rejection.
+	if logged != 3 {
+		t.Errorf("logged %d summaries, want 3", logged)
+	}
+}
+
+func TestFilterRejectionLog(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		// want is the summary of web after Filter, or nil if no rejection was recorded.
+		want *summaryCounts
+	}{
+		{
+			name: "second rejection awaits the next summary",
+			want: &summaryCounts{Rejections: 1, Pods: 1},
+		},
+		{
+			name: "observe mode records no rejection",
+			args: ControllerSpreadArgs{Mode: ObserveMode},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+			filterNodes(t, p, pod)
+
+			var got *summaryCounts
+			if summary, ok := p.rejections.byController[string(testUID("web"))]; ok {
+				counts := countsOf(summary, summary.logged)
+				got = &counts
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("summary (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
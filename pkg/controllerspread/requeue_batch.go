@@ -0,0 +1,78 @@
+// pkg/controllerspread/requeue_batch.go
+//
+// Staggered requeueing of rejected peers. When the placement of a peer changes, every pod of the
+// controller that was rejected by the spread constraint is moved back to the scheduling queue at
+// once. For a large controller whose pods were rejected in a burst, they then retry together,
+// mostly to be rejected again, and each failed attempt lengthens their backoff. With
+// RequeueBatchSize set in the plugin args, a peer event queues at most that many of the
+// controller's rejected pods. The pods placed from a batch generate peer events of their own,
+// which queue the next batch, so the rejected pods are retried in waves. Pods left out wait for
+// the next peer or node event, or for the scheduler's periodic flush of unschedulable pods.
+package controllerspread
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// requeueBatchExpiry is how long the batch of a peer event is remembered.
+	requeueBatchExpiry = time.Minute
+)
+
+// requeueBatch counts the pods of a controller queued for a peer event.
+type requeueBatch struct {
+	event  string
+	queued int32
+	time   time.Time
+}
+
+// requeueBatcher limits the number of rejected pods of a controller queued per peer event.
+type requeueBatcher struct {
+	size int32
+
+	mu sync.Mutex
+	// batches are the latest batch of each controller, keyed by controller UID.
+	batches map[string]requeueBatch
+}
+
+// newRequeueBatcher returns a batcher for the args, or nil if RequeueBatchSize is not set.
+func newRequeueBatcher(args *ControllerSpreadArgs) *requeueBatcher {
+	if args.RequeueBatchSize == 0 {
+		return nil
+	}
+	return &requeueBatcher{size: args.RequeueBatchSize, batches: make(map[string]requeueBatch)}
+}
+
+// admit reports whether a rejected pod of the controller may be queued for the event of the
+// changed pod, i.e. whether fewer than size pods of the controller were queued for it.
+func (b *requeueBatcher) admit(controllerUID string, oldPod, newPod *v1.Pod, now time.Time) bool {
+	event := peerEventKey(oldPod, newPod)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch, ok := b.batches[controllerUID]
+	if !ok || batch.event != event {
+		for uid, expired := range b.batches {
+			if now.Sub(expired.time) > requeueBatchExpiry {
+				delete(b.batches, uid)
+			}
+		}
+		batch = requeueBatch{event: event, time: now}
+	}
+	if batch.queued >= b.size {
+		return false
+	}
+	batch.queued++
+	b.batches[controllerUID] = batch
+	return true
+}
+
+// peerEventKey identifies a pod event by the changed pod and its resource version.
+func peerEventKey(oldPod, newPod *v1.Pod) string {
+	if newPod == nil {
+		return string(oldPod.UID) + "/deleted"
+	}
+	return string(newPod.UID) + "/" + newPod.ResourceVersion
+}
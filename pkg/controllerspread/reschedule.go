@@ -0,0 +1,156 @@
+// pkg/controllerspread/reschedule.go
+//
+// Relaxed spread for pods replacing peers lost to a node failure. A pod never changes its node,
+// so a "rescheduled" pod is a new pod the controller creates to replace one that was deleted.
+// Pods are recognized as replacements through the DisruptionTarget condition the control plane
+// sets on the pods it deletes because their node failed: the taint manager deletes the pods of an
+// unreachable or not-ready node (reason DeletionByTaintManager), and the pod garbage collector
+// deletes the pods of a node that was removed (reason DeletionByPodGC). Each such deletion of a
+// bound pod is recorded for its controller for rescheduleWindow. With the
+// "controller-spread-scheduler/enforce-on-reschedule: false" annotation on the controller, that
+// many of its next pods are spread as preferred rather than required, so they may re-pack onto
+// the remaining nodes instead of staying pending. Deletions for scale-downs, rollouts or drains
+// carry no such condition and are not recorded.
+package controllerspread
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+const (
+	// Annotation key on the controller; "false" relaxes the spread of pods replacing peers lost
+	// to a node failure.
+	enforceOnRescheduleAnnotationKey = "controller-spread-scheduler/enforce-on-reschedule"
+
+	// rescheduleWindow is how long a lost peer may be replaced by a pod with a relaxed spread.
+	rescheduleWindow = 10 * time.Minute
+
+	// Reasons of the DisruptionTarget condition of pods deleted because their node failed.
+	deletionByTaintManager = "DeletionByTaintManager"
+	deletionByPodGC        = "DeletionByPodGC"
+)
+
+// lostPeers tracks the bound pods deleted for a reason that relaxes the spread of their
+// replacement, by controller UID: pods lost to a node failure, or evicted by a VPA (see vpa.go).
+type lostPeers struct {
+	logger  klog.Logger
+	resolve func(klog.Logger, *v1.Pod) (ControllerInfo, bool)
+	// lost reports whether a deleted pod is recorded.
+	lost func(*v1.Pod) bool
+	// window is how long a lost pod may be replaced by a pod with a relaxed spread.
+	window time.Duration
+
+	mu sync.Mutex
+	// byController are the expiry times of the unreplaced lost pods of each controller.
+	byController map[string][]time.Time
+}
+
+// newLostPeers returns a tracker fed by the delete events of the pod informer, recording the
+// bound pods for which lost reports true for window. resolve returns the controller of a deleted
+// pod.
+func newLostPeers(logger klog.Logger, podInformer cache.SharedIndexInformer, resolve func(klog.Logger, *v1.Pod) (ControllerInfo, bool), lost func(*v1.Pod) bool, window time.Duration) *lostPeers {
+	l := &lostPeers{logger: logger, resolve: resolve, lost: lost, window: window, byController: make(map[string][]time.Time)}
+	_, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{DeleteFunc: l.delete})
+	if err != nil {
+		logger.Error(err, "Failed to add lost peer event handler")
+	}
+	return l
+}
+
+// delete records the pod from an informer delete event if it was bound to a node and lost
+// reports it.
+func (l *lostPeers) delete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Spec.NodeName == "" || !l.lost(pod) {
+		return
+	}
+	controller, ok := l.resolve(l.logger, pod)
+	if !ok {
+		return
+	}
+	l.logger.V(4).Info("Recording lost pod to relax the spread of its replacement", "pod", klog.KObj(pod), "node", pod.Spec.NodeName, "controller", controller.Name)
+	l.add(controller.UID, time.Now())
+}
+
+// lostToNodeFailure reports whether the pod carries the DisruptionTarget condition of a deletion
+// caused by a failed node.
+func lostToNodeFailure(pod *v1.Pod) bool {
+	_, condition := podutil.GetPodCondition(&pod.Status, v1.DisruptionTarget)
+	if condition == nil || condition.Status != v1.ConditionTrue {
+		return false
+	}
+	return condition.Reason == deletionByTaintManager || condition.Reason == deletionByPodGC
+}
+
+// add records a lost pod of the controller.
+func (l *lostPeers) add(controllerUID string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byController[controllerUID] = append(l.byController[controllerUID], now.Add(l.window))
+}
+
+// pending reports whether the controller has a lost pod that has not been replaced and has not
+// expired. Expired records are forgotten.
+func (l *lostPeers) pending(controllerUID string, now time.Time) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expireLocked(controllerUID, now)
+	return len(l.byController[controllerUID]) > 0
+}
+
+// replace marks the oldest pending lost pod of the controller as replaced.
+func (l *lostPeers) replace(controllerUID string, now time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expireLocked(controllerUID, now)
+	if expires := l.byController[controllerUID]; len(expires) > 0 {
+		l.byController[controllerUID] = expires[1:]
+	}
+	if len(l.byController[controllerUID]) == 0 {
+		delete(l.byController, controllerUID)
+	}
+}
+
+// expireLocked forgets the expired lost pods of the controller.
+func (l *lostPeers) expireLocked(controllerUID string, now time.Time) {
+	expires := l.byController[controllerUID]
+	for len(expires) > 0 && now.After(expires[0]) {
+		expires = expires[1:]
+	}
+	if len(expires) == 0 {
+		delete(l.byController, controllerUID)
+		return
+	}
+	l.byController[controllerUID] = expires
+}
+
+// enforcesOnReschedule reports whether the controller's spread is enforced for pods replacing
+// peers lost to a node failure. Values that are not a valid bool are logged and ignored.
+func enforcesOnReschedule(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	val, exists := annotations[enforceOnRescheduleAnnotationKey]
+	if !exists {
+		return true
+	}
+	enforce, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", enforceOnRescheduleAnnotationKey, "value", val, "controller", controller.Name)
+		return true
+	}
+	return enforce
+}
@@ -0,0 +1,230 @@
+package controllerspread
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// withDisruptionTarget returns the pod with a DisruptionTarget condition of the given status and
+// reason.
+func withDisruptionTarget(pod *v1.Pod, status v1.ConditionStatus, reason string) *v1.Pod {
+	pod.Status.Conditions = append(pod.Status.Conditions, v1.PodCondition{Type: v1.DisruptionTarget, Status: status, Reason: reason})
+	return pod
+}
+
+func TestLostToNodeFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want bool
+	}{
+		{
+			name: "no condition",
+			pod:  makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")),
+		},
+		{
+			name: "deleted by taint manager",
+			pod:  withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, deletionByTaintManager),
+			want: true,
+		},
+		{
+			name: "deleted by pod GC",
+			pod:  withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, deletionByPodGC),
+			want: true,
+		},
+		{
+			name: "evicted",
+			pod:  withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, evictionByEvictionAPI),
+		},
+		{
+			name: "condition false",
+			pod:  withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionFalse, deletionByTaintManager),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lostToNodeFailure(tt.pod); got != tt.want {
+				t.Errorf("lostToNodeFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLostPeersDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  interface{}
+		want bool
+	}{
+		{
+			name: "bound pod lost to node failure",
+			obj:  withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, deletionByTaintManager),
+			want: true,
+		},
+		{
+			name: "tombstone of pod lost to node failure",
+			obj: cache.DeletedFinalStateUnknown{Key: "default/web-0",
+				Obj: withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, deletionByPodGC)},
+			want: true,
+		},
+		{
+			name: "unbound pod",
+			obj:  withDisruptionTarget(makePod("web-0", "", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, deletionByTaintManager),
+		},
+		{
+			name: "pod deleted by a scale-down",
+			obj:  makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")),
+		},
+		{
+			name: "pod of unknown controller",
+			obj:  withDisruptionTarget(makePod("api-0", "node-a", ownerRef(ReplicaSetType, "api-hash")), v1.ConditionTrue, deletionByTaintManager),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 2, nil))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, makeNodes("node-a"), objs...)
+
+			p.lostPeers.delete(tt.obj)
+			if got := p.lostPeers.pending(string(testUID("web")), time.Now()); got != tt.want {
+				t.Errorf("pending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLostPeersReplace(t *testing.T) {
+	now := time.Now()
+	l := &lostPeers{window: rescheduleWindow, byController: make(map[string][]time.Time)}
+	l.add("web", now.Add(-rescheduleWindow-time.Minute))
+	l.add("web", now)
+	l.add("web", now)
+
+	// The expired record is forgotten, so two replacements are pending.
+	for i := 0; i < 2; i++ {
+		if !l.pending("web", now) {
+			t.Fatalf("pending() after %d replacements = false, want true", i)
+		}
+		l.replace("web", now)
+	}
+	if l.pending("web", now) {
+		t.Errorf("pending() after all replacements = true, want false")
+	}
+	if diff := cmp.Diff(map[string][]time.Time{}, l.byController); diff != "" {
+		t.Errorf("byController (-want,+got):\n%s", diff)
+	}
+
+	l.add("web", now)
+	if l.pending("web", now.Add(rescheduleWindow+time.Second)) {
+		t.Errorf("pending() after rescheduleWindow = true, want false")
+	}
+}
+
+func TestEnforcesOnReschedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "no annotation", want: true},
+		{name: "false", annotations: map[string]string{enforceOnRescheduleAnnotationKey: "false"}},
+		{name: "true", annotations: map[string]string{enforceOnRescheduleAnnotationKey: "true"}, want: true},
+		{name: "invalid", annotations: map[string]string{enforceOnRescheduleAnnotationKey: "sometimes"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enforcesOnReschedule(klog.Background(), tt.annotations, ControllerInfo{Name: "web"}); got != tt.want {
+				t.Errorf("enforcesOnReschedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterEnforceOnReschedule(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name    string
+		enforce string
+		// lost records a lost peer of the controller with the given UID at lostAt.
+		lost   string
+		lostAt time.Duration
+		want   []string
+	}{
+		{
+			name:    "no lost peer",
+			enforce: "false",
+			want:    []string{"node-c"},
+		},
+		{
+			name: "lost peer, spread enforced",
+			lost: "web",
+			want: []string{"node-c"},
+		},
+		{
+			name:    "lost peer, spread relaxed",
+			enforce: "false",
+			lost:    "web",
+			want:    []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:    "lost peer of another controller",
+			enforce: "false",
+			lost:    "api",
+			want:    []string{"node-c"},
+		},
+		{
+			name:    "lost peer expired",
+			enforce: "false",
+			lost:    "web",
+			lostAt:  -rescheduleWindow - time.Minute,
+			want:    []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{minHostsAnnotationKey: "3"}
+			if tt.enforce != "" {
+				annotations[enforceOnRescheduleAnnotationKey] = tt.enforce
+			}
+			objs := makeDeploymentPods(makeDeployment("web", 3, annotations), "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+			if tt.lost != "" {
+				p.lostPeers.add(string(testUID(tt.lost)), time.Now().Add(tt.lostAt))
+			}
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReserveReplacesLostPeer(t *testing.T) {
+	annotations := map[string]string{minHostsAnnotationKey: "3", enforceOnRescheduleAnnotationKey: "false"}
+	objs := makeDeploymentPods(makeDeployment("web", 3, annotations), "node-a", "node-b")
+	pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+	p := newTestPlugin(t, &ControllerSpreadArgs{}, makeNodes("node-a", "node-b", "node-c"), append(objs, pod)...)
+	uid := string(testUID("web"))
+	p.lostPeers.add(uid, time.Now())
+
+	state, status := preFilter(t, p, pod)
+	if !status.IsSuccess() {
+		t.Fatalf("PreFilter: %v", status)
+	}
+	if s := p.Reserve(t.Context(), state, pod, "node-a"); !s.IsSuccess() {
+		t.Fatalf("Reserve: %v", s)
+	}
+	if p.lostPeers.pending(uid, time.Now()) {
+		t.Errorf("pending() after Reserve = true, want false")
+	}
+	p.Unreserve(t.Context(), state, pod, "node-a")
+	if !p.lostPeers.pending(uid, time.Now()) {
+		t.Errorf("pending() after Unreserve = false, want true")
+	}
+}
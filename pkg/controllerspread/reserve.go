@@ -0,0 +1,143 @@
+// pkg/controllerspread/reserve.go
+//
+// Reserve extension point for ControllerSpreadFilter. Pods that have been assigned a node but
+// are not yet visible as bound in the informer cache are tracked in memory, so that two pods of
+// the same controller scheduled in quick succession do not both pass against a stale view.
+package controllerspread
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// assumedPodTTL is how long an assumed placement is kept if Unreserve is never called and
+	// the pod never shows up as bound in the informer cache.
+	assumedPodTTL = 30 * time.Second
+)
+
+var _ framework.ReservePlugin = &ControllerSpreadFilter{}
+
+// assumedPlacement is an in-flight placement of a pod of a controller on a node.
+type assumedPlacement struct {
+	// groupKey identifies the peers of the pod, see controllerSpreadState.groupKey.
+	groupKey string
+	nodeName string
+	expires  time.Time
+}
+
+// assumedPods tracks in-flight placements keyed by pod UID.
+type assumedPods struct {
+	mu         sync.Mutex
+	placements map[types.UID]assumedPlacement
+}
+
+// newAssumedPods returns an empty assumedPods.
+func newAssumedPods() *assumedPods {
+	return &assumedPods{placements: make(map[types.UID]assumedPlacement)}
+}
+
+// add records that the pod of the group was assumed onto the node.
+func (a *assumedPods) add(groupKey string, podUID types.UID, nodeName string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.placements[podUID] = assumedPlacement{groupKey: groupKey, nodeName: nodeName, expires: now.Add(assumedPodTTL)}
+}
+
+// remove forgets the assumed placement of the pod, if any.
+func (a *assumedPods) remove(podUID types.UID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.placements, podUID)
+}
+
+// has reports whether the pod has an assumed placement that has not expired.
+func (a *assumedPods) has(podUID types.UID, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	placement, ok := a.placements[podUID]
+	return ok && !now.After(placement.expires)
+}
+
+// addToNodeCounts adds the assumed placements of the group to nodeCounts. Placements of
+// pods that are already bound according to the peer placements are forgotten, as are expired
+// placements. The assumed placement of a nominated pod replaces its count on the nominated node.
+func (a *assumedPods) addToNodeCounts(groupKey string, peers map[types.UID]peerPlacement, excludeUID types.UID, nodeCounts map[string]int, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for podUID, placement := range a.placements {
+		peer, known := peers[podUID]
+		if now.After(placement.expires) || known && peer.bound {
+			delete(a.placements, podUID)
+			continue
+		}
+		if placement.groupKey != groupKey || podUID == excludeUID {
+			continue
+		}
+		if known && nodeCounts[peer.nodeName] > 0 {
+			// The pod is nominated to peer.nodeName; the assumed placement supersedes it.
+			nodeCounts[peer.nodeName]--
+			if nodeCounts[peer.nodeName] == 0 {
+				delete(nodeCounts, peer.nodeName)
+			}
+		}
+		nodeCounts[placement.nodeName]++
+	}
+}
+
+// hasGroup reports whether the group has assumed placements that have not expired.
+func (a *assumedPods) hasGroup(groupKey string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, placement := range a.placements {
+		if placement.groupKey == groupKey && !now.After(placement.expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserve records the placement of the pod until it is visible as bound in the informer cache.
+func (csf *ControllerSpreadFilter) Reserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		// PreFilter skipped the pod, so it is not subject to the spread constraint.
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+	csf.assumed.add(s.groupKey, pod.UID, nodeName, time.Now())
+	logger.V(5).Info("Assuming placement of pod until it is bound", "pod", klog.KObj(pod), "node", nodeName, "controller", s.controller.Name)
+	if s.reschedule {
+		csf.lostPeers.replace(s.controller.UID, time.Now())
+		logger.V(4).Info("Pod replaces a peer lost to a node failure", "pod", klog.KObj(pod), "controller", s.controller.Name)
+	}
+	if s.vpaReplacement {
+		csf.vpaEvictions.replace(s.controller.UID, time.Now())
+		logger.V(4).Info("Pod replaces a peer evicted by a VerticalPodAutoscaler", "pod", klog.KObj(pod), "controller", s.controller.Name)
+	}
+	return nil
+}
+
+// Unreserve rolls back the placement recorded by Reserve.
+func (csf *ControllerSpreadFilter) Unreserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) {
+	csf.assumed.remove(pod.UID)
+	if s, err := getPreFilterState(cycleState); err == nil {
+		// The lost or evicted peer is not replaced after all.
+		if s.reschedule {
+			csf.lostPeers.add(s.controller.UID, time.Now())
+		}
+		if s.vpaReplacement {
+			csf.vpaEvictions.add(s.controller.UID, time.Now())
+		}
+	}
+	if csf.binds != nil {
+		// The pod was rejected in or after Permit; free its bind slot or its place in the queue.
+		csf.binds.release(pod.UID, csf.handle, csf.Name())
+	}
+}
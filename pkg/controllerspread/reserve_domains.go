@@ -0,0 +1,66 @@
+// pkg/controllerspread/reserve_domains.go
+//
+// Free-domain reservation for ControllerSpreadFilter. With the
+// "controller-spread-scheduler/reserve-domains" annotation on the controller, the spread is
+// checked by the domains it leaves free rather than by the domains it occupies: a pod may only
+// open a new domain at the finest level if at least that many eligible domains without a peer
+// remain for the controller's pods that are not placed yet. Placements in domains that already
+// run a peer are always accepted. The annotation replaces the min-hosts and min-zones minimums
+// for the controller.
+package controllerspread
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key for the number of free domains to keep for the controller's unplaced pods.
+	reserveDomainsAnnotationKey = "controller-spread-scheduler/reserve-domains"
+)
+
+// parseReserveDomainsAnnotation returns the reserve-domains annotation value, or 0 (occupied
+// domains are counted instead) if it is absent or not a positive integer.
+func parseReserveDomainsAnnotation(logger klog.Logger, annotations map[string]string, controller ControllerInfo) int32 {
+	val, exists := annotations[reserveDomainsAnnotationKey]
+	if !exists {
+		return 0
+	}
+	parsed, ok := parseMaxPodsPerNodeAnnotation(val)
+	if !ok {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", reserveDomainsAnnotationKey, "value", val, "controller", controller.Name)
+		return 0
+	}
+	return parsed
+}
+
+// reserveDomainsDecision decides on a placement in the candidate domain of the finest level under
+// reserve-domains. The reservation is capped at the pods still unplaced after this one, so the
+// last pods may take the last free domains.
+func reserveDomainsDecision(s *controllerSpreadState, candidateDomain string) Decision {
+	level := s.levels[len(s.levels)-1]
+	if _, occupied := level.domainCounts[candidateDomain]; occupied {
+		return Decision{Allowed: true}
+	}
+	unplaced := s.desired - int32(s.scheduledPeers) - 1
+	required := min(s.reserveDomains, unplaced)
+	if required <= 0 {
+		return Decision{Allowed: true}
+	}
+	var free int32
+	for domain := range level.eligibleDomains {
+		if _, occupied := level.domainCounts[domain]; !occupied && domain != candidateDomain {
+			free++
+		}
+	}
+	if free >= required {
+		return Decision{Allowed: true}
+	}
+	decision := Decision{Reason: fmt.Sprintf("must leave at least %d free nodes for the remaining %d pods", required, unplaced),
+		Rule: RuleReserveDomains, TopologyKey: level.key, Required: int(required), Current: int(free)}
+	if level.key != defaultTopologyKey {
+		decision.Reason = fmt.Sprintf("must leave at least %d free %s domains for the remaining %d pods", required, level.key, unplaced)
+	}
+	return decision
+}
@@ -0,0 +1,143 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+func TestParseReserveDomainsAnnotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int32
+	}{
+		{name: "no annotation"},
+		{name: "valid", annotations: map[string]string{reserveDomainsAnnotationKey: "2"}, want: 2},
+		{name: "zero", annotations: map[string]string{reserveDomainsAnnotationKey: "0"}},
+		{name: "not a number", annotations: map[string]string{reserveDomainsAnnotationKey: "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseReserveDomainsAnnotation(klog.Background(), tt.annotations, ControllerInfo{Name: "web"}); got != tt.want {
+				t.Errorf("parseReserveDomainsAnnotation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveDomainsDecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		desired   int32
+		candidate string
+		want      Decision
+	}{
+		{
+			name:      "occupied domain",
+			key:       defaultTopologyKey,
+			desired:   4,
+			candidate: "node-a",
+			want:      Decision{Allowed: true},
+		},
+		{
+			name:      "enough free domains left",
+			key:       defaultTopologyKey,
+			desired:   3,
+			candidate: "node-b",
+			want:      Decision{Allowed: true},
+		},
+		{
+			name:      "too few free domains left",
+			key:       defaultTopologyKey,
+			desired:   4,
+			candidate: "node-b",
+			want: Decision{Reason: "must leave at least 2 free nodes for the remaining 2 pods",
+				Rule: RuleReserveDomains, TopologyKey: defaultTopologyKey, Required: 2, Current: 1},
+		},
+		{
+			name:      "too few free zones left",
+			key:       v1.LabelTopologyZone,
+			desired:   4,
+			candidate: "node-b",
+			want: Decision{Reason: "must leave at least 2 free topology.kubernetes.io/zone domains for the remaining 2 pods",
+				Rule: RuleReserveDomains, TopologyKey: v1.LabelTopologyZone, Required: 2, Current: 1},
+		},
+		{
+			name:      "last pod",
+			key:       defaultTopologyKey,
+			desired:   2,
+			candidate: "node-b",
+			want:      Decision{Allowed: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &controllerSpreadState{
+				levels: []topologyLevel{{
+					key:             tt.key,
+					domainCounts:    map[string]int{"node-a": 1},
+					eligibleDomains: map[string]bool{"node-a": true, "node-b": true, "node-c": true},
+				}},
+				scheduledPeers: 1,
+				desired:        tt.desired,
+				reserveDomains: 2,
+			}
+			if diff := cmp.Diff(tt.want, reserveDomainsDecision(s, tt.candidate)); diff != "" {
+				t.Errorf("reserveDomainsDecision() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterReserveDomains(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		peerNodes   []string
+		want        []string
+	}{
+		{
+			name:      "min-hosts without reserve-domains",
+			peerNodes: []string{"node-a"},
+			want:      []string{"node-b", "node-c"},
+		},
+		{
+			name:        "new domain would leave too few free domains",
+			annotations: map[string]string{reserveDomainsAnnotationKey: "2"},
+			peerNodes:   []string{"node-a"},
+			want:        []string{"node-a"},
+		},
+		{
+			name:        "enough free domains left",
+			annotations: map[string]string{reserveDomainsAnnotationKey: "1"},
+			peerNodes:   []string{"node-a"},
+			want:        []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:        "first placement constrained",
+			annotations: map[string]string{reserveDomainsAnnotationKey: "3"},
+		},
+		{
+			name:        "invalid annotation ignored",
+			annotations: map[string]string{reserveDomainsAnnotationKey: "all"},
+			peerNodes:   []string{"node-a"},
+			want:        []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 4, tt.annotations), tt.peerNodes...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package controllerspread
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestAssumedPodsAddToNodeCounts(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		// assumedAt is when web-1 was assumed onto node-c.
+		assumedAt time.Time
+		peers     map[types.UID]peerPlacement
+		want      map[string]int
+		// wantKept reports whether the assumed placement is still tracked afterwards.
+		wantKept bool
+	}{
+		{
+			name:      "assumed pod not yet listed",
+			assumedAt: now,
+			peers:     map[types.UID]peerPlacement{testUID("web-0"): {nodeName: "node-a", bound: true}},
+			want:      map[string]int{"node-a": 1, "node-c": 1},
+			wantKept:  true,
+		},
+		{
+			name:      "assumed pod nominated to another node",
+			assumedAt: now,
+			peers: map[types.UID]peerPlacement{
+				testUID("web-0"): {nodeName: "node-a", bound: true},
+				testUID("web-1"): {nodeName: "node-b"},
+			},
+			want:     map[string]int{"node-a": 1, "node-c": 1},
+			wantKept: true,
+		},
+		{
+			name:      "assumed pod bound",
+			assumedAt: now,
+			peers: map[types.UID]peerPlacement{
+				testUID("web-0"): {nodeName: "node-a", bound: true},
+				testUID("web-1"): {nodeName: "node-c", bound: true},
+			},
+			want: map[string]int{"node-a": 1, "node-c": 1},
+		},
+		{
+			name:      "assumed placement expired",
+			assumedAt: now.Add(-assumedPodTTL - time.Second),
+			peers:     map[types.UID]peerPlacement{testUID("web-0"): {nodeName: "node-a", bound: true}},
+			want:      map[string]int{"node-a": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAssumedPods()
+			a.add("web", testUID("web-1"), "node-c", tt.assumedAt)
+			a.add("api", testUID("api-0"), "node-b", now)
+			nodeCounts := countPlacements(tt.peers)
+
+			a.addToNodeCounts("web", tt.peers, testUID("web-new"), nodeCounts, now)
+			if diff := cmp.Diff(tt.want, nodeCounts); diff != "" {
+				t.Errorf("node counts (-want,+got):\n%s", diff)
+			}
+			if got := a.has(testUID("web-1"), now); got != tt.wantKept {
+				t.Errorf("has(web-1) = %v, want %v", got, tt.wantKept)
+			}
+		})
+	}
+}
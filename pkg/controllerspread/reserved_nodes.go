@@ -0,0 +1,58 @@
+// pkg/controllerspread/reserved_nodes.go
+//
+// Nodes reserved for a tenant. With ReservedNodeAnnotation set in the plugin args, a node whose
+// annotation of that key has a non-empty value is reserved for the tenant it names. A pod's
+// tenant is the value of its TenantLabel label. Nodes reserved for a different tenant, or for any
+// tenant if the pod has none, are not spread domains for the pod: peers on them are not counted,
+// their domains are not eligible, and Filter rejects them as candidates.
+package controllerspread
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// tenantOf returns the tenant of the pod, or "" if it has none.
+func (csf *ControllerSpreadFilter) tenantOf(pod *v1.Pod) string {
+	if csf.args.TenantLabel == "" {
+		return ""
+	}
+	return pod.Labels[csf.args.TenantLabel]
+}
+
+// reservedForOtherTenant returns the tenant the node is reserved for if it is not the given one.
+func (csf *ControllerSpreadFilter) reservedForOtherTenant(node *v1.Node, tenant string) (string, bool) {
+	if csf.args.ReservedNodeAnnotation == "" {
+		return "", false
+	}
+	reservedFor := node.Annotations[csf.args.ReservedNodeAnnotation]
+	return reservedFor, reservedFor != "" && reservedFor != tenant
+}
+
+// withoutReservedNodes removes the nodes reserved for another tenant from the per-node pod counts.
+// Nodes that cannot be resolved through the node lister are kept.
+func (csf *ControllerSpreadFilter) withoutReservedNodes(nodeCounts map[string]int, tenant string) map[string]int {
+	if csf.args.ReservedNodeAnnotation == "" {
+		return nodeCounts
+	}
+	for nodeName := range nodeCounts {
+		node, err := csf.nodeLister.Get(nodeName)
+		if err != nil {
+			continue
+		}
+		if _, reserved := csf.reservedForOtherTenant(node, tenant); reserved {
+			delete(nodeCounts, nodeName)
+		}
+	}
+	return nodeCounts
+}
+
+// filterReservedNode rejects the node if it is reserved for another tenant than the pod's.
+func (csf *ControllerSpreadFilter) filterReservedNode(pod *v1.Pod, node *v1.Node) *framework.Status {
+	if reservedFor, reserved := csf.reservedForOtherTenant(node, csf.tenantOf(pod)); reserved {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("node is reserved for tenant %q", reservedFor))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterReservedNodes(t *testing.T) {
+	const tenantLabel = "example.com/tenant"
+	reserved := makeNode("node-r", nil)
+	reserved.Annotations = map[string]string{"example.com/reserved-for": "blue"}
+	nodes := []*v1.Node{makeNode("node-a", nil), makeNode("node-b", nil), makeNode("node-c", nil), reserved}
+	reservation := ControllerSpreadArgs{ReservedNodeAnnotation: "example.com/reserved-for", TenantLabel: tenantLabel}
+	tests := []struct {
+		name     string
+		args     ControllerSpreadArgs
+		tenant   string
+		minHosts string
+		peers    []string
+		want     []string
+	}{
+		{
+			name:     "no reservations",
+			tenant:   "red",
+			minHosts: "3",
+			peers:    []string{"node-a", "node-b"},
+			want:     []string{"node-c", "node-r"},
+		},
+		{
+			name:     "node reserved for the pod's tenant",
+			args:     reservation,
+			tenant:   "blue",
+			minHosts: "3",
+			peers:    []string{"node-a", "node-b"},
+			want:     []string{"node-c", "node-r"},
+		},
+		{
+			name:     "node reserved for another tenant",
+			args:     reservation,
+			tenant:   "red",
+			minHosts: "3",
+			peers:    []string{"node-a", "node-b"},
+			want:     []string{"node-c"},
+		},
+		{
+			name:     "pod without a tenant",
+			args:     reservation,
+			minHosts: "3",
+			peers:    []string{"node-a", "node-b"},
+			want:     []string{"node-c"},
+		},
+		{
+			name:     "peer on a node reserved for another tenant",
+			args:     reservation,
+			tenant:   "red",
+			minHosts: "2",
+			peers:    []string{"node-a", "node-r"},
+			want:     []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: tt.minHosts}), tt.peers...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			if tt.tenant != "" {
+				pod.Labels = map[string]string{tenantLabel: tt.tenant}
+			}
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// pkg/controllerspread/resource_scope.go
+//
+// Spreading scoped to a resource, e.g. for GPU workloads. With RequireResource set in the plugin
+// args, e.g. to "nvidia.com/gpu", only pods whose containers request the resource are spread,
+// and only the peers that request it are counted, so CPU-only pods of the same controller neither
+// consume the spread budget nor are held to it.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// requestsResource reports whether any container of the pod requests a non-zero quantity of
+// the resource.
+func requestsResource(pod *v1.Pod, resource v1.ResourceName) bool {
+	for _, c := range pod.Spec.Containers {
+		if quantity, ok := c.Resources.Requests[resource]; ok && !quantity.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// outOfResourceScope reports whether the pod is exempt from spreading because RequireResource is
+// set and the pod does not request it.
+func (csf *ControllerSpreadFilter) outOfResourceScope(pod *v1.Pod) bool {
+	return csf.args.RequireResource != "" && !requestsResource(pod, v1.ResourceName(csf.args.RequireResource))
+}
+
+// withRequiredResource returns the pods that request the RequireResource, or all pods if it is
+// not set.
+func (csf *ControllerSpreadFilter) withRequiredResource(pods []*v1.Pod) []*v1.Pod {
+	if csf.args.RequireResource == "" {
+		return pods
+	}
+	var result []*v1.Pod
+	for _, p := range pods {
+		if requestsResource(p, v1.ResourceName(csf.args.RequireResource)) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
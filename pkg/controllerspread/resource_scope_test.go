@@ -0,0 +1,98 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const gpuResource = "nvidia.com/gpu"
+
+// withRequest sets a request of the quantity of the resource on the pod's container and returns
+// the pod.
+func withRequest(pod *v1.Pod, resourceName v1.ResourceName, quantity string) *v1.Pod {
+	pod.Spec.Containers = []v1.Container{{
+		Name:      "main",
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{resourceName: resource.MustParse(quantity)}},
+	}}
+	return pod
+}
+
+func TestRequestsResource(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want bool
+	}{
+		{
+			name: "no containers",
+			pod:  makePod("web-0", "", ownerRef(ReplicaSetType, "web-hash")),
+		},
+		{
+			name: "resource requested",
+			pod:  withRequest(makePod("web-0", "", ownerRef(ReplicaSetType, "web-hash")), gpuResource, "1"),
+			want: true,
+		},
+		{
+			name: "zero quantity requested",
+			pod:  withRequest(makePod("web-0", "", ownerRef(ReplicaSetType, "web-hash")), gpuResource, "0"),
+		},
+		{
+			name: "other resource requested",
+			pod:  withRequest(makePod("web-0", "", ownerRef(ReplicaSetType, "web-hash")), v1.ResourceCPU, "1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestsResource(tt.pod, gpuResource); got != tt.want {
+				t.Errorf("requestsResource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterRequireResource(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		// podResource is the resource the pod being scheduled requests.
+		podResource v1.ResourceName
+		want        []string
+	}{
+		{
+			name:        "all pods spread",
+			podResource: v1.ResourceCPU,
+			want:        []string{"node-c"},
+		},
+		{
+			name:        "GPU pod spread among GPU peers",
+			args:        ControllerSpreadArgs{RequireResource: gpuResource},
+			podResource: gpuResource,
+			want:        []string{"node-b", "node-c"},
+		},
+		{
+			name:        "CPU pod exempt",
+			args:        ControllerSpreadArgs{RequireResource: gpuResource},
+			podResource: v1.ResourceCPU,
+			want:        []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := ownerRef(ReplicaSetType, "web-hash")
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}))
+			objs = append(objs,
+				withRequest(makePod("web-0", "node-a", owner), gpuResource, "1"),
+				withRequest(makePod("web-1", "node-b", owner), v1.ResourceCPU, "1"))
+			pod := withRequest(makePod("web-new", "", owner), tt.podResource, "1")
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// pkg/controllerspread/revision_scope.go
+//
+// Per-revision spreading for Deployments. Pods of a Deployment are normally spread across all of
+// its ReplicaSets. With the "controller-spread-scheduler/spread-per-revision" annotation on the
+// Deployment, each revision (e.g. canary and stable) is spread independently: the peers of a
+// pod are the pods with the same pod-template-hash, and its desired count is the replica count
+// of its ReplicaSet.
+package controllerspread
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key on a Deployment that spreads each of its revisions independently.
+	spreadPerRevisionAnnotationKey = "controller-spread-scheduler/spread-per-revision"
+)
+
+// revisionOf returns the pod-template-hash of the pod and the desired replica count of its
+// ReplicaSet if the pod's Deployment spreads per revision.
+func (csf *ControllerSpreadFilter) revisionOf(logger klog.Logger, pod *v1.Pod, controller ControllerInfo, annotations map[string]string) (string, int32, bool) {
+	if controller.Type != DeploymentType || !isSpreadPerRevision(logger, annotations, controller) {
+		return "", 0, false
+	}
+	hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	if !ok {
+		return "", 0, false
+	}
+	ownerRef := metav1.GetControllerOf(pod)
+	if ownerRef == nil || !isBuiltinOwner(*ownerRef, ReplicaSetType) {
+		return "", 0, false
+	}
+	rs, err := csf.rsLister.ReplicaSets(pod.Namespace).Get(ownerRef.Name)
+	if err != nil || rs.UID != ownerRef.UID {
+		return "", 0, false
+	}
+	desired := int32(1)
+	if rs.Spec.Replicas != nil {
+		desired = *rs.Spec.Replicas
+	}
+	return hash, desired, true
+}
+
+// isSpreadPerRevision reports whether the Deployment spreads each revision independently through
+// its spread-per-revision annotation. Values that are not a valid bool are logged and ignored.
+func isSpreadPerRevision(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	val, exists := annotations[spreadPerRevisionAnnotationKey]
+	if !exists {
+		return false
+	}
+	perRevision, err := strconv.ParseBool(val)
+	if err != nil {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", spreadPerRevisionAnnotationKey, "value", val, "controller", controller.Name)
+		return false
+	}
+	return perRevision
+}
+
+// withPodTemplateHash returns the pods with the given pod-template-hash.
+func withPodTemplateHash(pods []*v1.Pod, hash string) []*v1.Pod {
+	var result []*v1.Pod
+	for _, p := range pods {
+		if p.Labels[appsv1.DefaultDeploymentUniqueLabelKey] == hash {
+			result = append(result, p)
+		}
+	}
+	return result
+}
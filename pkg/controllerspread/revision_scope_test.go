@@ -0,0 +1,115 @@
+package controllerspread
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+// makeRevision returns a ReplicaSet of the Deployment with the pod-template-hash and replicas,
+// and its pods on the nodes.
+func makeRevision(deploy *appsv1.Deployment, hash string, replicas int32, nodeNames ...string) []runtime.Object {
+	rs := makeReplicaSet(deploy)
+	rs.Name = deploy.Name + "-" + hash
+	rs.UID = testUID(rs.Name)
+	rs.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: hash}
+	rs.Spec.Replicas = ptr.To(replicas)
+	objs := []runtime.Object{rs}
+	for i, nodeName := range nodeNames {
+		objs = append(objs, makeRevisionPod(rs, i, nodeName))
+	}
+	return objs
+}
+
+// makeRevisionPod returns the pod of the ordinal of the ReplicaSet on the node.
+func makeRevisionPod(rs *appsv1.ReplicaSet, i int, nodeName string) *v1.Pod {
+	pod := makePod(fmt.Sprintf("%s-%d", rs.Name, i), nodeName, ownerRef(ReplicaSetType, rs.Name))
+	pod.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]}
+	return pod
+}
+
+func TestSpreadPerRevision(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c", "node-d")
+	tests := []struct {
+		name        string
+		perRevision string
+		want        []string
+	}{
+		{
+			name: "revisions spread together",
+			want: []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+		{
+			name:        "revisions spread separately",
+			perRevision: "true",
+			want:        []string{"node-b", "node-c", "node-d"},
+		},
+		{
+			name:        "invalid annotation",
+			perRevision: "canary",
+			want:        []string{"node-a", "node-b", "node-c", "node-d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{minHostsAnnotationKey: "2"}
+			if tt.perRevision != "" {
+				annotations[spreadPerRevisionAnnotationKey] = tt.perRevision
+			}
+			deploy := makeDeployment("web", 5, annotations)
+			objs := []runtime.Object{deploy}
+			objs = append(objs, makeRevision(deploy, "stable", 3, "node-a", "node-b")...)
+			canary := makeRevision(deploy, "canary", 2, "node-a")
+			objs = append(objs, canary...)
+			pod := makeRevisionPod(canary[0].(*appsv1.ReplicaSet), 1, "")
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithPodTemplateHash(t *testing.T) {
+	deploy := makeDeployment("web", 3, nil)
+	stable := makeRevision(deploy, "stable", 2, "node-a", "node-b")
+	canary := makeRevision(deploy, "canary", 1, "node-c")
+	pods := []*v1.Pod{stable[1].(*v1.Pod), stable[2].(*v1.Pod), canary[1].(*v1.Pod)}
+	tests := []struct {
+		name string
+		hash string
+		want []string
+	}{
+		{
+			name: "stable revision",
+			hash: "stable",
+			want: []string{"web-stable-0", "web-stable-1"},
+		},
+		{
+			name: "canary revision",
+			hash: "canary",
+			want: []string{"web-canary-0"},
+		},
+		{
+			name: "unknown revision",
+			hash: "gone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range withPodTemplateHash(pods, tt.hash) {
+				got = append(got, p.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withPodTemplateHash() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// pkg/controllerspread/scale_controllers.go
+//
+// Generic support for scalable CRDs through the scale subresource. Each group resource in the
+// ScaleGroupResources plugin arg, e.g. "rollouts.argoproj.io", is resolved to its kind through API
+// discovery when the plugin starts, and pods owned by an object of that kind are spread like those
+// of a custom controller. The desired replica count is spec.replicas of the object's /scale
+// subresource, so no per-kind field path is needed. The object's metadata, i.e. its annotations
+// and owner references, is read through a metadata-only informer, which also invalidates the
+// cached spec, as the scale subresource itself is read from the API server.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/scale"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// scaleRequestTimeout bounds each read of a scale subresource.
+	scaleRequestTimeout = 5 * time.Second
+)
+
+// addScaleControllers resolves the ScaleGroupResources to their kinds and adds a controller read
+// through the scale subresource for each, keyed by kind, to the custom controllers. The metadata
+// informers run for the lifetime of the scheduler process.
+func addScaleControllers(args *ControllerSpreadArgs, handle framework.Handle, customControllers map[string]*customController) (map[string]*customController, error) {
+	if len(args.ScaleGroupResources) == 0 {
+		return customControllers, nil
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(handle.KubeConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %v", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	scales, err := scale.NewForConfig(handle.KubeConfig(), mapper, dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scale client: %v", err)
+	}
+	metadataClient, err := metadata.NewForConfig(handle.KubeConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata client: %v", err)
+	}
+	informerFactory := metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+
+	if customControllers == nil {
+		customControllers = make(map[string]*customController, len(args.ScaleGroupResources))
+	}
+	for _, resource := range args.ScaleGroupResources {
+		// The group resources were validated by ValidateControllerSpreadArgs.
+		groupResource := schema.ParseGroupResource(resource)
+		gvr, err := mapper.ResourceFor(groupResource.WithVersion(""))
+		if err != nil {
+			return nil, fmt.Errorf("resolving scaleGroupResources %q: %v", resource, err)
+		}
+		gvk, err := mapper.KindFor(gvr)
+		if err != nil {
+			return nil, fmt.Errorf("resolving the kind of scaleGroupResources %q: %v", resource, err)
+		}
+		if _, exists := customControllers[gvk.Kind]; exists || isBuiltinControllerType(ControllerType(gvk.Kind)) {
+			return nil, fmt.Errorf("kind %q of scaleGroupResources %q is already configured", gvk.Kind, resource)
+		}
+		informer := informerFactory.ForResource(gvr)
+		customControllers[gvk.Kind] = &customController{
+			config: CustomControllerConfig{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Resource:   gvr.Resource,
+			},
+			lister:        informer.Lister(),
+			hasSynced:     informer.Informer().HasSynced,
+			informer:      informer.Informer(),
+			scales:        scales,
+			groupResource: groupResource,
+		}
+	}
+
+	informerFactory.Start(wait.NeverStop)
+	return customControllers, nil
+}
+
+// scaleReplicas reads the desired replica count of the object from its scale subresource.
+func (cc *customController) scaleReplicas(obj *unstructured.Unstructured) (int32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scaleRequestTimeout)
+	defer cancel()
+	s, err := cc.scales.Scales(obj.GetNamespace()).Get(ctx, cc.groupResource, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if s.UID != obj.GetUID() {
+		return 0, fmt.Errorf("%w: scale of %s %s/%s has UID %s, expected %s", errInvalidSpec, cc.config.Kind, obj.GetNamespace(), obj.GetName(), s.UID, obj.GetUID())
+	}
+	return s.Spec.Replicas, nil
+}
+
+// isScaleController reports whether the desired count of the controller type is read from the
+// scale subresource.
+func (csf *ControllerSpreadFilter) isScaleController(t ControllerType) bool {
+	cc, ok := csf.customControllers[string(t)]
+	return ok && cc.scales != nil
+}
@@ -0,0 +1,156 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	scalefake "k8s.io/client-go/scale/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// rolloutResource is the group resource of the scale controller in the tests.
+var rolloutResource = schema.GroupResource{Group: "argoproj.io", Resource: "rollouts"}
+
+// newScaleController returns a Rollout controller read through the scale subresource, whose
+// metadata informer holds the Rollout of the name and whose scale subresource has the replicas
+// and UID, or fails with err if set.
+func newScaleController(t *testing.T, name string, annotations map[string]string, replicas int32, scaleUID types.UID, err error) *customController {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	rollout := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name), Annotations: annotations},
+	}
+	if err := indexer.Add(rollout); err != nil {
+		t.Fatalf("adding Rollout: %v", err)
+	}
+	scales := &scalefake.FakeScaleClient{}
+	scales.AddReactor("get", rolloutResource.Resource, func(clienttesting.Action) (bool, runtime.Object, error) {
+		if err != nil {
+			return true, nil, err
+		}
+		return true, &autoscalingv1.Scale{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: scaleUID},
+			Spec: autoscalingv1.ScaleSpec{Replicas: replicas}}, nil
+	})
+	return &customController{
+		config:        CustomControllerConfig{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Resource: rolloutResource.Resource},
+		lister:        cache.NewGenericLister(indexer, rolloutResource),
+		hasSynced:     func() bool { return true },
+		scales:        scales,
+		groupResource: rolloutResource,
+	}
+}
+
+func TestScaleReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		scaleUID types.UID
+		err      error
+		want     int32
+		wantErr  bool
+	}{
+		{
+			name:     "scale of the object",
+			scaleUID: testUID("web"),
+			want:     4,
+		},
+		{
+			name:     "scale of a recreated object",
+			scaleUID: testUID("web-recreated"),
+			wantErr:  true,
+		},
+		{
+			name:    "scale not found",
+			err:     apierrors.NewNotFound(rolloutResource, "web"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newScaleController(t, "web", nil, 4, tt.scaleUID, tt.err)
+			obj, err := cc.get(testNamespace, ControllerInfo{Type: "Rollout", Name: "web", UID: string(testUID("web"))})
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			got, err := cc.desiredReplicas(obj)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("desiredReplicas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("desiredReplicas() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleControllerIsOwner(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		want       bool
+	}{
+		{
+			name:       "configured version",
+			apiVersion: "argoproj.io/v1alpha1",
+			want:       true,
+		},
+		{
+			name:       "other version of the group",
+			apiVersion: "argoproj.io/v1",
+			want:       true,
+		},
+		{
+			name:       "other group",
+			apiVersion: "example.com/v1alpha1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newScaleController(t, "web", nil, 1, testUID("web"), nil)
+			if got := cc.isOwner(metav1.OwnerReference{APIVersion: tt.apiVersion, Kind: "Rollout"}); got != tt.want {
+				t.Errorf("isOwner(%q) = %v, want %v", tt.apiVersion, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterScaleController(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	owner := metav1.OwnerReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web", UID: testUID("web"), Controller: ptr.To(true)}
+	tests := []struct {
+		name     string
+		replicas int32
+		want     []string
+	}{
+		{
+			name:     "min-hosts within the scale replicas",
+			replicas: 3,
+			want:     []string{"node-c"},
+		},
+		{
+			name:     "min-hosts capped at the scale replicas",
+			replicas: 2,
+			want:     []string{"node-a", "node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("web-new", "", owner)
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, makePod("web-0", "node-a", owner), makePod("web-1", "node-b", owner), pod)
+			cc := newScaleController(t, "web", map[string]string{minHostsAnnotationKey: "3"}, tt.replicas, testUID("web"), nil)
+			p.customControllers = map[string]*customController{"Rollout": cc}
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
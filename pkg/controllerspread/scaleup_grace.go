@@ -0,0 +1,172 @@
+// pkg/controllerspread/scaleup_grace.go
+//
+// Scale-up grace period for ControllerSpreadFilter. When a controller is scaled up quickly,
+// enforcing every constraint on every new pod can leave many pods pending at once. With the
+// "controller-spread-scheduler/scaleup-grace-seconds" annotation on the controller, the
+// constraint is relaxed to min-hosts alone for that many seconds after the controller's replica
+// count last increased: max-pods-per-node and max-skew are not enforced during the window.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// Annotation key for the number of seconds after a scale-up during which the spread is relaxed.
+	scaleUpGraceAnnotationKey = "controller-spread-scheduler/scaleup-grace-seconds"
+
+	// maxScaleUpGrace caps the grace period, and is how long scale-ups are remembered.
+	maxScaleUpGrace = time.Hour
+)
+
+// scaleUpTracker records when the replica count of a controller last increased, keyed by
+// controller UID. Scale-ups are observed through informer update events, so they are only known
+// for scale-ups since the scheduler started.
+type scaleUpTracker struct {
+	// stopped is closed once the tracker no longer receives events, after the context of the
+	// plugin is done.
+	stopped chan struct{}
+
+	mu       sync.Mutex
+	scaledAt map[string]time.Time
+}
+
+// newScaleUpTracker returns a tracker fed by the informers of the scalable controller types
+// until ctx is done, when it removes its event handlers.
+func newScaleUpTracker(ctx context.Context, handle framework.Handle) (*scaleUpTracker, error) {
+	t := &scaleUpTracker{stopped: make(chan struct{}), scaledAt: make(map[string]time.Time)}
+	handler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: t.update,
+		DeleteFunc: t.forget,
+	}
+	informers := []cache.SharedIndexInformer{
+		handle.SharedInformerFactory().Apps().V1().Deployments().Informer(),
+		handle.SharedInformerFactory().Apps().V1().ReplicaSets().Informer(),
+		handle.SharedInformerFactory().Apps().V1().StatefulSets().Informer(),
+		handle.SharedInformerFactory().Core().V1().ReplicationControllers().Informer(),
+	}
+	registrations := make([]cache.ResourceEventHandlerRegistration, 0, len(informers))
+	removeHandlers := func() {
+		for i, registration := range registrations {
+			if err := informers[i].RemoveEventHandler(registration); err != nil {
+				klog.FromContext(ctx).Error(err, "Failed to remove scale-up tracker event handler")
+			}
+		}
+	}
+	for _, informer := range informers {
+		registration, err := informer.AddEventHandler(handler)
+		if err != nil {
+			removeHandlers()
+			return nil, fmt.Errorf("adding scale-up tracker event handler: %w", err)
+		}
+		registrations = append(registrations, registration)
+	}
+	go func() {
+		defer close(t.stopped)
+		<-ctx.Done()
+		removeHandlers()
+	}()
+	return t, nil
+}
+
+// update records a scale-up if the replica count of the controller increased.
+func (t *scaleUpTracker) update(oldObj, newObj interface{}) {
+	oldReplicas, ok := specReplicas(oldObj)
+	if !ok {
+		return
+	}
+	newReplicas, ok := specReplicas(newObj)
+	if !ok || newReplicas <= oldReplicas {
+		return
+	}
+	accessor, err := meta.Accessor(newObj)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for uid, scaledAt := range t.scaledAt {
+		if now.Sub(scaledAt) > maxScaleUpGrace {
+			delete(t.scaledAt, uid)
+		}
+	}
+	t.scaledAt[string(accessor.GetUID())] = now
+}
+
+// forget drops the controller object from an informer delete event.
+func (t *scaleUpTracker) forget(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.scaledAt, string(accessor.GetUID()))
+}
+
+// scaledUpWithin reports whether the controller was scaled up within the grace period. A nil
+// tracker knows of no scale-ups.
+func (t *scaleUpTracker) scaledUpWithin(uid string, grace time.Duration, now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scaledAt, ok := t.scaledAt[uid]
+	return ok && now.Sub(scaledAt) <= grace
+}
+
+// specReplicas returns the desired replica count of a scalable controller object; defaults to 1
+// when unset.
+func specReplicas(obj interface{}) (int32, bool) {
+	var replicas *int32
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		replicas = o.Spec.Replicas
+	case *appsv1.ReplicaSet:
+		replicas = o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		replicas = o.Spec.Replicas
+	case *v1.ReplicationController:
+		replicas = o.Spec.Replicas
+	default:
+		return 0, false
+	}
+	if replicas == nil {
+		return 1, true
+	}
+	return *replicas, true
+}
+
+// parseScaleUpGraceAnnotation returns the scaleup-grace-seconds annotation value, or 0 (no grace)
+// if it is absent or not a positive integer. Values above maxScaleUpGrace are capped.
+func parseScaleUpGraceAnnotation(logger klog.Logger, annotations map[string]string, controller ControllerInfo) time.Duration {
+	val, exists := annotations[scaleUpGraceAnnotationKey]
+	if !exists {
+		return 0
+	}
+	seconds, err := strconv.ParseInt(val, 10, 32)
+	if err != nil || seconds <= 0 {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", scaleUpGraceAnnotationKey, "value", val, "controller", controller.Name)
+		return 0
+	}
+	if grace := time.Duration(seconds) * time.Second; grace < maxScaleUpGrace {
+		return grace
+	}
+	return maxScaleUpGrace
+}
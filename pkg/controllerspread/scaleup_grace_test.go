@@ -0,0 +1,62 @@
+package controllerspread
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestScaleUpTrackerStopsWithContext(t *testing.T) {
+	deploy := makeDeployment("web", 2, nil)
+	client := clientsetfake.NewSimpleClientset(deploy)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	fh := newTestFramework(t, nil, nil, frameworkruntime.WithClientSet(client), frameworkruntime.WithInformerFactory(informerFactory))
+	ctx, cancel := context.WithCancel(t.Context())
+	tracker, err := newScaleUpTracker(ctx, fh)
+	if err != nil {
+		t.Fatalf("newScaleUpTracker: %v", err)
+	}
+	// deleted is signalled on Deployment delete events, once the tracker would have received them.
+	deleted := make(chan struct{}, 1)
+	if _, err := informerFactory.Apps().V1().Deployments().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(interface{}) { deleted <- struct{}{} },
+	}); err != nil {
+		t.Fatalf("adding event handler: %v", err)
+	}
+	informerFactory.Start(t.Context().Done())
+	informerFactory.WaitForCacheSync(t.Context().Done())
+
+	uid := string(deploy.UID)
+	deploy.Spec.Replicas = ptr.To[int32](4)
+	if _, err := client.AppsV1().Deployments(testNamespace).Update(t.Context(), deploy, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("scaling up: %v", err)
+	}
+	if err := wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		return tracker.scaledUpWithin(uid, time.Minute, time.Now()), nil
+	}); err != nil {
+		t.Fatalf("scale-up not recorded: %v", err)
+	}
+
+	cancel()
+	select {
+	case <-tracker.stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("scale-up tracker did not stop after its context was done")
+	}
+	if err := client.AppsV1().Deployments(testNamespace).Delete(t.Context(), deploy.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("deleting: %v", err)
+	}
+	<-deleted
+	// The delete event is no longer delivered to the tracker, so the scale-up is not forgotten.
+	if !tracker.scaledUpWithin(uid, time.Minute, time.Now()) {
+		t.Errorf("scaledUpWithin() after stop = false, want true")
+	}
+}
@@ -0,0 +1,35 @@
+// pkg/controllerspread/scheduling_gates.go
+//
+// Staged rollout of the spread through pod scheduling gates. kube-scheduler does not schedule a
+// pod while it carries a scheduling gate, so the gate named by SpreadGate in the plugin args is
+// read from the other pods of the controller: while any of them still carries it, the
+// controller is not ready to spread and its ungated pods are placed freely. Once the gate is
+// removed from every pod, the spread is enforced.
+package controllerspread
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// hasSchedulingGate reports whether the pod carries the named scheduling gate.
+func hasSchedulingGate(pod *v1.Pod, gate string) bool {
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name == gate {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitingSpreadGate returns a pod of the controller that still carries SpreadGate, if any.
+func (csf *ControllerSpreadFilter) awaitingSpreadGate(controllerPods []*v1.Pod) (*v1.Pod, bool) {
+	if csf.args.SpreadGate == "" {
+		return nil, false
+	}
+	for _, p := range controllerPods {
+		if hasSchedulingGate(p, csf.args.SpreadGate) {
+			return p, true
+		}
+	}
+	return nil, false
+}
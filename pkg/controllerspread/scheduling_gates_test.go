@@ -0,0 +1,58 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterSpreadGate(t *testing.T) {
+	const gate = "example.com/spread"
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		args ControllerSpreadArgs
+		// gates are the scheduling gates of the pending peer.
+		gates []string
+		want  []string
+	}{
+		{
+			name:  "no spread gate configured",
+			gates: []string{gate},
+			want:  []string{"node-b", "node-c"},
+		},
+		{
+			name:  "peer gated",
+			args:  ControllerSpreadArgs{SpreadGate: gate},
+			gates: []string{gate},
+			want:  []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name:  "peer gated by another gate",
+			args:  ControllerSpreadArgs{SpreadGate: gate},
+			gates: []string{"example.com/quota"},
+			want:  []string{"node-b", "node-c"},
+		},
+		{
+			name: "gate removed",
+			args: ControllerSpreadArgs{SpreadGate: gate},
+			want: []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a")
+			gated := makePod("web-1", "", ownerRef(ReplicaSetType, "web-hash"))
+			for _, g := range tt.gates {
+				gated.Spec.SchedulingGates = append(gated.Spec.SchedulingGates, v1.PodSchedulingGate{Name: g})
+			}
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, gated, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
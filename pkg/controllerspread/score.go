@@ -0,0 +1,189 @@
+// pkg/controllerspread/score.go
+//
+// Score extension point for ControllerSpreadFilter. Once the hard minimum host spread is
+// satisfied by Filter, Score prefers nodes hosting fewer pods of the same controller so that
+// replicas keep spreading evenly instead of packing onto the fewest feasible nodes.
+package controllerspread
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// preScoreStateKey is the CycleState key under which PreScore stores its result.
+	preScoreStateKey = "PreScore" + Name
+
+	// Annotation key for how strongly a controller wants its pods spread by Score.
+	spreadWeightAnnotationKey = "controller-spread-scheduler/spread-weight"
+
+	// defaultSpreadWeight is used when the spread-weight annotation is absent or invalid.
+	defaultSpreadWeight = 10
+
+	// maxSpreadWeight is the largest accepted spread weight. A controller with this weight
+	// gets MaxNodeScore on its best node.
+	maxSpreadWeight = 100
+)
+
+var _ framework.PreScorePlugin = &ControllerSpreadFilter{}
+var _ framework.ScorePlugin = &ControllerSpreadFilter{}
+var _ framework.ScoreExtensions = &ControllerSpreadFilter{}
+
+// preScoreState holds the number of same-controller pods per node, computed once per cycle.
+type preScoreState struct {
+	nodeCounts   map[string]int
+	spreadWeight int64
+	// weights are the domain weights, or nil when no domain weights are configured.
+	weights *domainWeights
+	// nodeDomains is the weighted domain of each candidate node.
+	nodeDomains map[string]string
+	// domainCounts is the number of same-controller pods per weighted domain.
+	domainCounts map[string]int
+	// excludedNodes are the candidate nodes matching ExcludedNodeSelector.
+	excludedNodes map[string]bool
+}
+
+// Clone implements framework.StateData. The state is read-only after PreScore.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore records how many of the controller's pods run on each node. The distribution
+// computed by PreFilter is reused when available; otherwise the controller's pods are listed once.
+func (csf *ControllerSpreadFilter) PreScore(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodes []*framework.NodeInfo) *framework.Status {
+	logger := klog.FromContext(ctx)
+	if s, err := getPreFilterState(cycleState); err == nil {
+		cycleState.Write(preScoreStateKey, csf.newPreScoreState(logger, nodes, s.nodeCounts, s.spreadWeight))
+		return nil
+	}
+
+	// Scoring is a soft preference, so it is skipped on errors regardless of the OnError policy.
+	if inScope, err := csf.namespaces.inScope(logger, pod.Namespace); err != nil || !inScope || !csf.caches.ready() {
+		return framework.NewStatus(framework.Skip)
+	}
+	controller, ok := csf.resolveGroup(logger, pod)
+	if !ok || !csf.isControllerTypeEnabled(controller.Type) || csf.outOfResourceScope(pod) {
+		return framework.NewStatus(framework.Skip)
+	}
+
+	spreadWeight := int64(defaultSpreadWeight)
+	if _, annotations, err := csf.getGroupSpec(ctx, pod, controller); err == nil {
+		spreadWeight = parseSpreadWeightAnnotation(annotations)
+	}
+
+	controllerPods, err := csf.listControllerPods(ctx, pod.Namespace, controller)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "Error listing pods", "namespace", pod.Namespace)
+		return framework.AsStatus(fmt.Errorf("error listing pods: %w", err))
+	}
+	cycleState.Write(preScoreStateKey, csf.newPreScoreState(logger, nodes, csf.withoutCordonedNodes(csf.withoutExcludedNodes(countPodsPerNode(csf.withRequiredResource(withoutPod(controllerPods, pod))))), spreadWeight))
+	return nil
+}
+
+// newPreScoreState returns the PreScore state, including the excluded candidate nodes and the
+// per-domain counts of the domain weights when they are configured.
+func (csf *ControllerSpreadFilter) newPreScoreState(logger klog.Logger, nodes []*framework.NodeInfo, nodeCounts map[string]int, spreadWeight int64) *preScoreState {
+	s := &preScoreState{nodeCounts: nodeCounts, spreadWeight: spreadWeight, weights: csf.domainWeights.get()}
+	if csf.excludedNodes != nil {
+		s.excludedNodes = make(map[string]bool)
+		for _, nodeInfo := range nodes {
+			if node := nodeInfo.Node(); node != nil && csf.isExcludedNode(node) {
+				s.excludedNodes[node.Name] = true
+			}
+		}
+	}
+	if s.weights == nil {
+		return s
+	}
+	s.nodeDomains = make(map[string]string, len(nodes))
+	for _, nodeInfo := range nodes {
+		if node := nodeInfo.Node(); node != nil {
+			s.nodeDomains[node.Name] = topologyDomain(logger, node, s.weights.topologyKey)
+		}
+	}
+	s.domainCounts = csf.countPodsPerDomain(logger, nodeCounts, s.weights.topologyKey)
+	return s
+}
+
+// parseSpreadWeightAnnotation returns the spread weight from the controller annotations.
+// Values outside 1..maxSpreadWeight fall back to defaultSpreadWeight.
+func parseSpreadWeightAnnotation(annotations map[string]string) int64 {
+	val, exists := annotations[spreadWeightAnnotationKey]
+	if !exists {
+		return defaultSpreadWeight
+	}
+	parsed, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || parsed < 1 || parsed > maxSpreadWeight {
+		return defaultSpreadWeight
+	}
+	return parsed
+}
+
+// getPreScoreState reads the PreScore result from the cycle state.
+func getPreScoreState(cycleState *framework.CycleState) (*preScoreState, error) {
+	c, err := cycleState.Read(preScoreStateKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q from cycleState: %w", preScoreStateKey, err)
+	}
+	s, ok := c.(*preScoreState)
+	if !ok {
+		return nil, fmt.Errorf("%+v convert to controllerspread.preScoreState error", c)
+	}
+	return s, nil
+}
+
+// Score returns a score that is inversely proportional to the number of same-controller pods
+// already on the node, multiplied by the controller's spread weight. With domain weights, the
+// score is further scaled by w/(w+n) for the node's domain of weight w running n pods, so that
+// domains fill up in proportion to their weight.
+func (csf *ControllerSpreadFilter) Score(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	s, err := getPreScoreState(cycleState)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	if s.excludedNodes[nodeName] {
+		// Excluded nodes do not spread the controller, so scoring does not favor them.
+		return 0, nil
+	}
+	score := s.spreadWeight * framework.MaxNodeScore / int64(1+s.nodeCounts[nodeName])
+	if s.weights != nil {
+		domain := s.nodeDomains[nodeName]
+		weight := s.weights.weight(domain)
+		score = score * weight / (weight + int64(s.domainCounts[domain]))
+	}
+	return score, nil
+}
+
+// ScoreExtensions returns the plugin itself, which implements NormalizeScore.
+func (csf *ControllerSpreadFilter) ScoreExtensions() framework.ScoreExtensions {
+	return csf
+}
+
+// NormalizeScore rescales the scores so that the best node gets MaxNodeScore scaled by the
+// spread weight, i.e. MaxNodeScore for a weight of maxSpreadWeight.
+func (csf *ControllerSpreadFilter) NormalizeScore(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, scores framework.NodeScoreList) *framework.Status {
+	s, err := getPreScoreState(cycleState)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	best := s.spreadWeight * framework.MaxNodeScore / maxSpreadWeight
+
+	var highest int64
+	for _, score := range scores {
+		if score.Score > highest {
+			highest = score.Score
+		}
+	}
+	if highest == 0 {
+		return nil
+	}
+	for i := range scores {
+		scores[i].Score = scores[i].Score * best / highest
+	}
+	return nil
+}
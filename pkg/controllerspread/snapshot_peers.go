@@ -0,0 +1,60 @@
+// pkg/controllerspread/snapshot_peers.go
+//
+// Peers assumed by the scheduler cache. Once the scheduler assumes a pod onto a node, the pod is
+// part of the NodeInfos of the scheduling snapshot, but the informer cache shows it unbound until
+// its binding completes. Reserve records the placements this plugin makes, but not those of pods
+// scheduled by other profiles of the same scheduler or of pods whose Reserve ran before a restart
+// of the plugin's state. PreFilter therefore looks up the listed peers that are not bound yet in
+// the snapshot by UID and counts those it finds on a node, so that Filter, which reads the
+// candidate NodeInfo from the same snapshot, sees them too. Only listed peers are looked up, so
+// the owner chain of the snapshot pods is never resolved.
+package controllerspread
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// addSnapshotPeers adds to nodeCounts the listed peers without a node that the snapshot places on
+// a node and Reserve did not record. A peer that is only nominated moves from its nominated node
+// to the node of the snapshot.
+func (csf *ControllerSpreadFilter) addSnapshotPeers(logger klog.Logger, pod *v1.Pod, peers []*v1.Pod, nodeCounts map[string]int) {
+	now := time.Now()
+	unbound := make(map[types.UID]*v1.Pod)
+	for _, p := range peers {
+		if p.Spec.NodeName == "" && !csf.assumed.has(p.UID, now) {
+			unbound[p.UID] = p
+		}
+	}
+	if len(unbound) == 0 {
+		return
+	}
+	nodeInfos, err := csf.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		logger.V(4).Info("Could not list snapshot nodes for assumed peers", "pod", klog.KObj(pod), "err", err)
+		return
+	}
+	for _, nodeInfo := range nodeInfos {
+		for _, podInfo := range nodeInfo.Pods {
+			lp, ok := unbound[podInfo.Pod.UID]
+			if !ok || podInfo.Pod.Spec.NodeName == "" {
+				continue
+			}
+			if nominated := lp.Status.NominatedNodeName; nominated != "" && nodeCounts[nominated] > 0 {
+				nodeCounts[nominated]--
+				if nodeCounts[nominated] == 0 {
+					delete(nodeCounts, nominated)
+				}
+			}
+			logger.V(5).Info("Counting peer assumed by the scheduler cache", "pod", klog.KObj(pod), "peer", klog.KObj(lp), "node", podInfo.Pod.Spec.NodeName)
+			nodeCounts[podInfo.Pod.Spec.NodeName]++
+			delete(unbound, lp.UID)
+			if len(unbound) == 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterSnapshotPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name string
+		// nominatedNode is the node web-1 is nominated to in the informer cache, if not empty.
+		nominatedNode string
+		// assumedNode is the node the scheduler cache assumed web-1 onto, if not empty.
+		assumedNode string
+		// unlistedNode is the node of a peer only the snapshot holds, if not empty.
+		unlistedNode string
+		want         []string
+	}{
+		{
+			name: "peer not assumed",
+			want: []string{"node-b", "node-c"},
+		},
+		{
+			name:        "peer assumed by the scheduler cache",
+			assumedNode: "node-b",
+			want:        []string{"node-c"},
+		},
+		{
+			name:          "nominated peer assumed onto another node",
+			nominatedNode: "node-c",
+			assumedNode:   "node-b",
+			want:          []string{"node-c"},
+		},
+		{
+			// Only listed peers are looked up in the snapshot.
+			name:         "unlisted pod in the snapshot",
+			unlistedNode: "node-b",
+			want:         []string{"node-b", "node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := makeDeploymentPods(makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"}), "node-a")
+			peer := makePod("web-1", "", ownerRef(ReplicaSetType, "web-hash"))
+			peer.Status.NominatedNodeName = tt.nominatedNode
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, peer, pod)...)
+			snapshotPods := []*v1.Pod{objs[2].(*v1.Pod)}
+			if tt.assumedNode != "" {
+				assumed := peer.DeepCopy()
+				assumed.Spec.NodeName = tt.assumedNode
+				snapshotPods = append(snapshotPods, assumed)
+			}
+			if tt.unlistedNode != "" {
+				snapshotPods = append(snapshotPods, makePod("web-2", tt.unlistedNode, ownerRef(ReplicaSetType, "web-hash")))
+			}
+			p.handle = newTestFramework(t, nodes, snapshotPods)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// pkg/controllerspread/spec_cache.go
+//
+// Short-lived cache of controller specs. PreFilter and PreScore read the desired replica count
+// and the annotations of the pod's controller on every scheduling attempt; pods of the same
+// controller are often scheduled in bursts, so the spec is cached by controller UID. Entries are
+// invalidated by informer event handlers when the controller is updated or deleted, and expire
+// after specCacheTTL as a safety net.
+package controllerspread
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// specCacheTTL is how long a cached controller spec is used without an informer event.
+	specCacheTTL = 10 * time.Second
+)
+
+// cachedSpec is the desired count and annotations of a controller.
+type cachedSpec struct {
+	desired     int32
+	annotations map[string]string
+	expires     time.Time
+}
+
+// specCache caches controller specs keyed by controller UID.
+type specCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSpec
+}
+
+// newSpecCache returns a specCache that is invalidated by the controller informers of the handle.
+// DaemonSets are not cached, as their desired count depends on the nodes rather than on the
+// DaemonSet object. Custom controllers are not cached either, except those read through the
+// scale subresource, see invalidateOn.
+func newSpecCache(handle framework.Handle) *specCache {
+	c := &specCache{entries: make(map[string]cachedSpec)}
+	informers := []cache.SharedIndexInformer{
+		handle.SharedInformerFactory().Apps().V1().Deployments().Informer(),
+		handle.SharedInformerFactory().Apps().V1().ReplicaSets().Informer(),
+		handle.SharedInformerFactory().Apps().V1().StatefulSets().Informer(),
+		handle.SharedInformerFactory().Batch().V1().Jobs().Informer(),
+		handle.SharedInformerFactory().Batch().V1().CronJobs().Informer(),
+		handle.SharedInformerFactory().Core().V1().ReplicationControllers().Informer(),
+	}
+	for _, informer := range informers {
+		c.invalidateOn(informer)
+	}
+	return c
+}
+
+// invalidateOn invalidates cached specs on updates and deletes of the informer's objects. A nil
+// cache has nothing to invalidate.
+func (c *specCache) invalidateOn(informer cache.SharedIndexInformer) {
+	if c == nil {
+		return
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) { c.invalidate(newObj) },
+		DeleteFunc: c.invalidate,
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to add controller spec cache event handler")
+	}
+}
+
+// isCacheableControllerType reports whether the spec of the controller type is cached.
+func isCacheableControllerType(t ControllerType) bool {
+	switch t {
+	case DeploymentType, ReplicaSetType, StatefulSetType, JobType, CronJobType, ReplicationControllerType:
+		return true
+	}
+	return false
+}
+
+// get returns the unexpired cached spec of the controller. A nil cache caches nothing.
+func (c *specCache) get(uid string, now time.Time) (cachedSpec, bool) {
+	if c == nil {
+		return cachedSpec{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spec, ok := c.entries[uid]
+	if !ok || now.After(spec.expires) {
+		delete(c.entries, uid)
+		return cachedSpec{}, false
+	}
+	return spec, true
+}
+
+// set caches the spec of the controller.
+func (c *specCache) set(uid string, desired int32, annotations map[string]string, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[uid] = cachedSpec{desired: desired, annotations: annotations, expires: now.Add(specCacheTTL)}
+}
+
+// invalidate forgets the cached spec of the controller object from an informer event.
+func (c *specCache) invalidate(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, string(accessor.GetUID()))
+}
@@ -0,0 +1,32 @@
+// pkg/controllerspread/spread_after.go
+//
+// Warmup for batch workloads. With the "controller-spread-scheduler/spread-after" annotation on
+// the controller, the first N pods of the controller are placed freely, which speeds up cold
+// start, and the spread constraint only applies once N peers occupy a node.
+package controllerspread
+
+import (
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key for the number of peers placed before the spread constraint applies.
+	spreadAfterAnnotationKey = "controller-spread-scheduler/spread-after"
+)
+
+// parseSpreadAfterAnnotation returns the spread-after annotation value, or 0 (spread from the
+// first pod) if it is absent or not a non-negative integer.
+func parseSpreadAfterAnnotation(logger klog.Logger, annotations map[string]string, controller ControllerInfo) int32 {
+	val, exists := annotations[spreadAfterAnnotationKey]
+	if !exists {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(val, 10, 32)
+	if err != nil || parsed < 0 {
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", spreadAfterAnnotationKey, "value", val, "controller", controller.Name)
+		return 0
+	}
+	return int32(parsed)
+}
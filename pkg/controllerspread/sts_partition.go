@@ -0,0 +1,64 @@
+// pkg/controllerspread/sts_partition.go
+//
+// Partition-aware spreading for StatefulSet rolling updates. With a RollingUpdate partition,
+// only the ordinals at or above the partition are replaced. While such an update is in
+// progress, the replaced pods are spread among themselves, so that older ordinals still
+// clustered on a few nodes do not block them.
+package controllerspread
+
+import (
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// rollingPartitionOf returns the partition of the pod's StatefulSet if a partitioned rolling
+// update is in progress and the pod's ordinal is being rolled.
+func (csf *ControllerSpreadFilter) rollingPartitionOf(pod *v1.Pod, controller ControllerInfo) (int32, bool) {
+	if controller.Type != StatefulSetType {
+		return 0, false
+	}
+	sts, err := csf.stsLister.StatefulSets(pod.Namespace).Get(controller.Name)
+	if err != nil || sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return 0, false
+	}
+	rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil || *rollingUpdate.Partition <= 0 {
+		return 0, false
+	}
+	if sts.Status.UpdateRevision == "" || sts.Status.UpdateRevision == sts.Status.CurrentRevision {
+		return 0, false
+	}
+	partition := *rollingUpdate.Partition
+	ordinal, ok := statefulSetOrdinal(pod.Name, controller.Name)
+	if !ok || ordinal < partition {
+		return 0, false
+	}
+	return partition, true
+}
+
+// statefulSetOrdinal parses the ordinal from the name of a pod of the named StatefulSet.
+func statefulSetOrdinal(podName, stsName string) (int32, bool) {
+	suffix, found := strings.CutPrefix(podName, stsName+"-")
+	if !found {
+		return 0, false
+	}
+	ordinal, err := strconv.ParseInt(suffix, 10, 32)
+	if err != nil || ordinal < 0 {
+		return 0, false
+	}
+	return int32(ordinal), true
+}
+
+// withOrdinalAtLeast returns the pods of the named StatefulSet whose ordinal is at least partition.
+func withOrdinalAtLeast(pods []*v1.Pod, stsName string, partition int32) []*v1.Pod {
+	var result []*v1.Pod
+	for _, p := range pods {
+		if ordinal, ok := statefulSetOrdinal(p.Name, stsName); ok && ordinal >= partition {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package controllerspread
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+// makeStatefulSet returns a StatefulSet in the test namespace rolling out updateRevision to the
+// pods at or above the partition.
+func makeStatefulSet(name string, replicas, partition int32, currentRevision, updateRevision string, annotations map[string]string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: testUID(name), Annotations: annotations},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To(replicas),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To(partition)},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{CurrentRevision: currentRevision, UpdateRevision: updateRevision},
+	}
+}
+
+func TestRollingPartitionPeers(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b", "node-c")
+	tests := []struct {
+		name           string
+		updateRevision string
+		// peerNodes are the nodes of the pods of the ordinals other than pod's.
+		peerNodes map[string]string
+		pod       string
+		want      []string
+	}{
+		{
+			name:           "pod above the partition during an update",
+			updateRevision: "v2",
+			peerNodes:      map[string]string{"db-0": "node-a", "db-1": "node-a", "db-2": "node-b"},
+			pod:            "db-3",
+			want:           []string{"node-a", "node-c"},
+		},
+		{
+			name:           "update complete",
+			updateRevision: "v1",
+			peerNodes:      map[string]string{"db-0": "node-a", "db-1": "node-a", "db-2": "node-b"},
+			pod:            "db-3",
+			want:           []string{"node-c"},
+		},
+		{
+			name:           "pod below the partition",
+			updateRevision: "v2",
+			peerNodes:      map[string]string{"db-0": "node-a", "db-2": "node-b", "db-3": "node-a"},
+			pod:            "db-1",
+			want:           []string{"node-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sts := makeStatefulSet("db", 4, 2, "v1", tt.updateRevision, map[string]string{minHostsAnnotationKey: "3"})
+			objs := []runtime.Object{sts}
+			for name, nodeName := range tt.peerNodes {
+				objs = append(objs, makePod(name, nodeName, ownerRef(StatefulSetType, "db")))
+			}
+			pod := makePod(tt.pod, "", ownerRef(StatefulSetType, "db"))
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStatefulSetOrdinal(t *testing.T) {
+	tests := []struct {
+		name        string
+		podName     string
+		wantOrdinal int32
+		wantOK      bool
+	}{
+		{
+			name:        "pod of the StatefulSet",
+			podName:     "db-12",
+			wantOrdinal: 12,
+			wantOK:      true,
+		},
+		{
+			name:    "pod of another StatefulSet",
+			podName: "cache-1",
+		},
+		{
+			name:    "pod of a StatefulSet with a longer name",
+			podName: "db-primary-1",
+		},
+		{
+			name:    "negative ordinal",
+			podName: "db--1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordinal, ok := statefulSetOrdinal(tt.podName, "db")
+			if ordinal != tt.wantOrdinal || ok != tt.wantOK {
+				t.Errorf("statefulSetOrdinal(%q) = %d, %v, want %d, %v", tt.podName, ordinal, ok, tt.wantOrdinal, tt.wantOK)
+			}
+		})
+	}
+}
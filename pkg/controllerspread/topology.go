@@ -0,0 +1,279 @@
+// pkg/controllerspread/topology.go
+//
+// Topology domain resolution for ControllerSpreadFilter. By default pods are spread across
+// hostnames; the "controller-spread-scheduler/topology-key" annotation on the controller
+// selects another node label (e.g. topology.kubernetes.io/zone) whose distinct values are counted.
+// The TopologyKeys plugin arg spreads across several levels at once, e.g. zones and then nodes,
+// and the TopologyTaintKey plugin arg adds a level grouping nodes by the value of a taint.
+package controllerspread
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	v1helper "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation key for the node label whose distinct values are counted as spread domains.
+	topologyKeyAnnotationKey = "controller-spread-scheduler/topology-key"
+
+	// Annotation key for the minimum number of distinct domains at each topology level above
+	// the last one, e.g. zones when spreading across zones and nodes.
+	minZonesAnnotationKey = "controller-spread-scheduler/min-zones"
+
+	// Annotation key on the controller selecting whether every topology level ("all") or any
+	// one of them ("any") must reach its minimum spread.
+	levelMatchAnnotationKey = "controller-spread-scheduler/level-match"
+
+	// Values of the level-match annotation.
+	allLevelsMatch = "all"
+	anyLevelMatch  = "any"
+
+	// defaultTopologyKey spreads pods across distinct nodes.
+	defaultTopologyKey = v1.LabelHostname
+
+	// taintTopologyKeyPrefix marks a level keyed on a node taint rather than a node label, see
+	// RegisterDomainExtractor.
+	taintTopologyKeyPrefix = taintDomainExtractor + ":"
+
+	// untaintedDomain is the domain of the nodes without the topology taint. Taint values cannot
+	// contain angle brackets, so it never collides with a taint value.
+	untaintedDomain = "<untainted>"
+)
+
+// topologyLevel is one level of the spread constraint.
+type topologyLevel struct {
+	// key is the node label whose distinct values are counted as spread domains.
+	key string
+	// required is the minimum number of distinct domains the controller's pods must span.
+	required int32
+	// domainCounts is the number of controller pods per domain.
+	domainCounts map[string]int
+	// eligibleDomains are the domains of the nodes the pod may run on, see addEligibleDomains.
+	eligibleDomains map[string]bool
+}
+
+// topologyKeys returns the ordered topology keys for the controller. The topology-key
+// annotation selects a single level; otherwise the TopologyKeys plugin arg is used, and
+// hostnames when neither is set, below the taint level of the TopologyTaintKey plugin arg.
+func (csf *ControllerSpreadFilter) topologyKeys(annotations map[string]string) []string {
+	if val := annotations[topologyKeyAnnotationKey]; val != "" {
+		return []string{val}
+	}
+	keys := csf.args.TopologyKeys
+	if len(keys) == 0 {
+		keys = []string{defaultTopologyKey}
+	}
+	if csf.args.TopologyTaintKey != "" {
+		keys = append([]string{taintTopologyKeyPrefix + csf.args.TopologyTaintKey}, keys...)
+	}
+	return keys
+}
+
+// parseLevelMatchAnnotation reports whether the controller's level-match annotation accepts a
+// placement that meets the minimum of any one level. Invalid values are logged and ignored.
+func parseLevelMatchAnnotation(logger klog.Logger, annotations map[string]string, controller ControllerInfo) bool {
+	val, exists := annotations[levelMatchAnnotationKey]
+	if !exists {
+		return false
+	}
+	switch val {
+	case allLevelsMatch:
+		return false
+	case anyLevelMatch:
+		return true
+	default:
+		logger.V(2).Info("Ignoring invalid annotation", "annotation", levelMatchAnnotationKey, "value", val, "controller", controller.Name)
+		return false
+	}
+}
+
+// topologyLevels builds the spread levels of the controller. The last level requires
+// requiredHosts domains; the levels above it require the min-zones annotation value,
+// defaulting to the min-hosts value, capped at the desired replica count.
+func (csf *ControllerSpreadFilter) topologyLevels(logger klog.Logger, controller ControllerInfo, annotations map[string]string, nodeCounts map[string]int,
+	desired, minHostsVal, requiredHosts int32) []topologyLevel {
+	keys := csf.topologyKeys(annotations)
+	if val := annotations[topologyKeyAnnotationKey]; val != "" {
+		if _, err := newDomainExtractor(val); err != nil {
+			logger.V(2).Info("Ignoring invalid topology key, treating every node as its own domain", "annotation", topologyKeyAnnotationKey,
+				"controllerType", controller.Type, "controller", controller.Name, "err", err)
+			invalidAnnotations.WithLabelValues(csf.Name(), topologyKeyAnnotationKey).Inc()
+		}
+	}
+	minZonesVal := minHostsVal
+	if val, exists := annotations[minZonesAnnotationKey]; exists {
+		var err error
+		minZonesVal, err = parseMinHostsAnnotation(val, desired, minHostsVal)
+		if err != nil {
+			csf.reportInvalidAnnotation(logger, minZonesAnnotationKey, controller, err)
+		}
+	}
+
+	levels := make([]topologyLevel, len(keys))
+	for i, key := range keys {
+		required := min(desired, minZonesVal)
+		if i == len(keys)-1 {
+			required = requiredHosts
+		}
+		levels[i] = topologyLevel{key: key, required: required, domainCounts: csf.countPodsPerDomain(logger, nodeCounts, key)}
+	}
+	return levels
+}
+
+// addEligibleDomains records, for each level, the domains of the nodes matching the pod's node
+// selector and required node affinity, whose NoSchedule and NoExecute taints the pod
+// tolerates and that are not reserved for another tenant. They bound the achievable spread (clampToFeasibleDomains)
+// and, like in pod topology spread, are the domains considered for the skew, so that nodes the
+// pod can never run on do not pin the minimum at 0.
+func (csf *ControllerSpreadFilter) addEligibleDomains(logger klog.Logger, pod *v1.Pod, levels []topologyLevel) error {
+	nodeInfos, err := csf.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return err
+	}
+	requiredAffinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+	tenant := csf.tenantOf(pod)
+	for i := range levels {
+		levels[i].eligibleDomains = make(map[string]bool)
+	}
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		if !isFeasibleNode(pod, requiredAffinity, node) || csf.isExcludedNode(node) {
+			continue
+		}
+		if _, reserved := csf.reservedForOtherTenant(node, tenant); reserved {
+			continue
+		}
+		for i := range levels {
+			levels[i].eligibleDomains[topologyDomain(logger, node, levels[i].key)] = true
+		}
+	}
+	return nil
+}
+
+// isFeasibleNode reports whether the node matches the pod's node selector and required node
+// affinity and the pod tolerates its NoSchedule and NoExecute taints.
+func isFeasibleNode(pod *v1.Pod, requiredAffinity nodeaffinity.RequiredNodeAffinity, node *v1.Node) bool {
+	if match, _ := requiredAffinity.Match(node); !match {
+		return false
+	}
+	_, untolerated := v1helper.FindMatchingUntoleratedTaint(node.Spec.Taints, pod.Spec.Tolerations, doNotScheduleTaints)
+	return !untolerated
+}
+
+// FeasibleDomains returns the number of distinct domains of the topology key among the nodes the
+// pod may run on, going by its node selector, required node affinity and tolerations of
+// NoSchedule and NoExecute taints. Nodes missing the label are each their own domain. It is the
+// upper bound of the spread Filter can achieve for the pod, see clampToFeasibleDomains, and lets
+// callers such as admission webhooks detect requirements no placement can meet.
+func FeasibleDomains(pod *v1.Pod, nodes []*v1.Node, topologyKey string) int {
+	logger := klog.Background()
+	requiredAffinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+	domains := make(map[string]bool)
+	for _, node := range nodes {
+		if isFeasibleNode(pod, requiredAffinity, node) {
+			domains[topologyDomain(logger, node, topologyKey)] = true
+		}
+	}
+	return len(domains)
+}
+
+// withoutUntoleratedNodes removes the nodes with a NoSchedule or NoExecute taint the pod does not
+// tolerate from the per-node pod counts. The pod can never join the peers on them, so their
+// domains count neither toward the current spread nor toward the feasible domains, see
+// clampToFeasibleDomains. Nodes that cannot be looked up are kept.
+func (csf *ControllerSpreadFilter) withoutUntoleratedNodes(pod *v1.Pod, nodeCounts map[string]int) map[string]int {
+	for nodeName := range nodeCounts {
+		node, err := csf.nodeLister.Get(nodeName)
+		if err != nil {
+			continue
+		}
+		if _, untolerated := v1helper.FindMatchingUntoleratedTaint(node.Spec.Taints, pod.Spec.Tolerations, doNotScheduleTaints); untolerated {
+			delete(nodeCounts, nodeName)
+		}
+	}
+	return nodeCounts
+}
+
+// doNotScheduleTaints selects the taints that keep pods without a matching toleration off a node.
+func doNotScheduleTaints(t *v1.Taint) bool {
+	return t.Effect == v1.TaintEffectNoSchedule || t.Effect == v1.TaintEffectNoExecute
+}
+
+// clampToFeasibleDomains lowers the required spread of each level to the number of domains the
+// controller's pods can span: the eligible domains plus the domains already running a peer on a
+// node whose taints the pod tolerates.
+// Without it, a controller whose pods are confined to fewer domains than min-hosts by node
+// affinity would stay pending forever.
+func clampToFeasibleDomains(logger klog.Logger, pod *v1.Pod, levels []topologyLevel) {
+	for i, level := range levels {
+		feasible := len(level.eligibleDomains)
+		for domain := range level.domainCounts {
+			if !level.eligibleDomains[domain] {
+				feasible++
+			}
+		}
+		if int(level.required) > feasible {
+			logger.V(3).Info("Clamping required spread to the feasible number of domains", "pod", klog.KObj(pod),
+				"topologyKey", level.key, "required", level.required, "feasible", feasible)
+			levels[i].required = int32(feasible)
+		}
+	}
+}
+
+// describeSpread summarizes the current and required spread of the levels for events.
+func describeSpread(levels []topologyLevel) string {
+	if len(levels) == 1 {
+		return fmt.Sprintf("%d of %d required", len(levels[0].domainCounts), levels[0].required)
+	}
+	parts := make([]string, len(levels))
+	for i, level := range levels {
+		parts[i] = fmt.Sprintf("%d of %d required %s domains", len(level.domainCounts), level.required, level.key)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// topologyValue returns the domain of the node for the topology key from its DomainExtractor,
+// e.g. the value of the label, or for a taint level the value of the taint. It reports false if
+// the node has no domain, e.g. it is missing the label.
+func topologyValue(node *v1.Node, topologyKey string) (string, bool) {
+	val := domainExtractorFor(topologyKey).Domain(node)
+	return val, val != ""
+}
+
+// topologyDomain returns the domain of the node for the topology key, see topologyValue. A node
+// that is missing the label is treated as its own unique domain, identified by the node name.
+func topologyDomain(logger klog.Logger, node *v1.Node, topologyKey string) string {
+	if val, ok := topologyValue(node, topologyKey); ok {
+		return val
+	}
+	logger.V(3).Info("Node is missing topology label, treating it as its own domain", "node", node.Name, "topologyKey", topologyKey)
+	return node.Name
+}
+
+// nodeDomain resolves the topology domain of the named node through the node lister. Nodes
+// that cannot be resolved or are missing the label are treated as their own unique domain,
+// like in topologyDomain.
+func (csf *ControllerSpreadFilter) nodeDomain(logger klog.Logger, nodeName, topologyKey string) string {
+	if val, ok := csf.nodeTopologyValue(nodeName, topologyKey); ok {
+		return val
+	}
+	logger.V(3).Info("Could not resolve node topology label, treating it as its own domain", "node", nodeName, "topologyKey", topologyKey)
+	return nodeName
+}
+
+// countPodsPerDomain aggregates per-node pod counts into per-domain pod counts.
+func (csf *ControllerSpreadFilter) countPodsPerDomain(logger klog.Logger, nodeCounts map[string]int, topologyKey string) map[string]int {
+	domainCounts := make(map[string]int, len(nodeCounts))
+	for nodeName, count := range nodeCounts {
+		domainCounts[csf.nodeDomain(logger, nodeName, topologyKey)] += count
+	}
+	return domainCounts
+}
@@ -0,0 +1,263 @@
+package controllerspread
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+func TestUntoleratedPeerDomains(t *testing.T) {
+	dedicated := v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	tolerateDedicated := v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	tests := []struct {
+		name         string
+		taints       []v1.Taint
+		tolerations  []v1.Toleration
+		wantRequired int32
+		wantDomains  map[string]int
+		want         []string
+	}{
+		{
+			name:         "untolerated taint",
+			taints:       []v1.Taint{dedicated},
+			wantRequired: 2,
+			wantDomains:  map[string]int{"node-a": 1},
+			want:         []string{"node-b", "node-c"},
+		},
+		{
+			name:         "tolerated taint",
+			taints:       []v1.Taint{dedicated},
+			tolerations:  []v1.Toleration{tolerateDedicated},
+			wantRequired: 3,
+			wantDomains:  map[string]int{"node-a": 1, "node-c": 1},
+			want:         []string{"node-b"},
+		},
+		{
+			name:         "one of two taints tolerated",
+			taints:       []v1.Taint{dedicated, {Key: "maintenance", Effect: v1.TaintEffectNoExecute}},
+			tolerations:  []v1.Toleration{tolerateDedicated},
+			wantRequired: 2,
+			wantDomains:  map[string]int{"node-a": 1},
+			want:         []string{"node-b", "node-c"},
+		},
+		{
+			name:         "PreferNoSchedule taint",
+			taints:       []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectPreferNoSchedule}},
+			wantRequired: 3,
+			wantDomains:  map[string]int{"node-a": 1, "node-c": 1},
+			want:         []string{"node-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := makeNodes("node-a", "node-b", "node-c")
+			nodes[2].Spec.Taints = tt.taints
+			deploy := makeDeployment("web", 3, map[string]string{minHostsAnnotationKey: "3"})
+			objs := makeDeploymentPods(deploy, "node-a", "node-c")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Spec.Tolerations = tt.tolerations
+			p := newTestPlugin(t, &ControllerSpreadArgs{}, nodes, append(objs, pod)...)
+
+			state, status := preFilter(t, p, pod)
+			if !status.IsSuccess() {
+				t.Fatalf("PreFilter: %v", status)
+			}
+			s, err := getPreFilterState(state)
+			if err != nil {
+				t.Fatalf("getPreFilterState: %v", err)
+			}
+			level := s.levels[len(s.levels)-1]
+			if level.required != tt.wantRequired {
+				t.Errorf("required domains = %d, want %d", level.required, tt.wantRequired)
+			}
+			if diff := cmp.Diff(tt.wantDomains, level.domainCounts); diff != "" {
+				t.Errorf("domain counts (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTopologyLevels(t *testing.T) {
+	zoneKeys := []string{v1.LabelTopologyZone, v1.LabelHostname}
+	tests := []struct {
+		name        string
+		args        ControllerSpreadArgs
+		annotations map[string]string
+		peerNodes   []string
+		want        []string
+	}{
+		{
+			name:        "hostnames by default",
+			annotations: map[string]string{minHostsAnnotationKey: "4"},
+			peerNodes:   []string{"node-a1"},
+			want:        []string{"node-a2", "node-b1", "node-b2"},
+		},
+		{
+			name:        "zones and hostnames",
+			args:        ControllerSpreadArgs{TopologyKeys: zoneKeys},
+			annotations: map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "2"},
+			peerNodes:   []string{"node-a1"},
+			want:        []string{"node-b1", "node-b2"},
+		},
+		{
+			name:        "zone minimum met",
+			args:        ControllerSpreadArgs{TopologyKeys: zoneKeys},
+			annotations: map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "2"},
+			peerNodes:   []string{"node-a1", "node-b1"},
+			want:        []string{"node-a2", "node-b2"},
+		},
+		{
+			name:        "any level matching",
+			args:        ControllerSpreadArgs{TopologyKeys: zoneKeys},
+			annotations: map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "2", levelMatchAnnotationKey: anyLevelMatch},
+			peerNodes:   []string{"node-a1", "node-b1"},
+			want:        []string{"node-a1", "node-a2", "node-b1", "node-b2"},
+		},
+		{
+			name:        "topology-key annotation selects a single level",
+			args:        ControllerSpreadArgs{TopologyKeys: zoneKeys},
+			annotations: map[string]string{minHostsAnnotationKey: "2", topologyKeyAnnotationKey: v1.LabelTopologyZone},
+			peerNodes:   []string{"node-a1"},
+			want:        []string{"node-b1", "node-b2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []*v1.Node{
+				makeNode("node-a1", map[string]string{v1.LabelTopologyZone: "zone-a"}),
+				makeNode("node-a2", map[string]string{v1.LabelTopologyZone: "zone-a"}),
+				makeNode("node-b1", map[string]string{v1.LabelTopologyZone: "zone-b"}),
+				makeNode("node-b2", map[string]string{v1.LabelTopologyZone: "zone-b"}),
+			}
+			objs := makeDeploymentPods(makeDeployment("web", 4, tt.annotations), tt.peerNodes...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTopologyKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        ControllerSpreadArgs
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name: "default",
+			want: []string{v1.LabelHostname},
+		},
+		{
+			name: "plugin arg",
+			args: ControllerSpreadArgs{TopologyKeys: []string{v1.LabelTopologyZone, v1.LabelHostname}},
+			want: []string{v1.LabelTopologyZone, v1.LabelHostname},
+		},
+		{
+			name:        "annotation",
+			args:        ControllerSpreadArgs{TopologyKeys: []string{v1.LabelTopologyZone, v1.LabelHostname}},
+			annotations: map[string]string{topologyKeyAnnotationKey: v1.LabelTopologyRegion},
+			want:        []string{v1.LabelTopologyRegion},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csf := &ControllerSpreadFilter{args: &tt.args}
+			if diff := cmp.Diff(tt.want, csf.topologyKeys(tt.annotations)); diff != "" {
+				t.Errorf("topologyKeys() (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTopologyTaintKey(t *testing.T) {
+	const taintKey = "example.com/maintenance-domain"
+	tests := []struct {
+		name      string
+		args      ControllerSpreadArgs
+		peerNodes []string
+		want      []string
+	}{
+		{
+			name:      "hostnames only",
+			peerNodes: []string{"node-a1"},
+			want:      []string{"node-a2", "node-b1", "node-c1"},
+		},
+		{
+			name:      "taint domain occupied",
+			args:      ControllerSpreadArgs{TopologyTaintKey: taintKey},
+			peerNodes: []string{"node-a1"},
+			want:      []string{"node-b1", "node-c1"},
+		},
+		{
+			name:      "untainted domain left",
+			args:      ControllerSpreadArgs{TopologyTaintKey: taintKey},
+			peerNodes: []string{"node-a1", "node-b1"},
+			want:      []string{"node-c1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := makeNodes("node-a1", "node-a2", "node-b1", "node-c1")
+			for _, node := range nodes[:3] {
+				domain := strings.TrimPrefix(node.Name, "node-")[:1]
+				node.Spec.Taints = []v1.Taint{{Key: taintKey, Value: domain, Effect: v1.TaintEffectPreferNoSchedule}}
+			}
+			annotations := map[string]string{minHostsAnnotationKey: "4", minZonesAnnotationKey: "3"}
+			objs := makeDeploymentPods(makeDeployment("web", 4, annotations), tt.peerNodes...)
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTaintTopologyValue(t *testing.T) {
+	const taintKey = "example.com/maintenance-domain"
+	tests := []struct {
+		name   string
+		taints []v1.Taint
+		want   string
+	}{
+		{
+			name:   "tainted node",
+			taints: []v1.Taint{{Key: taintKey, Value: "a", Effect: v1.TaintEffectNoSchedule}},
+			want:   "a",
+		},
+		{
+			name:   "taint of any effect",
+			taints: []v1.Taint{{Key: taintKey, Value: "b", Effect: v1.TaintEffectNoExecute}},
+			want:   "b",
+		},
+		{
+			name:   "other taint",
+			taints: []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}},
+			want:   untaintedDomain,
+		},
+		{
+			name: "untainted node",
+			want: untaintedDomain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := makeNode("node-a", nil)
+			node.Spec.Taints = tt.taints
+			if got := topologyDomain(klog.Background(), node, taintTopologyKeyPrefix+taintKey); got != tt.want {
+				t.Errorf("topologyDomain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
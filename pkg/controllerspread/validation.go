@@ -0,0 +1,266 @@
+// pkg/controllerspread/validation.go
+//
+// Defaulting and validation of ControllerSpreadArgs, following the conventions of the
+// in-tree scheduler plugin args. Misconfiguration is reported at scheduler startup.
+package controllerspread
+
+import (
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SetDefaults_ControllerSpreadArgs sets the default values of unset fields.
+func SetDefaults_ControllerSpreadArgs(args *ControllerSpreadArgs) {
+	if args.PluginName == "" {
+		args.PluginName = Name
+	}
+	applyPreset(args)
+	if args.DefaultMinHosts == 0 {
+		args.DefaultMinHosts = defaultMinHosts
+	}
+	if args.Mode == "" {
+		args.Mode = EnforceMode
+	}
+	if args.OnError == "" {
+		args.OnError = OnErrorOpen
+	}
+	if args.MaxOwnerChainDepth == 0 {
+		args.MaxOwnerChainDepth = defaultMaxOwnerChainDepth
+	}
+	if len(args.CountedPhases) == 0 {
+		args.CountedPhases = []v1.PodPhase{v1.PodRunning, v1.PodPending}
+	}
+	if args.ExternalPolicyTimeout.Duration == 0 {
+		args.ExternalPolicyTimeout.Duration = defaultExternalPolicyTimeout
+	}
+	if args.BindWaitTimeout.Duration == 0 {
+		args.BindWaitTimeout.Duration = defaultBindWaitTimeout
+	}
+	if args.RejectRateWindow.Duration == 0 {
+		args.RejectRateWindow.Duration = defaultRejectRateWindow
+	}
+	if args.RejectionLogInterval.Duration == 0 {
+		args.RejectionLogInterval.Duration = defaultRejectionLogInterval
+	}
+	if args.VPAUpdateWindow.Duration == 0 {
+		args.VPAUpdateWindow.Duration = defaultVPAUpdateWindow
+	}
+	if args.ExternalPolicyFailurePolicy == "" {
+		args.ExternalPolicyFailurePolicy = FailurePolicyIgnore
+	}
+	for i := range args.CustomControllers {
+		if args.CustomControllers[i].ReplicasField == "" {
+			args.CustomControllers[i].ReplicasField = defaultReplicasField
+		}
+	}
+}
+
+// ValidateControllerSpreadArgs validates the defaulted args and returns all errors found.
+func ValidateControllerSpreadArgs(path *field.Path, args *ControllerSpreadArgs) error {
+	var allErrs field.ErrorList
+
+	if args.DefaultMinHosts < 2 {
+		allErrs = append(allErrs, field.Invalid(path.Child("defaultMinHosts"), args.DefaultMinHosts, "must be at least 2"))
+	}
+	for _, namespace := range slices.Sorted(maps.Keys(args.NamespaceDefaults)) {
+		if minHosts := args.NamespaceDefaults[namespace]; minHosts < 2 {
+			allErrs = append(allErrs, field.Invalid(path.Child("namespaceDefaults").Key(namespace), minHosts, "must be at least 2"))
+		}
+	}
+	if args.Preset != "" && args.Preset != HAPreset {
+		allErrs = append(allErrs, field.NotSupported(path.Child("preset"), args.Preset, []string{string(HAPreset)}))
+	}
+	if args.Mode != EnforceMode && args.Mode != ObserveMode {
+		allErrs = append(allErrs, field.NotSupported(path.Child("mode"), args.Mode, []string{string(EnforceMode), string(ObserveMode)}))
+	}
+	if args.OnError != OnErrorOpen && args.OnError != OnErrorClosed {
+		allErrs = append(allErrs, field.NotSupported(path.Child("onError"), args.OnError, []string{string(OnErrorOpen), string(OnErrorClosed)}))
+	}
+	if args.MaxConcurrentBinds < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxConcurrentBinds"), args.MaxConcurrentBinds, "must be non-negative"))
+	}
+	if args.BindWaitTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("bindWaitTimeout"), args.BindWaitTimeout.Duration.String(), "must be positive"))
+	}
+	if args.MaxRejectRate < 0 || args.MaxRejectRate > 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxRejectRate"), args.MaxRejectRate, "must be between 0 and 1"))
+	}
+	if args.RejectRateWindow.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("rejectRateWindow"), args.RejectRateWindow.Duration.String(), "must be positive"))
+	}
+	if args.RejectionLogInterval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("rejectionLogInterval"), args.RejectionLogInterval.Duration.String(), "must be positive"))
+	}
+	if args.VPAUpdateWindow.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("vpaUpdateWindow"), args.VPAUpdateWindow.Duration.String(), "must be positive"))
+	}
+	if args.SpreadGate != "" {
+		for _, msg := range validation.IsQualifiedName(args.SpreadGate) {
+			allErrs = append(allErrs, field.Invalid(path.Child("spreadGate"), args.SpreadGate, msg))
+		}
+	}
+	if args.ConsistentReadQPS < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("consistentReadQPS"), args.ConsistentReadQPS, "must be non-negative"))
+	}
+	if args.RequeueBatchSize < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("requeueBatchSize"), args.RequeueBatchSize, "must be non-negative"))
+	}
+	if args.JobCompletionsWindow < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("jobCompletionsWindow"), args.JobCompletionsWindow, "must be non-negative"))
+	}
+	if args.MaxOwnerChainDepth < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxOwnerChainDepth"), args.MaxOwnerChainDepth, "must be non-negative"))
+	}
+
+	customKinds := sets.New[string]()
+	if args.OpenKruise {
+		customKinds.Insert(string(CloneSetType), string(AdvancedStatefulSetType))
+	}
+	for i, config := range args.CustomControllers {
+		allErrs = append(allErrs, validateCustomControllerConfig(path.Child("customControllers").Index(i), config, customKinds)...)
+		customKinds.Insert(config.Kind)
+	}
+	scaleGroupResources := sets.New[string]()
+	for i, resource := range args.ScaleGroupResources {
+		resourcePath := path.Child("scaleGroupResources").Index(i)
+		groupResource := schema.ParseGroupResource(resource)
+		if groupResource.Resource == "" || groupResource.Group == "" {
+			allErrs = append(allErrs, field.Invalid(resourcePath, resource, "must be a resource and its API group, e.g. rollouts.argoproj.io"))
+		}
+		if scaleGroupResources.Has(resource) {
+			allErrs = append(allErrs, field.Duplicate(resourcePath, resource))
+		}
+		scaleGroupResources.Insert(resource)
+	}
+	for i, t := range args.EnabledControllerTypes {
+		// The kinds of the scale group resources are only known once resolved through discovery.
+		if !isBuiltinControllerType(t) && !customKinds.Has(string(t)) && len(args.ScaleGroupResources) == 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("enabledControllerTypes").Index(i), t, "unknown controller type"))
+		}
+	}
+	for _, t := range slices.Sorted(maps.Keys(args.TypeDefaults)) {
+		typePath := path.Child("typeDefaults").Key(string(t))
+		if !isBuiltinControllerType(t) && !customKinds.Has(string(t)) && len(args.ScaleGroupResources) == 0 {
+			allErrs = append(allErrs, field.Invalid(typePath, t, "unknown controller type"))
+		}
+		if minHosts := args.TypeDefaults[t]; minHosts < 2 {
+			allErrs = append(allErrs, field.Invalid(typePath, minHosts, "must be at least 2"))
+		}
+	}
+
+	topologyKeys := sets.New[string]()
+	for i, key := range args.TopologyKeys {
+		keyPath := path.Child("topologyKeys").Index(i)
+		if _, err := newDomainExtractor(key); err != nil {
+			allErrs = append(allErrs, field.Invalid(keyPath, key, err.Error()))
+		}
+		if topologyKeys.Has(key) {
+			allErrs = append(allErrs, field.Duplicate(keyPath, key))
+		}
+		topologyKeys.Insert(key)
+	}
+	if args.TopologyTaintKey != "" {
+		for _, msg := range validation.IsQualifiedName(args.TopologyTaintKey) {
+			allErrs = append(allErrs, field.Invalid(path.Child("topologyTaintKey"), args.TopologyTaintKey, msg))
+		}
+	}
+	if args.RequireResource != "" {
+		for _, msg := range validation.IsQualifiedName(args.RequireResource) {
+			allErrs = append(allErrs, field.Invalid(path.Child("requireResource"), args.RequireResource, msg))
+		}
+	}
+	if args.ReservedNodeAnnotation != "" {
+		for _, msg := range validation.IsQualifiedName(args.ReservedNodeAnnotation) {
+			allErrs = append(allErrs, field.Invalid(path.Child("reservedNodeAnnotation"), args.ReservedNodeAnnotation, msg))
+		}
+	}
+	if args.TenantLabel != "" {
+		for _, msg := range validation.IsQualifiedName(args.TenantLabel) {
+			allErrs = append(allErrs, field.Invalid(path.Child("tenantLabel"), args.TenantLabel, msg))
+		}
+		if args.ReservedNodeAnnotation == "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("tenantLabel"), args.TenantLabel, "requires reservedNodeAnnotation"))
+		}
+	}
+
+	if args.DebugEndpoint != "" {
+		if _, _, err := net.SplitHostPort(args.DebugEndpoint); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("debugEndpoint"), args.DebugEndpoint, err.Error()))
+		}
+	}
+
+	if args.ExternalPolicyEndpoint != "" {
+		if u, err := url.Parse(args.ExternalPolicyEndpoint); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("externalPolicyEndpoint"), args.ExternalPolicyEndpoint, err.Error()))
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("externalPolicyEndpoint"), args.ExternalPolicyEndpoint, "must be an absolute http or https URL"))
+		}
+	}
+	if args.ExternalPolicyTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("externalPolicyTimeout"), args.ExternalPolicyTimeout.Duration.String(), "must be positive"))
+	}
+	if args.ExternalPolicyFailurePolicy != FailurePolicyIgnore && args.ExternalPolicyFailurePolicy != FailurePolicyFail {
+		allErrs = append(allErrs, field.NotSupported(path.Child("externalPolicyFailurePolicy"), args.ExternalPolicyFailurePolicy,
+			[]string{string(FailurePolicyIgnore), string(FailurePolicyFail)}))
+	}
+
+	if ref := args.DomainWeightsConfigMap; ref != nil {
+		refPath := path.Child("domainWeightsConfigMap")
+		if ref.Namespace == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("namespace"), ""))
+		}
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("name"), ""))
+		}
+	}
+
+	supportedPhases := []string{string(v1.PodPending), string(v1.PodRunning), string(v1.PodSucceeded), string(v1.PodFailed), string(v1.PodUnknown)}
+	for i, phase := range args.CountedPhases {
+		if !slices.Contains(supportedPhases, string(phase)) {
+			allErrs = append(allErrs, field.NotSupported(path.Child("countedPhases").Index(i), phase, supportedPhases))
+		}
+	}
+
+	if args.NamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(args.NamespaceSelector,
+			metav1validation.LabelSelectorValidationOptions{}, path.Child("namespaceSelector"))...)
+	}
+	if args.ExcludedNodeSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(args.ExcludedNodeSelector,
+			metav1validation.LabelSelectorValidationOptions{}, path.Child("excludedNodeSelector"))...)
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// validateCustomControllerConfig validates a custom controller against the kinds seen so far.
+func validateCustomControllerConfig(path *field.Path, config CustomControllerConfig, seenKinds sets.Set[string]) field.ErrorList {
+	var allErrs field.ErrorList
+	if config.APIVersion == "" {
+		allErrs = append(allErrs, field.Required(path.Child("apiVersion"), ""))
+	} else if _, err := schema.ParseGroupVersion(config.APIVersion); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("apiVersion"), config.APIVersion, err.Error()))
+	}
+	if config.Resource == "" {
+		allErrs = append(allErrs, field.Required(path.Child("resource"), ""))
+	}
+	switch {
+	case config.Kind == "":
+		allErrs = append(allErrs, field.Required(path.Child("kind"), ""))
+	case isBuiltinControllerType(ControllerType(config.Kind)):
+		allErrs = append(allErrs, field.Invalid(path.Child("kind"), config.Kind, "collides with a built-in controller"))
+	case seenKinds.Has(config.Kind):
+		allErrs = append(allErrs, field.Duplicate(path.Child("kind"), config.Kind))
+	}
+	return allErrs
+}
@@ -0,0 +1,162 @@
+package controllerspread
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateControllerSpreadArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*ControllerSpreadArgs)
+		// wantErr is a substring of the expected error, or empty if the args are valid.
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			modify: func(*ControllerSpreadArgs) {},
+		},
+		{
+			name:   "topology keys",
+			modify: func(args *ControllerSpreadArgs) { args.TopologyKeys = []string{v1.LabelTopologyZone, v1.LabelHostname} },
+		},
+		{
+			name:    "duplicate topology key",
+			modify:  func(args *ControllerSpreadArgs) { args.TopologyKeys = []string{v1.LabelHostname, v1.LabelHostname} },
+			wantErr: "args.topologyKeys[1]: Duplicate value",
+		},
+		{
+			name: "topology key schemes",
+			modify: func(args *ControllerSpreadArgs) {
+				args.TopologyKeys = []string{"node-pool:", "taint:example.com/domain", "hostname:"}
+			},
+		},
+		{
+			name:    "unknown topology key scheme",
+			modify:  func(args *ControllerSpreadArgs) { args.TopologyKeys = []string{"rack:a", v1.LabelHostname} },
+			wantErr: "args.topologyKeys[0]: Invalid value",
+		},
+		{
+			name:    "invalid topology taint key",
+			modify:  func(args *ControllerSpreadArgs) { args.TopologyTaintKey = "maintenance domain" },
+			wantErr: "args.topologyTaintKey: Invalid value",
+		},
+		{
+			name:    "unsupported counted phase",
+			modify:  func(args *ControllerSpreadArgs) { args.CountedPhases = []v1.PodPhase{v1.PodRunning, "Done"} },
+			wantErr: "args.countedPhases[1]: Unsupported value",
+		},
+		{
+			name:    "negative Job completions window",
+			modify:  func(args *ControllerSpreadArgs) { args.JobCompletionsWindow = -1 },
+			wantErr: "args.jobCompletionsWindow: Invalid value",
+		},
+		{
+			name:    "unsupported preset",
+			modify:  func(args *ControllerSpreadArgs) { args.Preset = "Cheap" },
+			wantErr: "args.preset: Unsupported value",
+		},
+		{
+			name:    "invalid spread gate",
+			modify:  func(args *ControllerSpreadArgs) { args.SpreadGate = "spread gate" },
+			wantErr: "args.spreadGate: Invalid value",
+		},
+		{
+			name:    "scale group resource without a group",
+			modify:  func(args *ControllerSpreadArgs) { args.ScaleGroupResources = []string{"rollouts"} },
+			wantErr: "args.scaleGroupResources[0]: Invalid value",
+		},
+		{
+			name: "duplicate scale group resource",
+			modify: func(args *ControllerSpreadArgs) {
+				args.ScaleGroupResources = []string{"rollouts.argoproj.io", "rollouts.argoproj.io"}
+			},
+			wantErr: "args.scaleGroupResources[1]: Duplicate value",
+		},
+		{
+			name:    "negative consistent read QPS",
+			modify:  func(args *ControllerSpreadArgs) { args.ConsistentReadQPS = -1 },
+			wantErr: "args.consistentReadQPS: Invalid value",
+		},
+		{
+			name:    "tenant label without reserved node annotation",
+			modify:  func(args *ControllerSpreadArgs) { args.TenantLabel = "example.com/tenant" },
+			wantErr: "args.tenantLabel: Invalid value",
+		},
+		{
+			name:    "type default of an unknown type",
+			modify:  func(args *ControllerSpreadArgs) { args.TypeDefaults = map[ControllerType]int32{"Rollout": 3} },
+			wantErr: "args.typeDefaults[Rollout]: Invalid value",
+		},
+		{
+			name:    "type default below 2",
+			modify:  func(args *ControllerSpreadArgs) { args.TypeDefaults = map[ControllerType]int32{StatefulSetType: 1} },
+			wantErr: "args.typeDefaults[StatefulSet]: Invalid value",
+		},
+		{
+			name:    "namespace default below 2",
+			modify:  func(args *ControllerSpreadArgs) { args.NamespaceDefaults = map[string]int32{"prod": 1} },
+			wantErr: "args.namespaceDefaults[prod]: Invalid value",
+		},
+		{
+			name:    "reject rate above 1",
+			modify:  func(args *ControllerSpreadArgs) { args.MaxRejectRate = 1.5 },
+			wantErr: "args.maxRejectRate: Invalid value",
+		},
+		{
+			name:    "negative reject rate window",
+			modify:  func(args *ControllerSpreadArgs) { args.RejectRateWindow.Duration = -time.Minute },
+			wantErr: "args.rejectRateWindow: Invalid value",
+		},
+		{
+			name:    "negative rejection log interval",
+			modify:  func(args *ControllerSpreadArgs) { args.RejectionLogInterval.Duration = -time.Minute },
+			wantErr: "args.rejectionLogInterval: Invalid value",
+		},
+		{
+			name: "excluded node selector",
+			modify: func(args *ControllerSpreadArgs) {
+				args.ExcludedNodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"node-role.example.com/ci": "true"}}
+			},
+		},
+		{
+			name: "excluded node selector without values",
+			modify: func(args *ControllerSpreadArgs) {
+				args.ExcludedNodeSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "node-role.example.com/ci", Operator: metav1.LabelSelectorOpIn},
+				}}
+			},
+			wantErr: "args.excludedNodeSelector.matchExpressions[0].values: Required value",
+		},
+		{
+			name: "custom controller of an OpenKruise kind",
+			modify: func(args *ControllerSpreadArgs) {
+				args.OpenKruise = true
+				args.CustomControllers = []CustomControllerConfig{{APIVersion: "example.com/v1", Kind: "CloneSet", Resource: "clonesets"}}
+			},
+			wantErr: "args.customControllers[0].kind: Duplicate value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &ControllerSpreadArgs{}
+			SetDefaults_ControllerSpreadArgs(args)
+			tt.modify(args)
+			err := ValidateControllerSpreadArgs(field.NewPath("args"), args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateControllerSpreadArgs() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateControllerSpreadArgs() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// pkg/controllerspread/vpa.go
+//
+// VerticalPodAutoscaler awareness for ControllerSpreadFilter. The VPA updater evicts pods whose
+// resources are off their recommendation, and the VPA admission controller sets the recommended
+// resources on the recreated pods, recording them in the "vpaUpdates" pod annotation. While a pod
+// is being recreated its peers may be transiently co-located, and max-pods-per-node or max-skew
+// could keep the replacement pending. The admission controller annotates every pod it admits,
+// including those of scale-ups and rollouts, so the annotation alone does not identify a
+// replacement. With VPAAware set, the evictions of annotated pods through the eviction API are
+// recorded per controller for VPAUpdateWindow, like the pods lost to node failures (see
+// reschedule.go), and that many of the controller's next annotated pods get the scale-up grace
+// relaxation: only min-hosts (and min-zones) is enforced.
+package controllerspread
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+const (
+	// vpaUpdatesAnnotationKey is the pod annotation in which the VPA admission controller records
+	// the resources it set on the pod.
+	vpaUpdatesAnnotationKey = "vpaUpdates"
+
+	// defaultVPAUpdateWindow is how long after an eviction by a VPA the replacement pod is
+	// relaxed by default.
+	defaultVPAUpdateWindow = 10 * time.Minute
+
+	// evictionByEvictionAPI is the reason of the DisruptionTarget condition of pods evicted through
+	// the eviction API, which the VPA updater uses.
+	evictionByEvictionAPI = "EvictionByEvictionAPI"
+)
+
+// evictedByVPA reports whether the VPA admission controller set the resources of the pod and the
+// pod carries the DisruptionTarget condition of an eviction through the eviction API.
+func evictedByVPA(pod *v1.Pod) bool {
+	if pod.Annotations[vpaUpdatesAnnotationKey] == "" {
+		return false
+	}
+	_, condition := podutil.GetPodCondition(&pod.Status, v1.DisruptionTarget)
+	return condition != nil && condition.Status == v1.ConditionTrue && condition.Reason == evictionByEvictionAPI
+}
+
+// replacesVPAEviction reports whether VPAAware is set, the VPA admission controller set the
+// resources of the pod and a pod of the controller evicted by a VPA within VPAUpdateWindow has
+// not been replaced yet.
+func (csf *ControllerSpreadFilter) replacesVPAEviction(pod *v1.Pod, controller ControllerInfo, now time.Time) bool {
+	if !csf.args.VPAAware || pod.Annotations[vpaUpdatesAnnotationKey] == "" {
+		return false
+	}
+	return csf.vpaEvictions.pending(controller.UID, now)
+}
@@ -0,0 +1,163 @@
+package controllerspread
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testVPAUpdates = "Pod resources updated by web: container 0: cpu request"
+
+func TestEvictedByVPA(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		reason      string
+		want        bool
+	}{
+		{
+			name:        "evicted by the VPA updater",
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			reason:      evictionByEvictionAPI,
+			want:        true,
+		},
+		{
+			name:        "deleted without eviction",
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+		},
+		{
+			name:        "lost to a node failure",
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			reason:      deletionByTaintManager,
+		},
+		{
+			name:   "evicted without VPA annotation",
+			reason: evictionByEvictionAPI,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Annotations = tt.annotations
+			if tt.reason != "" {
+				pod = withDisruptionTarget(pod, v1.ConditionTrue, tt.reason)
+			}
+			if got := evictedByVPA(pod); got != tt.want {
+				t.Errorf("evictedByVPA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVPARelaxation(t *testing.T) {
+	nodes := makeNodes("node-a", "node-b")
+	tests := []struct {
+		name        string
+		args        ControllerSpreadArgs
+		annotations map[string]string
+		// evicted records a VPA eviction of a peer of the controller evictedAt before now.
+		evicted   bool
+		evictedAt time.Duration
+		want      []string
+	}{
+		{
+			name:        "replaces a pod evicted by the VPA",
+			args:        ControllerSpreadArgs{VPAAware: true},
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			evicted:     true,
+			want:        []string{"node-a", "node-b"},
+		},
+		{
+			// The admission controller annotates every pod it admits, e.g. on a scale-up.
+			name:        "updated by the VPA without a recent eviction",
+			args:        ControllerSpreadArgs{VPAAware: true},
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+		},
+		{
+			name:        "VPA awareness disabled",
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			evicted:     true,
+		},
+		{
+			name:    "not updated by the VPA",
+			args:    ControllerSpreadArgs{VPAAware: true},
+			evicted: true,
+		},
+		{
+			name:        "evicted before the default window",
+			args:        ControllerSpreadArgs{VPAAware: true},
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			evicted:     true,
+			evictedAt:   time.Hour,
+		},
+		{
+			name:        "evicted within a longer window",
+			args:        ControllerSpreadArgs{VPAAware: true, VPAUpdateWindow: metav1.Duration{Duration: 2 * time.Hour}},
+			annotations: map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates},
+			evicted:     true,
+			evictedAt:   time.Hour,
+			want:        []string{"node-a", "node-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := makeDeployment("web", 3, map[string]string{maxPodsPerNodeAnnotationKey: "1"})
+			objs := makeDeploymentPods(deploy, "node-a", "node-b")
+			pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+			pod.Annotations = tt.annotations
+			p := newTestPlugin(t, &tt.args, nodes, append(objs, pod)...)
+			if tt.evicted && p.vpaEvictions != nil {
+				p.vpaEvictions.add(string(testUID("web")), time.Now().Add(-tt.evictedAt))
+			}
+
+			if diff := cmp.Diff(tt.want, filterNodes(t, p, pod)); diff != "" {
+				t.Errorf("feasible nodes (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVPAEvictionRecorded(t *testing.T) {
+	objs := makeDeploymentPods(makeDeployment("web", 3, nil))
+	p := newTestPlugin(t, &ControllerSpreadArgs{VPAAware: true}, makeNodes("node-a"), objs...)
+	evicted := withDisruptionTarget(makePod("web-0", "node-a", ownerRef(ReplicaSetType, "web-hash")), v1.ConditionTrue, evictionByEvictionAPI)
+	evicted.Annotations = map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates}
+
+	p.vpaEvictions.delete(evicted)
+	if !p.vpaEvictions.pending(string(testUID("web")), time.Now()) {
+		t.Errorf("pending() after VPA eviction = false, want true")
+	}
+	if p.lostPeers.pending(string(testUID("web")), time.Now()) {
+		t.Errorf("lost peers pending() after VPA eviction = true, want false")
+	}
+}
+
+func TestReserveReplacesVPAEviction(t *testing.T) {
+	deploy := makeDeployment("web", 3, map[string]string{maxPodsPerNodeAnnotationKey: "1"})
+	objs := makeDeploymentPods(deploy, "node-a", "node-b")
+	pod := makePod("web-new", "", ownerRef(ReplicaSetType, "web-hash"))
+	pod.Annotations = map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates}
+	next := makePod("web-next", "", ownerRef(ReplicaSetType, "web-hash"))
+	next.Annotations = map[string]string{vpaUpdatesAnnotationKey: testVPAUpdates}
+	p := newTestPlugin(t, &ControllerSpreadArgs{VPAAware: true}, makeNodes("node-a", "node-b"), append(objs, pod, next)...)
+	p.vpaEvictions.add(string(testUID("web")), time.Now())
+
+	state, status := preFilter(t, p, pod)
+	if !status.IsSuccess() {
+		t.Fatalf("PreFilter: %v", status)
+	}
+	if s := p.Reserve(t.Context(), state, pod, "node-a"); !s.IsSuccess() {
+		t.Fatalf("Reserve: %v", s)
+	}
+	// Only the replacement of the evicted pod is relaxed.
+	if got := filterNodes(t, p, next); len(got) != 0 {
+		t.Errorf("feasible nodes for the next pod = %v, want none", got)
+	}
+	p.Unreserve(t.Context(), state, pod, "node-a")
+	if diff := cmp.Diff([]string{"node-a", "node-b"}, filterNodes(t, p, next)); diff != "" {
+		t.Errorf("feasible nodes after Unreserve (-want,+got):\n%s", diff)
+	}
+}